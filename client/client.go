@@ -0,0 +1,512 @@
+package client
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/hedon954/go-lock-step-server/pb"
+	"github.com/hedon954/go-lock-step-server/pkg/packet/pb_packet"
+	"google.golang.org/protobuf/proto"
+)
+
+const (
+	DefaultTimeout           = time.Second * 5
+	DefaultHeartbeatInterval = time.Second
+	DefaultReconnectAttempts = 3
+	DefaultReconnectInterval = time.Millisecond * 500
+	DefaultEventBuffer       = 1024
+
+	// the redirects followed by a connect at most
+	kMaxRedirects = 3
+)
+
+var (
+	ErrNotConnected    = errors.New("client is not connected")
+	ErrClosed          = errors.New("client has been closed")
+	ErrUnexpectedReply = errors.New("the first packet received is not the reply of MSG_Connect")
+)
+
+// ConnectError is the error code replied to MSG_Connect
+type ConnectError struct {
+	Code    pb.ERRORCODE
+	Address string // the node owning the room if Code is ERR_Redirect
+}
+
+func (e *ConnectError) Error() string {
+	if e.Code == pb.ERRORCODE_ERR_Redirect {
+		return fmt.Sprintf("connect error: %s to [%s]", e.Code, e.Address)
+	}
+	return fmt.Sprintf("connect error: %s", e.Code)
+}
+
+// Config is the configuration of a client
+type Config struct {
+	Address       string      // the address of the server
+	Dialer        Dialer      // nil means KCPDialer(nil)
+	PlayerID      uint64      // the player id
+	RoomID        uint64      // the room id
+	Token         string      // the token of the player, it is the key of CHK_HMAC as well
+	ClientVersion string      // the client version like 1.2.3, empty means unknown
+	Checksum      pb.CHECKSUM // the packet checksum requested
+
+	// Timeout limits dialing, the reply of MSG_Connect and every read and write,
+	// the heartbeats keep the connection readable, 0 means DefaultTimeout
+	Timeout time.Duration
+
+	// HeartbeatInterval is the interval of MSG_Heartbeat, 0 means DefaultHeartbeatInterval
+	HeartbeatInterval time.Duration
+
+	// ReconnectAttempts is how many times the client resumes the session with the ticket after the connection breaks,
+	// it connects with the token and gets ready again once the ticket is rejected, so the frames are caught up from 0.
+	// 0 means DefaultReconnectAttempts, and a negative value disables reconnecting
+	ReconnectAttempts int
+
+	// ReconnectInterval is the delay before every reconnect attempt, 0 means DefaultReconnectInterval
+	ReconnectInterval time.Duration
+
+	// EventBuffer is the capacity of the event channel, 0 means DefaultEventBuffer.
+	// The connection stops reading while the channel is full
+	EventBuffer int
+}
+
+// Client is a player connected to a lock step server
+type Client struct {
+	config    Config
+	events    chan Event
+	nextFrame uint32 // the first frame not received yet
+
+	mu        sync.Mutex
+	address   string
+	conn      net.Conn
+	protocol  *pb_packet.MsgProtocol
+	ticket    string
+	closed    bool
+	connected bool
+
+	roomClosed bool // accessed by the read loop only
+
+	closeOnce sync.Once
+	closeChan chan struct{}
+	wg        sync.WaitGroup
+}
+
+// New creates a client, it does not connect until Connect is called
+func New(config *Config) *Client {
+	c := &Client{
+		config:    *config,
+		address:   config.Address,
+		closeChan: make(chan struct{}),
+	}
+	if c.config.Dialer == nil {
+		c.config.Dialer = KCPDialer(nil)
+	}
+	if c.config.Timeout <= 0 {
+		c.config.Timeout = DefaultTimeout
+	}
+	if c.config.HeartbeatInterval <= 0 {
+		c.config.HeartbeatInterval = DefaultHeartbeatInterval
+	}
+	if c.config.ReconnectAttempts == 0 {
+		c.config.ReconnectAttempts = DefaultReconnectAttempts
+	}
+	if c.config.ReconnectInterval <= 0 {
+		c.config.ReconnectInterval = DefaultReconnectInterval
+	}
+	if c.config.EventBuffer <= 0 {
+		c.config.EventBuffer = DefaultEventBuffer
+	}
+	c.events = make(chan Event, c.config.EventBuffer)
+	return c
+}
+
+// Events returns the event channel, it is closed after EventDisconnected or Close
+func (c *Client) Events() <-chan Event {
+	return c.events
+}
+
+// Ticket returns the resume ticket of the current session
+func (c *Client) Ticket() string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.ticket
+}
+
+// Connect connects the player to the room, the redirects are followed.
+// The events are delivered and the heartbeats are sent once it succeeds, it can only succeed once
+func (c *Client) Connect() error {
+	c.mu.Lock()
+	if c.closed {
+		c.mu.Unlock()
+		return ErrClosed
+	}
+	if c.connected {
+		c.mu.Unlock()
+		return errors.New("client has been connected")
+	}
+	c.mu.Unlock()
+
+	conn, mp, err := c.handshake(false)
+	if err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	if c.closed {
+		c.mu.Unlock()
+		conn.Close()
+		return ErrClosed
+	}
+	c.conn, c.protocol, c.connected = conn, mp, true
+	c.mu.Unlock()
+
+	c.wg.Add(2)
+	go c.run(conn, mp)
+	go c.heartbeat()
+	return nil
+}
+
+// JoinRoom asks for the seat and the others in the room, the reply is EventJoinRoom
+func (c *Client) JoinRoom() error {
+	return c.send(pb.ID_MSG_JoinRoom, nil)
+}
+
+// SendProgress sends the loading progress of 0~100, the others receive EventProgress
+func (c *Client) SendProgress(pro int32) error {
+	return c.send(pb.ID_MSG_Progress, &pb.C2S_ProgressMsg{
+		Pro: proto.Int32(pro),
+	})
+}
+
+// SendLoadout submits the pre-game choices of the seat before the game starts,
+// everybody receives EventLoadout, and the loadouts locked at start are in EventStart
+func (c *Client) SendLoadout(data []byte) error {
+	return c.send(pb.ID_MSG_Loadout, &pb.C2S_LoadoutMsg{
+		Data: data,
+	})
+}
+
+// Ready tells the server the player is ready, a player joining a running game receives the frames since then
+func (c *Client) Ready() error {
+	return c.send(pb.ID_MSG_Ready, nil)
+}
+
+// SendInput sends an operation, it is broadcast in the next frame
+func (c *Client) SendInput(sid, x, y int32) error {
+	return c.send(pb.ID_MSG_Input, &pb.C2S_InputMsg{
+		Sid:     proto.Int32(sid),
+		X:       proto.Int32(x),
+		Y:       proto.Int32(y),
+		FrameID: proto.Uint32(atomic.LoadUint32(&c.nextFrame)),
+	})
+}
+
+// SendChecksum sends the state hash after the frame, EventDesync is received if the server's differs
+func (c *Client) SendChecksum(frameID uint32, hash uint64) error {
+	return c.send(pb.ID_MSG_Checksum, &pb.C2S_ChecksumMsg{
+		FrameID: proto.Uint32(frameID),
+		Hash:    proto.Uint64(hash),
+	})
+}
+
+// SendResult reports the winner, the ack is EventResult
+func (c *Client) SendResult(winnerID uint64) error {
+	return c.send(pb.ID_MSG_Result, &pb.C2S_ResultMsg{
+		WinnerID: proto.Uint64(winnerID),
+	})
+}
+
+// Close closes the client and waits for its goroutines to quit
+func (c *Client) Close() {
+	c.shutdown()
+	c.wg.Wait()
+}
+
+func (c *Client) shutdown() {
+	c.closeOnce.Do(func() {
+		c.mu.Lock()
+		c.closed = true
+		if c.conn != nil {
+			c.conn.Close()
+		}
+		c.mu.Unlock()
+		close(c.closeChan)
+	})
+}
+
+// send writes the message to the current connection
+func (c *Client) send(id pb.ID, msg proto.Message) error {
+	p := pb_packet.NewPacket(uint8(id), msg)
+	if p == nil {
+		return fmt.Errorf("encode message %s failed", id)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.closed {
+		return ErrClosed
+	}
+	if c.conn == nil {
+		return ErrNotConnected
+	}
+	return c.write(c.conn, c.protocol, p)
+}
+
+func (c *Client) write(conn net.Conn, mp *pb_packet.MsgProtocol, p *pb_packet.Packet) error {
+	buff, err := mp.EncodePacket(p)
+	if err != nil {
+		return err
+	}
+	_ = conn.SetWriteDeadline(time.Now().Add(c.config.Timeout))
+	_, err = conn.Write(buff)
+	return err
+}
+
+// handshake connects to the server and sends MSG_Connect, the session is resumed with the ticket if resume is true
+func (c *Client) handshake(resume bool) (net.Conn, *pb_packet.MsgProtocol, error) {
+	c.mu.Lock()
+	address, ticket := c.address, c.ticket
+	c.mu.Unlock()
+
+	msg := &pb.C2S_ConnectMsg{
+		ProtocolVersion: proto.Uint32(pb_packet.MaxProtocolVersion),
+		ClientVersion:   proto.String(c.config.ClientVersion),
+		Checksum:        c.config.Checksum.Enum(),
+	}
+	key := c.config.Token
+	if resume {
+		msg.ResumeTicket = proto.String(ticket)
+		msg.FrameID = proto.Uint32(atomic.LoadUint32(&c.nextFrame))
+		key = ticket
+	} else {
+		msg.PlayerID = proto.Uint64(c.config.PlayerID)
+		msg.BattleID = proto.Uint64(c.config.RoomID)
+		msg.Token = proto.String(c.config.Token)
+	}
+
+	for redirects := 0; ; redirects++ {
+		conn, mp, ret, err := c.dialConnect(address, msg, key)
+		if err != nil {
+			return nil, nil, err
+		}
+		if ret.GetErrorCode() == pb.ERRORCODE_ERR_ok {
+			c.mu.Lock()
+			c.address, c.ticket = address, ret.GetResumeTicket()
+			c.mu.Unlock()
+			return conn, mp, nil
+		}
+
+		conn.Close()
+		if ret.GetErrorCode() == pb.ERRORCODE_ERR_Redirect && redirects < kMaxRedirects {
+			address = ret.GetAddress()
+			continue
+		}
+		return nil, nil, &ConnectError{Code: ret.GetErrorCode(), Address: ret.GetAddress()}
+	}
+}
+
+// dialConnect dials the address, sends MSG_Connect and reads the reply
+func (c *Client) dialConnect(address string, msg *pb.C2S_ConnectMsg, key string) (
+	net.Conn, *pb_packet.MsgProtocol, *pb.S2C_ConnectMsg, error,
+) {
+	conn, err := c.config.Dialer(address)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	mp := pb_packet.NewClientProtocol([]byte(key))
+	if err = c.write(conn, mp, pb_packet.NewPacket(uint8(pb.ID_MSG_Connect), msg)); err != nil {
+		conn.Close()
+		return nil, nil, nil, err
+	}
+
+	_ = conn.SetReadDeadline(time.Now().Add(c.config.Timeout))
+	p, err := mp.ReadPacket(conn)
+	if err != nil {
+		conn.Close()
+		return nil, nil, nil, err
+	}
+	packet := p.(*pb_packet.Packet)
+	if pb.ID(packet.GetMessageID()) != pb.ID_MSG_Connect {
+		conn.Close()
+		return nil, nil, nil, ErrUnexpectedReply
+	}
+	ret := &pb.S2C_ConnectMsg{}
+	if err = packet.UnmarshalPB(ret); err != nil {
+		conn.Close()
+		return nil, nil, nil, err
+	}
+	return conn, mp, ret, nil
+}
+
+// run reads the connection and resumes the session after it breaks
+func (c *Client) run(conn net.Conn, mp *pb_packet.MsgProtocol) {
+	defer c.wg.Done()
+	defer close(c.events)
+
+	for {
+		err := c.readLoop(conn, mp)
+		if c.isClosed() {
+			return
+		}
+
+		if !c.roomClosed && c.config.ReconnectAttempts > 0 && c.Ticket() != "" {
+			if conn, mp, err = c.reconnect(); err == nil {
+				c.emit(Event{Type: EventReconnected})
+				continue
+			}
+		}
+
+		c.emit(Event{Type: EventDisconnected, Err: err})
+		c.shutdown()
+		return
+	}
+}
+
+// reconnect resumes the session on a new connection, or connects with the token once the ticket is rejected
+func (c *Client) reconnect() (net.Conn, *pb_packet.MsgProtocol, error) {
+	var err error
+	resume := true
+	for i := 0; i < c.config.ReconnectAttempts; i++ {
+		select {
+		case <-time.After(c.config.ReconnectInterval):
+		case <-c.closeChan:
+			return nil, nil, ErrClosed
+		}
+
+		var conn net.Conn
+		var mp *pb_packet.MsgProtocol
+		if conn, mp, err = c.handshake(resume); err == nil {
+			c.mu.Lock()
+			if c.closed {
+				c.mu.Unlock()
+				conn.Close()
+				return nil, nil, ErrClosed
+			}
+			c.conn, c.protocol = conn, mp
+			c.mu.Unlock()
+			if resume {
+				return conn, mp, nil
+			}
+			// the running game sends the frames again once the player gets ready
+			if err = c.Ready(); err == nil {
+				return conn, mp, nil
+			}
+			conn.Close()
+			continue
+		}
+
+		// the server has rejected the ticket, the token is the last resort
+		if e, ok := err.(*ConnectError); ok {
+			if !resume || e.Code != pb.ERRORCODE_ERR_Ticket || c.config.Token == "" {
+				break
+			}
+			// the attempt with the token is not counted
+			resume = false
+			i--
+		}
+	}
+	return nil, nil, err
+}
+
+// readLoop reads the packets until the connection breaks
+func (c *Client) readLoop(conn net.Conn, mp *pb_packet.MsgProtocol) error {
+	defer conn.Close()
+	for {
+		_ = conn.SetReadDeadline(time.Now().Add(c.config.Timeout))
+		p, err := mp.ReadPacket(conn)
+		if err != nil {
+			return err
+		}
+		c.handle(p.(*pb_packet.Packet))
+	}
+}
+
+// handle delivers the packet as events
+func (c *Client) handle(p *pb_packet.Packet) {
+	switch pb.ID(p.GetMessageID()) {
+	case pb.ID_MSG_JoinRoom:
+		msg := &pb.S2C_JoinRoomMsg{}
+		if p.UnmarshalPB(msg) == nil {
+			c.emit(Event{Type: EventJoinRoom, JoinRoom: msg})
+		}
+	case pb.ID_MSG_Progress:
+		msg := &pb.S2C_ProgressMsg{}
+		if p.UnmarshalPB(msg) == nil {
+			c.emit(Event{Type: EventProgress, Progress: msg})
+		}
+	case pb.ID_MSG_Loadout:
+		msg := &pb.S2C_LoadoutMsg{}
+		if p.UnmarshalPB(msg) == nil {
+			c.emit(Event{Type: EventLoadout, Loadout: msg.GetLoadout()})
+		}
+	case pb.ID_MSG_Ready:
+		c.emit(Event{Type: EventReady})
+	case pb.ID_MSG_Start:
+		msg := &pb.S2C_StartMsg{}
+		if p.UnmarshalPB(msg) == nil {
+			c.emit(Event{Type: EventStart, Start: msg})
+		}
+	case pb.ID_MSG_Frame:
+		msg := &pb.S2C_FrameMsg{}
+		if p.UnmarshalPB(msg) != nil {
+			return
+		}
+		for _, f := range msg.GetFrames() {
+			// the frames are sent again when the player gets ready in a running game
+			next := atomic.LoadUint32(&c.nextFrame)
+			if f.GetFrameID() < next {
+				continue
+			}
+			atomic.StoreUint32(&c.nextFrame, f.GetFrameID()+1)
+			c.emit(Event{Type: EventFrame, Frame: f})
+		}
+	case pb.ID_MSG_Result:
+		c.emit(Event{Type: EventResult})
+	case pb.ID_MSG_Roster:
+		msg := &pb.S2C_RosterMsg{}
+		if p.UnmarshalPB(msg) == nil {
+			c.emit(Event{Type: EventRoster, Roster: msg})
+		}
+	case pb.ID_MSG_Checksum:
+		msg := &pb.S2C_ChecksumMsg{}
+		if p.UnmarshalPB(msg) == nil {
+			c.emit(Event{Type: EventDesync, Checksum: msg})
+		}
+	case pb.ID_MSG_Close:
+		c.roomClosed = true
+		c.emit(Event{Type: EventClose})
+	}
+}
+
+// emit delivers the event unless the client is closed
+func (c *Client) emit(e Event) {
+	select {
+	case c.events <- e:
+	case <-c.closeChan:
+	}
+}
+
+// heartbeat sends MSG_Heartbeat until the client is closed
+func (c *Client) heartbeat() {
+	defer c.wg.Done()
+	ticker := time.NewTicker(c.config.HeartbeatInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-c.closeChan:
+			return
+		case <-ticker.C:
+			_ = c.send(pb.ID_MSG_Heartbeat, nil)
+		}
+	}
+}
+
+func (c *Client) isClosed() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.closed
+}
@@ -0,0 +1,245 @@
+package client
+
+import (
+	"testing"
+	"time"
+
+	"github.com/hedon954/go-lock-step-server/pb"
+	"github.com/hedon954/go-lock-step-server/server"
+)
+
+func newTestServer(t *testing.T, players ...uint64) *server.LockStepServer {
+	s, err := server.New(&server.Config{
+		Listeners: []server.ListenerConfig{{Transport: server.TransportTCP, Address: "127.0.0.1:0"}},
+		Checksum:  server.ChecksumPolicy{Required: pb.CHECKSUM_CHK_CRC32},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(s.Stop)
+	if _, err = s.RoomManager().CreateRoom(1, 0, players, 0, "test"); err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+func newTestClient(t *testing.T, s *server.LockStepServer, pid uint64) *Client {
+	c := New(&Config{
+		Address:           s.Addrs()[0].String(),
+		Dialer:            TCPDialer(time.Second),
+		PlayerID:          pid,
+		RoomID:            1,
+		Token:             s.RoomManager().GetRoom(1).PlayerToken(pid),
+		Checksum:          pb.CHECKSUM_CHK_HMAC,
+		HeartbeatInterval: time.Millisecond * 200,
+		ReconnectInterval: time.Millisecond * 50,
+	})
+	t.Cleanup(c.Close)
+	if err := c.Connect(); err != nil {
+		t.Fatalf("player[%d] connect error: %v", pid, err)
+	}
+	return c
+}
+
+// waitEvent returns the first event of the type, the others are skipped
+func waitEvent(t *testing.T, c *Client, typ EventType) Event {
+	timeout := time.After(time.Second * 3)
+	for {
+		select {
+		case e, ok := <-c.Events():
+			if !ok {
+				t.Fatalf("events closed while waiting for %s", typ)
+			}
+			if e.Type == typ {
+				return e
+			}
+			if e.Type == EventDisconnected {
+				t.Fatalf("disconnected while waiting for %s: %v", typ, e.Err)
+			}
+		case <-timeout:
+			t.Fatalf("timeout while waiting for %s", typ)
+		}
+	}
+}
+
+// waitInput returns the first frame containing the input of the player
+func waitInput(t *testing.T, c *Client, pid uint64, sid int32) *pb.FrameData {
+	timeout := time.After(time.Second * 3)
+	for {
+		select {
+		case <-timeout:
+			t.Fatalf("timeout while waiting for the input of player[%d]", pid)
+		default:
+		}
+		f := waitEvent(t, c, EventFrame).Frame
+		for _, in := range f.GetInput() {
+			if in.GetId() == pid && in.GetSid() == sid {
+				return f
+			}
+		}
+	}
+}
+
+func Test_Client(t *testing.T) {
+	s := newTestServer(t, 1, 2)
+	c1 := newTestClient(t, s, 1)
+	c2 := newTestClient(t, s, 2)
+
+	for i, c := range []*Client{c1, c2} {
+		if err := c.JoinRoom(); err != nil {
+			t.Fatal(err)
+		}
+		if seat := waitEvent(t, c, EventJoinRoom).JoinRoom.GetRoomseatid(); seat != int32(i+1) {
+			t.Errorf("player[%d] seat[%d] should be [%d]", i+1, seat, i+1)
+		}
+	}
+
+	if err := c1.SendProgress(50); err != nil {
+		t.Fatal(err)
+	}
+	if e := waitEvent(t, c2, EventProgress); e.Progress.GetId() != 1 || e.Progress.GetPro() != 50 {
+		t.Errorf("unexpected progress: %v", e.Progress)
+	}
+
+	for _, c := range []*Client{c1, c2} {
+		if err := c.Ready(); err != nil {
+			t.Fatal(err)
+		}
+		waitEvent(t, c, EventStart)
+	}
+
+	if err := c1.SendInput(7, 1, 2); err != nil {
+		t.Fatal(err)
+	}
+	f1, f2 := waitInput(t, c1, 1, 7), waitInput(t, c2, 1, 7)
+	if f1.GetFrameID() != f2.GetFrameID() {
+		t.Errorf("the input is in frame[%d] and frame[%d]", f1.GetFrameID(), f2.GetFrameID())
+	}
+
+	for _, c := range []*Client{c1, c2} {
+		if err := c.SendResult(1); err != nil {
+			t.Fatal(err)
+		}
+		waitEvent(t, c, EventResult)
+	}
+	waitEvent(t, c1, EventClose)
+	waitEvent(t, c2, EventClose)
+}
+
+func Test_ClientReconnect(t *testing.T) {
+	s := newTestServer(t, 1, 2)
+	c1 := newTestClient(t, s, 1)
+	c2 := newTestClient(t, s, 2)
+	for _, c := range []*Client{c1, c2} {
+		if err := c.Ready(); err != nil {
+			t.Fatal(err)
+		}
+		waitEvent(t, c, EventStart)
+	}
+
+	if err := c2.SendInput(1, 0, 0); err != nil {
+		t.Fatal(err)
+	}
+	before := waitInput(t, c1, 2, 1).GetFrameID()
+	ticket := c1.Ticket()
+
+	// break the connection of player 1, it resumes with the ticket
+	c1.mu.Lock()
+	c1.conn.Close()
+	c1.mu.Unlock()
+	if err := c2.SendInput(2, 0, 0); err != nil {
+		t.Fatal(err)
+	}
+	waitEvent(t, c1, EventReconnected)
+	if c1.Ticket() == ticket {
+		t.Error("the ticket should be replaced after resuming")
+	}
+
+	// the frames during the break are caught up in order
+	after := waitInput(t, c1, 2, 2).GetFrameID()
+	if after <= before {
+		t.Errorf("frame[%d] should be after frame[%d]", after, before)
+	}
+
+	if err := c1.SendInput(3, 0, 0); err != nil {
+		t.Fatal(err)
+	}
+	waitInput(t, c2, 1, 3)
+}
+
+func Test_ClientReconnectWithToken(t *testing.T) {
+	s := newTestServer(t, 1, 2)
+	c1 := newTestClient(t, s, 1)
+	c2 := newTestClient(t, s, 2)
+	for _, c := range []*Client{c1, c2} {
+		if err := c.Ready(); err != nil {
+			t.Fatal(err)
+		}
+		waitEvent(t, c, EventStart)
+	}
+	if err := c2.SendInput(1, 0, 0); err != nil {
+		t.Fatal(err)
+	}
+	waitInput(t, c1, 2, 1)
+
+	// the ticket is rejected as it has been replaced, the client connects with the token
+	replaced := c1.Ticket()
+	c1.mu.Lock()
+	c1.conn.Close()
+	c1.mu.Unlock()
+	waitEvent(t, c1, EventReconnected)
+	c1.mu.Lock()
+	c1.ticket = replaced
+	c1.conn.Close()
+	c1.mu.Unlock()
+	if err := c2.SendInput(2, 0, 0); err != nil {
+		t.Fatal(err)
+	}
+	waitEvent(t, c1, EventReconnected)
+	if c1.Ticket() == replaced {
+		t.Error("the ticket should be issued again after connecting with the token")
+	}
+
+	// the frames are caught up once the player gets ready again
+	waitInput(t, c1, 2, 2)
+	if err := c1.SendInput(3, 0, 0); err != nil {
+		t.Fatal(err)
+	}
+	waitInput(t, c2, 1, 3)
+}
+
+func Test_ClientConnectError(t *testing.T) {
+	s := newTestServer(t, 1)
+	c := New(&Config{
+		Address:  s.Addrs()[0].String(),
+		Dialer:   TCPDialer(time.Second),
+		PlayerID: 3,
+		RoomID:   1,
+		Checksum: pb.CHECKSUM_CHK_CRC32,
+	})
+	defer c.Close()
+
+	err := c.Connect()
+	if ce, ok := err.(*ConnectError); !ok || ce.Code != pb.ERRORCODE_ERR_NoPlayer {
+		t.Errorf("want: ERR_NoPlayer, got: %v", err)
+	}
+}
+
+func Test_ClientBadToken(t *testing.T) {
+	s := newTestServer(t, 1, 2)
+	c := New(&Config{
+		Address:  s.Addrs()[0].String(),
+		Dialer:   TCPDialer(time.Second),
+		PlayerID: 1,
+		RoomID:   1,
+		Token:    s.RoomManager().GetRoom(1).PlayerToken(2),
+		Checksum: pb.CHECKSUM_CHK_CRC32,
+	})
+	defer c.Close()
+
+	// the token of another player is rejected
+	err := c.Connect()
+	if ce, ok := err.(*ConnectError); !ok || ce.Code != pb.ERRORCODE_ERR_Token {
+		t.Errorf("want: ERR_Token, got: %v", err)
+	}
+}
@@ -0,0 +1,48 @@
+package client
+
+import (
+	"net"
+	"time"
+
+	"github.com/hedon954/go-lock-step-server/pkg/kcp_server"
+	"golang.org/x/net/websocket"
+)
+
+// Dialer connects to the address of a lock step server
+type Dialer func(address string) (net.Conn, error)
+
+// KCPDialer dials the kcp listener, config must match the one of the server, nil means DefaultKCPConfig
+func KCPDialer(config *kcp_server.KCPConfig) Dialer {
+	return func(address string) (net.Conn, error) {
+		return kcp_server.Dial(address, config)
+	}
+}
+
+// TCPDialer dials the tcp listener
+func TCPDialer(timeout time.Duration) Dialer {
+	return func(address string) (net.Conn, error) {
+		conn, err := net.DialTimeout("tcp", address, timeout)
+		if err != nil {
+			return nil, err
+		}
+		if tcpConn, ok := conn.(*net.TCPConn); ok {
+			_ = tcpConn.SetNoDelay(true)
+		}
+		return conn, nil
+	}
+}
+
+// WebSocketDialer dials the websocket listener serving the http path
+func WebSocketDialer(path string) Dialer {
+	if path == "" {
+		path = "/"
+	}
+	return func(address string) (net.Conn, error) {
+		conn, err := websocket.Dial("ws://"+address+path, "", "http://"+address+"/")
+		if err != nil {
+			return nil, err
+		}
+		conn.PayloadType = websocket.BinaryFrame
+		return conn, nil
+	}
+}
@@ -0,0 +1,67 @@
+package client
+
+import (
+	"github.com/hedon954/go-lock-step-server/pb"
+)
+
+// EventType is the type of an event delivered by the client
+type EventType int
+
+const (
+	EventJoinRoom     EventType = iota + 1 // the reply of JoinRoom, JoinRoom is set
+	EventProgress                          // the loading progress of another player, Progress is set
+	EventReady                             // the server has accepted Ready
+	EventStart                             // the game starts, Start is set
+	EventFrame                             // a frame, Frame is set, the frames are delivered once in order
+	EventResult                            // the server has accepted SendResult
+	EventClose                             // the room is closed
+	EventReconnected                       // the session has been resumed on a new connection
+	EventDisconnected                      // the connection is lost for good, Err is set, no event follows
+	EventRoster                            // a seat is assigned to another player, Roster is set
+	EventLoadout                           // a seat has submitted its loadout, Loadout is set
+	EventDesync                            // the state hash sent differs from the server's, Checksum is set
+)
+
+func (t EventType) String() string {
+	switch t {
+	case EventJoinRoom:
+		return "JoinRoom"
+	case EventProgress:
+		return "Progress"
+	case EventReady:
+		return "Ready"
+	case EventStart:
+		return "Start"
+	case EventFrame:
+		return "Frame"
+	case EventResult:
+		return "Result"
+	case EventClose:
+		return "Close"
+	case EventReconnected:
+		return "Reconnected"
+	case EventDisconnected:
+		return "Disconnected"
+	case EventRoster:
+		return "Roster"
+	case EventLoadout:
+		return "Loadout"
+	case EventDesync:
+		return "Desync"
+	default:
+		return "Unknown"
+	}
+}
+
+// Event is a message from the server or a change of the connection
+type Event struct {
+	Type     EventType
+	JoinRoom *pb.S2C_JoinRoomMsg
+	Progress *pb.S2C_ProgressMsg
+	Start    *pb.S2C_StartMsg
+	Frame    *pb.FrameData
+	Roster   *pb.S2C_RosterMsg
+	Loadout  *pb.Loadout
+	Checksum *pb.S2C_ChecksumMsg
+	Err      error
+}
@@ -5,10 +5,9 @@ import (
 	"fmt"
 	"time"
 
+	"github.com/hedon954/go-lock-step-server/client"
 	"github.com/hedon954/go-lock-step-server/pb"
-	"github.com/hedon954/go-lock-step-server/pkg/packet/pb_packet"
-	"github.com/xtaci/kcp-go"
-	"google.golang.org/protobuf/proto"
+	"github.com/hedon954/go-lock-step-server/pkg/kcp_server"
 )
 
 var (
@@ -16,6 +15,13 @@ var (
 	msg  = flag.String("msg", "PING", "message you want to send")
 	room = flag.Uint64("room", 1, "room id")
 	id   = flag.Uint64("id", 1, "my id")
+
+	kcpPreset = flag.String("kcp", "default", "kcp preset: default, lan, mobile or lossy")
+	kcpCrypt  = flag.String("kcp_crypt", "", "kcp block crypt: aes or salsa20, empty means disabled")
+	kcpKey    = flag.String("kcp_key", "", "the shared key of the kcp block crypt")
+	kcpFEC    = flag.String("kcp_fec", "", "kcp reed-solomon shards 'data,parity', empty means the preset")
+	token     = flag.String("token", "", "the token of the player in the room, it is replied by /token of the web api")
+	checksum  = flag.String("checksum", "CHK_None", "the packet checksum requested: CHK_None, CHK_CRC32 or CHK_HMAC")
 )
 
 func main() {
@@ -24,71 +30,66 @@ func main() {
 
 	fmt.Println("addr", *addr, "room", *room, "id", *id)
 
-	ms := &pb_packet.MsgProtocol{}
+	requestedChecksum, ok := pb.CHECKSUM_value[*checksum]
+	if !ok {
+		panic(fmt.Sprintf("bad checksum[%s]", *checksum))
+	}
 
-	c, e := kcp.Dial(*addr)
+	config, e := kcp_server.PresetKCPConfig(*kcpPreset)
 	if nil != e {
 		panic(e)
 	}
+	config.Crypt = kcp_server.Crypt(*kcpCrypt)
+	config.Key = *kcpKey
+	if *kcpFEC != "" {
+		if _, e = fmt.Sscanf(*kcpFEC, "%d,%d", &config.DataShards, &config.ParityShards); nil != e {
+			panic(fmt.Sprintf("bad kcp_fec[%s]: %s", *kcpFEC, e.Error()))
+		}
+	}
+
+	c := client.New(&client.Config{
+		Address:  *addr,
+		Dialer:   client.KCPDialer(config),
+		PlayerID: *id,
+		RoomID:   *room,
+		Token:    *token,
+		Checksum: pb.CHECKSUM(requestedChecksum),
+	})
 	defer c.Close()
 
+	// connect
+	if e := c.Connect(); nil != e {
+		panic(fmt.Sprintf("connect error:%s", e.Error()))
+	}
+
 	// read
 	go func() {
-		for {
-			// c.SetReadDeadline(time.Now().Add(10*time.Second))
-			n, e := ms.ReadPacket(c)
-			if nil != e {
-				fmt.Println("read error:", e.Error())
-				return
-			}
-
-			// n.Serialize()
-			ret := n.(*pb_packet.Packet)
-			id := pb.ID(ret.GetMessageID())
-			fmt.Println("receive msg ", id.String())
-			switch id {
-			case pb.ID_MSG_Connect:
-				msg := &pb.S2C_ConnectMsg{}
-				proto.Unmarshal(ret.GetData(), msg)
-				if msg.GetErrorCode() != pb.ERRORCODE_ERR_ok {
-					panic(msg.GetErrorCode())
-				}
-				fmt.Println(msg)
-			case pb.ID_MSG_Frame:
-				msg := &pb.S2C_FrameMsg{}
-				proto.Unmarshal(ret.GetData(), msg)
-				fmt.Println(msg)
+		for e := range c.Events() {
+			fmt.Println("receive event ", e.Type.String())
+			switch e.Type {
+			case client.EventFrame:
+				fmt.Println(e.Frame)
+			case client.EventDisconnected:
+				fmt.Println("disconnected:", e.Err)
 			default:
 
 			}
 		}
 	}()
 
-	// connect
-	if _, e := c.Write(pb_packet.NewPacket(uint8(pb.ID_MSG_Connect), &pb.C2S_ConnectMsg{
-		PlayerID: proto.Uint64(*id),
-		BattleID: proto.Uint64(*room),
-	}).Serialize()); nil != e {
-		panic(fmt.Sprintf("write error:%s", e.Error()))
-	}
-	time.Sleep(time.Second)
-	// ready
-	if _, e := c.Write(pb_packet.NewPacket(uint8(pb.ID_MSG_JoinRoom), nil).Serialize()); nil != e {
+	// join
+	if e := c.JoinRoom(); nil != e {
 		panic(fmt.Sprintf("write error:%s", e.Error()))
 	}
 	time.Sleep(time.Second)
 	// ready
-	if _, e := c.Write(pb_packet.NewPacket(uint8(pb.ID_MSG_Ready), nil).Serialize()); nil != e {
+	if e := c.Ready(); nil != e {
 		panic(fmt.Sprintf("write error:%s", e.Error()))
 	}
 	time.Sleep(time.Second)
 	// write
 	for i := 0; i < 10; i++ {
-		p := pb_packet.NewPacket(uint8(pb.ID_MSG_Input), &pb.C2S_InputMsg{
-			Sid: proto.Int32(int32(i)),
-		})
-
-		if _, e := c.Write(p.Serialize()); nil != e {
+		if e := c.SendInput(int32(i), 0, 0); nil != e {
 			panic(fmt.Sprintf("write error:%s", e.Error()))
 		}
 
@@ -24,6 +24,8 @@ func NewWebAPI(addr string, m *logic.RoomManager) *WebAPI {
 	}
 	http.HandleFunc("/", r.index)
 	http.HandleFunc("/create", r.createRoom)
+	http.HandleFunc("/cancel", r.cancelRoom)
+	http.HandleFunc("/seat", r.assignSeat)
 
 	go func() {
 		fmt.Println("web api listen on", addr)
@@ -80,8 +82,52 @@ func (h *WebAPI) createRoom(w http.ResponseWriter, r *http.Request) {
 	if nil != err {
 		ret = err.Error()
 	} else {
-		ret = fmt.Sprintf("room.ID=[%d] room.Secret=[%s] room.Time=[%d], room.Member=[%v]", room.ID(), room.SecretKey(),
-			room.TimeStamp(), members)
+		tokens := make([]string, 0, len(ps))
+		for _, pid := range ps {
+			tokens = append(tokens, fmt.Sprintf("%d:%s", pid, room.PlayerToken(pid)))
+		}
+		ret = fmt.Sprintf("room.ID=[%d] room.Time=[%d], room.Member=[%v] room.Tokens=[%s]", room.ID(),
+			room.TimeStamp(), members, strings.Join(tokens, " "))
+	}
+
+}
+
+func (h *WebAPI) cancelRoom(w http.ResponseWriter, r *http.Request) {
+
+	ret := "ok"
+
+	defer func() {
+		w.Write([]byte(ret))
+	}()
+
+	roomID, _ := strconv.ParseUint(r.URL.Query().Get("room"), 10, 64)
+	if err := h.m.CancelRoom(roomID); nil != err {
+		ret = err.Error()
 	}
+}
+
+func (h *WebAPI) assignSeat(w http.ResponseWriter, r *http.Request) {
 
+	ret := "error"
+
+	defer func() {
+		w.Write([]byte(ret))
+	}()
+
+	query := r.URL.Query()
+	roomID, _ := strconv.ParseUint(query.Get("room"), 10, 64)
+	seat, _ := strconv.ParseInt(query.Get("seat"), 10, 32)
+	member, _ := strconv.ParseUint(query.Get("member"), 10, 64)
+
+	replaced, err := h.m.AssignSeat(roomID, int32(seat), member)
+	if nil != err {
+		ret = err.Error()
+	} else {
+		var token string
+		if room := h.m.GetRoom(roomID); room != nil {
+			token = room.PlayerToken(member)
+		}
+		ret = fmt.Sprintf("room.ID=[%d] seat=[%d] member=[%d] replaced=[%d] token=[%s]", roomID, seat, member, replaced,
+			token)
+	}
 }
@@ -3,6 +3,7 @@ package main
 import (
 	"flag"
 	"fmt"
+	"net/http"
 	"os"
 	"os/signal"
 	"syscall"
@@ -10,6 +11,13 @@ import (
 
 	"github.com/alecthomas/log4go"
 	"github.com/hedon954/go-lock-step-server/cmd/example_server/api"
+	"github.com/hedon954/go-lock-step-server/logic"
+	"github.com/hedon954/go-lock-step-server/logic/game"
+	"github.com/hedon954/go-lock-step-server/logic/ledger"
+	"github.com/hedon954/go-lock-step-server/logic/matchmaking"
+	"github.com/hedon954/go-lock-step-server/logic/room"
+	"github.com/hedon954/go-lock-step-server/pb"
+	"github.com/hedon954/go-lock-step-server/pkg/kcp_server"
 	"github.com/hedon954/go-lock-step-server/pkg/log4gox"
 	"github.com/hedon954/go-lock-step-server/server"
 )
@@ -17,19 +25,156 @@ import (
 var (
 	httpAddress = flag.String("web", ":80", "web listen address")
 	udpAddress  = flag.String("udp", ":10086", "udp listen address(':10086' means localhost:10086)")
+	kcpPreset   = flag.String("kcp", "default", "kcp preset: default, lan, mobile or lossy")
+	kcpCrypt    = flag.String("kcp_crypt", "", "kcp block crypt: aes or salsa20, empty means disabled")
+	kcpKey      = flag.String("kcp_key", "", "the shared key of the kcp block crypt")
+	kcpFEC      = flag.String("kcp_fec", "", "kcp reed-solomon shards 'data,parity', empty means the preset")
+	tcpAddress  = flag.String("tcp", "", "tcp listen address, empty means disabled")
+	wsAddress   = flag.String("ws", "", "websocket listen address, empty means disabled")
+	wsPath      = flag.String("ws_path", "/", "websocket http path")
 	debugLog    = flag.Bool("log", true, "debug log")
+	maxRooms    = flag.Int("max_rooms", 0, "the maximum number of concurrent rooms, 0 means unlimited")
+	maxPlayers  = flag.Int("max_players", 0, "the maximum number of players in all rooms, 0 means unlimited")
+	nodeAddress = flag.String("node", "", "the address players dial to reach this node in a cluster")
+	directory   = flag.String("directory", "", "the room directory file shared by the nodes of a cluster")
+	rateLimit   = flag.Bool("rate_limit", true, "limit the packet rate of every connection")
+	minClient   = flag.String("min_client", "", "the lowest client version accepted, empty means any")
+	checksum    = flag.String("checksum", "CHK_None", "the packet checksum required: CHK_None accepts any, CHK_CRC32 or CHK_HMAC require one of them")
+	idleTimeout = flag.Duration("idle_timeout", 0, "reap the room if nobody connects to it in time, 0 means disabled")
+	matchSize   = flag.Int("matchmaking", 0, "the players per room of the matchmaking api under /match/, 0 means disabled")
+	matchSecret = flag.String("match_secret", "", "the secret of the player keys of the matchmaking api, see matchmaking.PlayerKey")
+	ledgerFile  = flag.String("ledger", "", "the file of the match ledger served under /ledger/, empty means disabled")
+	spillDir    = flag.String("spill_dir", "", "spill the old frames of the rooms to the dir, empty keeps them in memory")
+	botInterval = flag.Uint("bot_interval", 0, "random bots input every n frames for the players not connected, 0 means no bots")
 )
 
+// logReporter logs the results of the rooms
+type logReporter struct{}
+
+func (l *logReporter) ReportResult(result *room.Result) {
+	log4go.Info("[main] room[%d] quit reason=[%s] winners=[%v]", result.RoomID, result.Reason, result.Winners)
+}
+
 func main() {
 	flag.Parse()
 
 	log4go.Close()
 	log4go.AddFilter("debug logger", log4go.DEBUG, log4gox.NewColorConsoleLogWriter())
 
-	s, err := server.New(*udpAddress)
+	var roomDirectory logic.RoomDirectory
+	if *directory != "" {
+		roomDirectory = logic.NewFileDirectory(*directory)
+		if *nodeAddress == "" {
+			*nodeAddress = *udpAddress
+		}
+	}
+
+	var reporter room.ResultReporter = &logReporter{}
+	if *ledgerFile != "" {
+		store, err := ledger.OpenFileStore(*ledgerFile)
+		if err != nil {
+			panic(err)
+		}
+		l, err := ledger.New(&ledger.Config{Store: store})
+		if err != nil {
+			panic(err)
+		}
+		defer l.Close()
+		reporter = room.Reporters{reporter, l}
+		http.Handle("/ledger/", http.StripPrefix("/ledger", l.Handler()))
+	}
+
+	var rateLimitConfig server.RateLimitConfig
+	if *rateLimit {
+		rateLimitConfig = server.DefaultRateLimitConfig()
+	}
+
+	kcpConfig, err := kcp_server.PresetKCPConfig(*kcpPreset)
 	if err != nil {
 		panic(err)
 	}
+	kcpConfig.Crypt = kcp_server.Crypt(*kcpCrypt)
+	kcpConfig.Key = *kcpKey
+	if *kcpFEC != "" {
+		if _, err = fmt.Sscanf(*kcpFEC, "%d,%d", &kcpConfig.DataShards, &kcpConfig.ParityShards); err != nil {
+			panic(fmt.Sprintf("bad kcp_fec[%s]: %v", *kcpFEC, err))
+		}
+	}
+
+	var listeners []server.ListenerConfig
+	if *udpAddress != "" {
+		listeners = append(listeners, server.ListenerConfig{
+			Transport: server.TransportKCP,
+			Address:   *udpAddress,
+			KCP:       kcpConfig,
+		})
+	}
+	if *tcpAddress != "" {
+		listeners = append(listeners, server.ListenerConfig{Transport: server.TransportTCP, Address: *tcpAddress})
+	}
+	if *wsAddress != "" {
+		listeners = append(listeners, server.ListenerConfig{
+			Transport: server.TransportWebSocket,
+			Address:   *wsAddress,
+			Path:      *wsPath,
+		})
+	}
+
+	requiredChecksum, ok := pb.CHECKSUM_value[*checksum]
+	if !ok {
+		panic(fmt.Sprintf("bad checksum[%s]", *checksum))
+	}
+
+	var bots game.BotFactory
+	if *botInterval > 0 {
+		bots = game.RandomBots(uint32(*botInterval))
+	}
+	var frames func(rid uint64) (game.FrameStore, error)
+	if *spillDir != "" {
+		frames = func(uint64) (game.FrameStore, error) {
+			return game.NewSpillStore(*spillDir, 0)
+		}
+	}
+
+	s, err := server.New(&server.Config{
+		Listeners: listeners,
+		RoomManager: logic.Config{
+			MaxRooms:   *maxRooms,
+			MaxPlayers: *maxPlayers,
+			Room: room.Config{
+				IdleTimeout: *idleTimeout,
+				Reporter:    reporter,
+				Bots:        bots,
+				Frames:      frames,
+			},
+			Directory:   roomDirectory,
+			NodeAddress: *nodeAddress,
+		},
+		RateLimit: rateLimitConfig,
+		Version: server.VersionPolicy{
+			MinClientVersion: *minClient,
+		},
+		Checksum: server.ChecksumPolicy{
+			Required: pb.CHECKSUM(requiredChecksum),
+		},
+	})
+	if err != nil {
+		panic(err)
+	}
+	var matcher *matchmaking.Matcher
+	if *matchSize > 0 {
+		// the matched rooms are numbered from 1<<32 to avoid the rooms created by the web api
+		matcher = matchmaking.New(s.RoomManager(), &matchmaking.Config{
+			PlayersPerRoom: *matchSize,
+			FirstRoomID:    1 << 32,
+			Secret:         *matchSecret,
+		})
+		if *matchSecret == "" {
+			log4go.Warn("[main] the matchmaking api rejects every request without -match_secret")
+		}
+		go matcher.Run()
+		http.Handle("/match/", http.StripPrefix("/match", matcher.Handler()))
+	}
 	_ = api.NewWebAPI(*httpAddress, s.RoomManager())
 
 	sigs := make(chan os.Signal, 1)
@@ -48,8 +193,12 @@ QUIT:
 		case <-ticker.C:
 			// todo
 			fmt.Println("room number ", s.RoomManager().RoomNum())
+			fmt.Println("abusive peers ", s.AbusivePeers())
 		}
 	}
 	log4go.Info("[main] quiting...")
+	if matcher != nil {
+		matcher.Stop()
+	}
 	s.Stop()
 }
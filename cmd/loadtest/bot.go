@@ -0,0 +1,176 @@
+package main
+
+import (
+	"fmt"
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/hedon954/go-lock-step-server/client"
+	"github.com/hedon954/go-lock-step-server/logic/room"
+)
+
+// botConfig is the configuration of a simulated player
+type botConfig struct {
+	client   client.Config
+	apm      int           // inputs per minute
+	duration time.Duration // how long the player sends inputs after the game starts
+	winner   uint64        // the winner reported when the player quits
+}
+
+// bot is a simulated player going through connect, ready and inputs
+type bot struct {
+	config botConfig
+	c      *client.Client
+	stats  *botStats
+
+	mu       sync.Mutex
+	pending  map[int32]time.Time // the inputs not seen in the frames yet
+	started  chan time.Time
+	closed   chan struct{}
+	minDelay time.Duration
+	delays   []time.Duration
+}
+
+func runBot(config botConfig) *botStats {
+	b := &bot{
+		config:  config,
+		stats:   &botStats{roomID: config.client.RoomID},
+		pending: make(map[int32]time.Time),
+		started: make(chan time.Time, 1),
+		closed:  make(chan struct{}),
+	}
+	b.run()
+	return b.stats
+}
+
+func (b *bot) run() {
+	b.c = client.New(&b.config.client)
+	defer b.c.Close()
+
+	if err := b.c.Connect(); err != nil {
+		b.stats.err = fmt.Errorf("connect: %w", err)
+		return
+	}
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		b.handleEvents()
+	}()
+	defer func() {
+		b.c.Close()
+		<-done
+	}()
+
+	for _, step := range []func() error{b.c.JoinRoom, func() error { return b.c.SendProgress(100) }, b.c.Ready} {
+		if err := step(); err != nil {
+			b.stats.err = fmt.Errorf("prepare: %w", err)
+			return
+		}
+	}
+
+	// the game starts once everybody is ready, or it is forced after the ready timeout
+	var start time.Time
+	select {
+	case start = <-b.started:
+	case <-b.closed:
+		b.stats.err = fmt.Errorf("room closed before start")
+		return
+	case <-time.After(time.Minute):
+		b.stats.err = fmt.Errorf("game not started in time")
+		return
+	}
+
+	b.sendInputs(start.Add(b.config.duration))
+
+	// the inputs in flight are counted as received if they arrive in the grace period
+	time.Sleep(time.Second)
+	_ = b.c.SendResult(b.config.winner)
+	select {
+	case <-b.closed:
+	case <-time.After(time.Second * 5):
+	}
+
+	b.mu.Lock()
+	b.stats.dropped = len(b.pending)
+	for _, d := range b.delays {
+		b.stats.jitter = append(b.stats.jitter, d-b.minDelay)
+	}
+	b.mu.Unlock()
+}
+
+// sendInputs sends the inputs at the configured APM with a random phase until the deadline
+func (b *bot) sendInputs(deadline time.Time) {
+	interval := time.Minute / time.Duration(b.config.apm)
+	timer := time.NewTimer(time.Duration(rand.Int63n(int64(interval))))
+	defer timer.Stop()
+
+	for sid := int32(1); ; sid++ {
+		select {
+		case <-timer.C:
+		case <-b.closed:
+			return
+		}
+		if time.Now().After(deadline) {
+			return
+		}
+
+		b.mu.Lock()
+		b.pending[sid] = time.Now()
+		b.mu.Unlock()
+		if err := b.c.SendInput(sid, rand.Int31n(1000), rand.Int31n(1000)); err != nil {
+			b.mu.Lock()
+			delete(b.pending, sid)
+			b.mu.Unlock()
+			b.stats.sendErrors++
+		} else {
+			b.stats.sent++
+		}
+		timer.Reset(interval)
+	}
+}
+
+// handleEvents measures the frames until the events channel is closed
+func (b *bot) handleEvents() {
+	var start time.Time
+	closeOnce := sync.Once{}
+	for e := range b.c.Events() {
+		now := time.Now()
+		switch e.Type {
+		case client.EventStart:
+			if start.IsZero() {
+				start = now
+				b.started <- now
+			}
+		case client.EventFrame:
+			b.stats.frames++
+			b.mu.Lock()
+			// the delay of the frame compared to the tick schedule since the start
+			delay := now.Sub(start) - time.Duration(e.Frame.GetFrameID())*room.TickTimer
+			if len(b.delays) == 0 || delay < b.minDelay {
+				b.minDelay = delay
+			}
+			b.delays = append(b.delays, delay)
+			for _, in := range e.Frame.GetInput() {
+				if in.GetId() != b.config.client.PlayerID {
+					continue
+				}
+				if sent, ok := b.pending[in.GetSid()]; ok {
+					b.stats.latency = append(b.stats.latency, now.Sub(sent))
+					b.stats.received++
+					delete(b.pending, in.GetSid())
+				}
+			}
+			b.mu.Unlock()
+		case client.EventReconnected:
+			b.stats.reconnects++
+		case client.EventDisconnected:
+			b.stats.disconnects++
+			closeOnce.Do(func() { close(b.closed) })
+		case client.EventClose:
+			closeOnce.Do(func() { close(b.closed) })
+		}
+	}
+	closeOnce.Do(func() { close(b.closed) })
+}
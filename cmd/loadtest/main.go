@@ -0,0 +1,219 @@
+// Command loadtest simulates many rooms of players against a lock step server on localhost,
+// and reports the frame latency, the tick jitter, the drops and the server cpu per room.
+//
+// The server runs in a child process by default, so that its cpu is measured alone,
+// and the rooms are created through its http api. With -inprocess, the server runs in this process
+// and the rooms are created through the room manager, the server cpu is not reported then.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/alecthomas/log4go"
+	"github.com/hedon954/go-lock-step-server/client"
+	"github.com/hedon954/go-lock-step-server/pkg/log4gox"
+	"github.com/hedon954/go-lock-step-server/server"
+)
+
+const kWebSocketPath = "/ws"
+
+var (
+	rooms     = flag.Int("rooms", 10, "the number of rooms")
+	players   = flag.Int("players", 2, "the number of players per room")
+	apm       = flag.Int("apm", 120, "the inputs per minute of every player")
+	duration  = flag.Duration("duration", time.Second*30, "how long the players send inputs after the game starts")
+	transport = flag.String("transport", "kcp", "the transport: kcp, tcp or ws")
+	inprocess = flag.Bool("inprocess", false, "run the server in this process and create the rooms by the room manager")
+	perRoom   = flag.Bool("per_room", false, "report every room")
+	logLevel  = flag.String("log", "ERROR", "the log level of the server: DEBUG, INFO, WARNING or ERROR")
+	firstRoom = flag.Uint64("first_room", 1, "the id of the first room")
+
+	// the serve mode is used by the child process running the server
+	serveMode = flag.Bool("serve", false, "run the server under test only")
+	address   = flag.String("addr", "127.0.0.1:10086", "the listen address in the serve mode")
+	web       = flag.String("web", "127.0.0.1:8080", "the http api address in the serve mode")
+)
+
+var logLevels = map[string]log4go.Level{
+	"DEBUG":   log4go.DEBUG,
+	"INFO":    log4go.INFO,
+	"WARNING": log4go.WARNING,
+	"ERROR":   log4go.ERROR,
+}
+
+func main() {
+	flag.Parse()
+
+	level, ok := logLevels[*logLevel]
+	if !ok {
+		fail("bad log level[%s]", *logLevel)
+	}
+	t := server.Transport(*transport)
+	var dialer client.Dialer
+	switch t {
+	case server.TransportKCP:
+		dialer = client.KCPDialer(nil)
+	case server.TransportTCP:
+		dialer = client.TCPDialer(time.Second * 5)
+	case server.TransportWebSocket:
+		dialer = client.WebSocketDialer(kWebSocketPath)
+	default:
+		fail("bad transport[%s]", *transport)
+	}
+
+	if *serveMode {
+		serve(t, *address, *web, level)
+		return
+	}
+
+	if *rooms <= 0 || *players <= 0 || *apm <= 0 {
+		fail("rooms, players and apm must be positive")
+	}
+	if *duration >= time.Minute*3 {
+		fail("the game times out after 3 minutes, duration must be shorter")
+	}
+
+	var (
+		createRoom func(rid uint64, players []uint64) (map[uint64]string, error)
+		serverAddr string
+		stopServer func() (time.Duration, error)
+	)
+	if *inprocess {
+		log4go.Close()
+		log4go.AddFilter("server logger", level, log4gox.NewColorConsoleLogWriter())
+		s, err := newServer(t, "127.0.0.1:0", *rooms)
+		if err != nil {
+			fail("start server: %v", err)
+		}
+		serverAddr = s.Addrs()[0].String()
+		createRoom = func(rid uint64, players []uint64) (map[uint64]string, error) {
+			r, err := s.RoomManager().CreateRoom(rid, 0, players, 0, "loadtest")
+			if err != nil {
+				return nil, err
+			}
+			tokens := make(map[uint64]string, len(players))
+			for _, pid := range players {
+				tokens[pid] = r.PlayerToken(pid)
+			}
+			return tokens, nil
+		}
+		stopServer = func() (time.Duration, error) {
+			s.Stop()
+			return 0, nil
+		}
+	} else {
+		p, err := startServerProcess(t, *logLevel)
+		if err != nil {
+			fail("start server: %v", err)
+		}
+		serverAddr = p.address
+		createRoom = p.createRoom
+		stopServer = p.stop
+	}
+
+	fmt.Printf("rooms=%d players=%d apm=%d duration=%s transport=%s server=%s inprocess=%v\n",
+		*rooms, *players, *apm, *duration, t, serverAddr, *inprocess)
+
+	var wg sync.WaitGroup
+	results := make(chan *botStats, *rooms**players)
+	begin := time.Now()
+	for i := 0; i < *rooms; i++ {
+		rid := *firstRoom + uint64(i)
+		pids := make([]uint64, *players)
+		for j := range pids {
+			pids[j] = uint64(i**players + j + 1)
+		}
+		tokens, err := createRoom(rid, pids)
+		if err != nil {
+			_, _ = stopServer()
+			fail("create room[%d]: %v", rid, err)
+		}
+
+		for _, pid := range pids {
+			wg.Add(1)
+			go func(rid, pid, winner uint64) {
+				defer wg.Done()
+				results <- runBot(botConfig{
+					client: client.Config{
+						Address:  serverAddr,
+						Dialer:   dialer,
+						PlayerID: pid,
+						RoomID:   rid,
+						Token:    tokens[pid],
+					},
+					apm:      *apm,
+					duration: *duration,
+					winner:   winner,
+				})
+			}(rid, pid, pids[0])
+		}
+	}
+	wg.Wait()
+	close(results)
+	elapsed := time.Since(begin)
+
+	cpu, err := stopServer()
+	if err != nil {
+		fmt.Println("stop server error:", err)
+	}
+	report(results, elapsed, cpu)
+}
+
+// report prints the stats of all rooms and every room if -per_room is set
+func report(results chan *botStats, elapsed time.Duration, cpu time.Duration) {
+	total := &botStats{}
+	byRoom := make(map[uint64]*botStats)
+	var errs []string
+	for s := range results {
+		total.merge(s)
+		if byRoom[s.roomID] == nil {
+			byRoom[s.roomID] = &botStats{roomID: s.roomID}
+		}
+		byRoom[s.roomID].merge(s)
+		if s.err != nil {
+			errs = append(errs, fmt.Sprintf("room[%d]: %v", s.roomID, s.err))
+		}
+	}
+
+	if *perRoom {
+		ids := make([]uint64, 0, len(byRoom))
+		for id := range byRoom {
+			ids = append(ids, id)
+		}
+		sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+		for _, id := range ids {
+			s := byRoom[id]
+			fmt.Printf("room[%d] sent=%d dropped=%d frames=%d latency p99=%v jitter p99=%v\n", id, s.sent, s.dropped,
+				s.frames, s.latency.percentile(99).Round(time.Microsecond), s.jitter.percentile(99).Round(time.Microsecond))
+		}
+	}
+
+	fmt.Printf("elapsed: %s\n", elapsed.Round(time.Millisecond))
+	fmt.Printf("inputs: sent=%d received=%d dropped=%d send_errors=%d\n", total.sent, total.received, total.dropped,
+		total.sendErrors)
+	fmt.Printf("connections: disconnects=%d reconnects=%d failed_players=%d\n", total.disconnects, total.reconnects,
+		len(errs))
+	fmt.Printf("frames: %d\n", total.frames)
+	fmt.Printf("frame latency: %s\n", total.latency)
+	fmt.Printf("tick jitter: %s\n", total.jitter)
+	if cpu > 0 {
+		fmt.Printf("server cpu: total=%s per_room=%s (%.2f%% of a core per room)\n", cpu.Round(time.Millisecond),
+			(cpu / time.Duration(*rooms)).Round(time.Millisecond),
+			float64(cpu)/float64(elapsed)/float64(*rooms)*100)
+	} else {
+		fmt.Println("server cpu: n/a")
+	}
+	for _, e := range errs {
+		fmt.Println("error:", e)
+	}
+}
+
+func fail(format string, args ...interface{}) {
+	fmt.Fprintf(os.Stderr, format+"\n", args...)
+	os.Exit(2)
+}
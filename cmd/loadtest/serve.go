@@ -0,0 +1,183 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"os/exec"
+	"os/signal"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/alecthomas/log4go"
+	"github.com/hedon954/go-lock-step-server/cmd/example_server/api"
+	"github.com/hedon954/go-lock-step-server/logic"
+	"github.com/hedon954/go-lock-step-server/pkg/log4gox"
+	"github.com/hedon954/go-lock-step-server/server"
+)
+
+// newServer creates the server under test listening on the address with the transport
+func newServer(transport server.Transport, address string, maxRooms int) (*server.LockStepServer, error) {
+	return server.New(&server.Config{
+		Listeners: []server.ListenerConfig{{
+			Transport: transport,
+			Address:   address,
+			Path:      kWebSocketPath,
+		}},
+		RoomManager: logic.Config{MaxRooms: maxRooms},
+	})
+}
+
+// serve runs the server under test until it is interrupted, it is the child process of the load test
+func serve(transport server.Transport, address, web string, logLevel log4go.Level) {
+	log4go.Close()
+	log4go.AddFilter("server logger", logLevel, log4gox.NewColorConsoleLogWriter())
+
+	s, err := newServer(transport, address, 0)
+	if err != nil {
+		panic(err)
+	}
+	_ = api.NewWebAPI(web, s.RoomManager())
+
+	sigs := make(chan os.Signal, 1)
+	signal.Notify(sigs, syscall.SIGINT, syscall.SIGTERM, os.Interrupt)
+	<-sigs
+	s.Stop()
+}
+
+// serverProcess is the server under test running in a child process, so that its cpu is measured alone
+type serverProcess struct {
+	cmd     *exec.Cmd
+	address string
+	web     string
+}
+
+// startServerProcess runs this command in the serve mode on the free localhost ports
+func startServerProcess(transport server.Transport, logLevel string) (*serverProcess, error) {
+	network := "tcp"
+	if transport == server.TransportKCP {
+		network = "udp"
+	}
+	address, err := freeAddress(network)
+	if err != nil {
+		return nil, err
+	}
+	web, err := freeAddress("tcp")
+	if err != nil {
+		return nil, err
+	}
+
+	exe, err := os.Executable()
+	if err != nil {
+		return nil, err
+	}
+	cmd := exec.Command(exe, "-serve", "-transport", string(transport), "-addr", address, "-web", web,
+		"-log", logLevel)
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	if err = cmd.Start(); err != nil {
+		return nil, err
+	}
+	return &serverProcess{cmd: cmd, address: address, web: web}, nil
+}
+
+// createRoom creates the room through the http api, it retries until the api is up,
+// and returns the tokens of the players
+func (p *serverProcess) createRoom(rid uint64, players []uint64) (map[uint64]string, error) {
+	members := make([]string, 0, len(players))
+	for _, pid := range players {
+		members = append(members, fmt.Sprint(pid))
+	}
+	query := url.Values{}
+	query.Set("room", fmt.Sprint(rid))
+	query.Set("member", strings.Join(members, ","))
+	u := "http://" + p.web + "/create?" + query.Encode()
+
+	deadline := time.Now().Add(time.Second * 10)
+	for {
+		body, err := get(u)
+		if err != nil {
+			if time.Now().After(deadline) {
+				return nil, err
+			}
+			time.Sleep(time.Millisecond * 100)
+			continue
+		}
+		if !strings.HasPrefix(body, "room.ID=") {
+			return nil, fmt.Errorf("create room[%d]: %s", rid, body)
+		}
+		return parseTokens(rid, body, players)
+	}
+}
+
+// parseTokens returns the tokens of the players in room.Tokens=[pid:token ...] of the created room
+func parseTokens(rid uint64, body string, players []uint64) (map[uint64]string, error) {
+	const prefix = "room.Tokens=["
+	i := strings.Index(body, prefix)
+	if i < 0 || !strings.HasSuffix(body, "]") {
+		return nil, fmt.Errorf("create room[%d] without tokens: %s", rid, body)
+	}
+	tokens := make(map[uint64]string, len(players))
+	for _, field := range strings.Fields(body[i+len(prefix) : len(body)-1]) {
+		pid, token, ok := strings.Cut(field, ":")
+		if !ok {
+			return nil, fmt.Errorf("create room[%d] with bad token: %s", rid, field)
+		}
+		id, err := strconv.ParseUint(pid, 10, 64)
+		if err != nil {
+			return nil, err
+		}
+		tokens[id] = token
+	}
+	for _, pid := range players {
+		if _, ok := tokens[pid]; !ok {
+			return nil, fmt.Errorf("create room[%d] without the token of player[%d]", rid, pid)
+		}
+	}
+	return tokens, nil
+}
+
+// get returns the body of the http response
+func get(u string) (string, error) {
+	resp, err := http.Get(u)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	return string(body), err
+}
+
+// stop interrupts the server and returns the cpu time it has used
+func (p *serverProcess) stop() (time.Duration, error) {
+	if err := p.cmd.Process.Signal(os.Interrupt); err != nil {
+		return 0, err
+	}
+	if err := p.cmd.Wait(); err != nil {
+		return 0, err
+	}
+	return p.cmd.ProcessState.UserTime() + p.cmd.ProcessState.SystemTime(), nil
+}
+
+// freeAddress returns a localhost address nobody listens on
+func freeAddress(network string) (string, error) {
+	if network == "udp" {
+		c, err := net.ListenPacket("udp", "127.0.0.1:0")
+		if err != nil {
+			return "", err
+		}
+		defer c.Close()
+		return c.LocalAddr().String(), nil
+	}
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return "", err
+	}
+	defer l.Close()
+	return l.Addr().String(), nil
+}
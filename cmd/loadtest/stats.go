@@ -0,0 +1,57 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"time"
+)
+
+// durations is a sample of durations
+type durations []time.Duration
+
+// percentile returns the p-th percentile(0~100) of the sample, it sorts the sample
+func (d durations) percentile(p float64) time.Duration {
+	if len(d) == 0 {
+		return 0
+	}
+	sort.Slice(d, func(i, j int) bool { return d[i] < d[j] })
+	idx := int(float64(len(d)-1) * p / 100)
+	return d[idx]
+}
+
+func (d durations) String() string {
+	if len(d) == 0 {
+		return "n/a"
+	}
+	return fmt.Sprintf("p50=%v p90=%v p99=%v max=%v (n=%d)", d.percentile(50).Round(time.Microsecond),
+		d.percentile(90).Round(time.Microsecond), d.percentile(99).Round(time.Microsecond),
+		d.percentile(100).Round(time.Microsecond), len(d))
+}
+
+// botStats is what a simulated player observes
+type botStats struct {
+	roomID      uint64
+	sent        int       // inputs sent
+	received    int       // own inputs seen in the frames
+	dropped     int       // own inputs never seen in the frames
+	sendErrors  int       // inputs failed to be sent
+	disconnects int       // connections lost for good
+	reconnects  int       // sessions resumed
+	frames      int       // frames received
+	latency     durations // from sending an input to receiving the frame containing it
+	jitter      durations // how late each frame arrives compared to the earliest one on the tick schedule
+	err         error     // the error stopping the player early
+}
+
+// merge adds the stats of another player
+func (s *botStats) merge(o *botStats) {
+	s.sent += o.sent
+	s.received += o.received
+	s.dropped += o.dropped
+	s.sendErrors += o.sendErrors
+	s.disconnects += o.disconnects
+	s.reconnects += o.reconnects
+	s.frames += o.frames
+	s.latency = append(s.latency, o.latency...)
+	s.jitter = append(s.jitter, o.jitter...)
+}
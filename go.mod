@@ -4,6 +4,8 @@ go 1.18
 
 require (
 	github.com/alecthomas/log4go v0.0.0-20180109082532-d146e6b86faa
+	github.com/xtaci/kcp-go v5.4.20+incompatible
+	golang.org/x/net v0.4.0
 	google.golang.org/protobuf v1.28.1
 )
 
@@ -14,8 +16,6 @@ require (
 	github.com/templexxx/cpufeat v0.0.0-20180724012125-cef66df7f161 // indirect
 	github.com/templexxx/xor v0.0.0-20191217153810-f85b25db303b // indirect
 	github.com/tjfoc/gmsm v1.4.1 // indirect
-	github.com/xtaci/kcp-go v5.4.20+incompatible // indirect
 	golang.org/x/crypto v0.4.0 // indirect
-	golang.org/x/net v0.4.0 // indirect
 	golang.org/x/sys v0.3.0 // indirect
 )
@@ -0,0 +1,190 @@
+package logic
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+)
+
+var (
+	ErrRoomOwned = errors.New("room is owned by another node")
+)
+
+// RoomDirectory records which node owns which room,
+// so that the nodes of a cluster can route the players to the right node
+type RoomDirectory interface {
+	// Register claims the room for the node,
+	// it returns an error wrapping ErrRoomOwned if the room is owned by another node
+	Register(rid uint64, node string) error
+
+	// Unregister releases the room if it is owned by the node
+	Unregister(rid uint64, node string) error
+
+	// Lookup returns the node which owns the room
+	Lookup(rid uint64) (node string, ok bool, err error)
+}
+
+// MemoryDirectory is a RoomDirectory kept in memory,
+// it can be shared by the room managers in the same process
+type MemoryDirectory struct {
+	rooms map[uint64]string
+	rw    sync.RWMutex
+}
+
+// NewMemoryDirectory creates a new in-memory room directory
+func NewMemoryDirectory() *MemoryDirectory {
+	return &MemoryDirectory{
+		rooms: make(map[uint64]string),
+	}
+}
+
+func (d *MemoryDirectory) Register(rid uint64, node string) error {
+	d.rw.Lock()
+	defer d.rw.Unlock()
+
+	return register(d.rooms, rid, node)
+}
+
+func (d *MemoryDirectory) Unregister(rid uint64, node string) error {
+	d.rw.Lock()
+	defer d.rw.Unlock()
+
+	unregister(d.rooms, rid, node)
+	return nil
+}
+
+func (d *MemoryDirectory) Lookup(rid uint64) (string, bool, error) {
+	d.rw.RLock()
+	defer d.rw.RUnlock()
+
+	node, ok := d.rooms[rid]
+	return node, ok, nil
+}
+
+// FileDirectory is a RoomDirectory stored in a json file,
+// every operation reloads the file so that several processes on the same machine can share it.
+// It is not safe against concurrent writers in different processes, use it for tests only
+type FileDirectory struct {
+	path string
+	mu   sync.Mutex
+}
+
+// NewFileDirectory creates a room directory stored in the file of path
+func NewFileDirectory(path string) *FileDirectory {
+	return &FileDirectory{
+		path: path,
+	}
+}
+
+func (d *FileDirectory) Register(rid uint64, node string) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	rooms, err := d.load()
+	if err != nil {
+		return err
+	}
+	if err = register(rooms, rid, node); err != nil {
+		return err
+	}
+	return d.save(rooms)
+}
+
+func (d *FileDirectory) Unregister(rid uint64, node string) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	rooms, err := d.load()
+	if err != nil {
+		return err
+	}
+	unregister(rooms, rid, node)
+	return d.save(rooms)
+}
+
+func (d *FileDirectory) Lookup(rid uint64) (string, bool, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	rooms, err := d.load()
+	if err != nil {
+		return "", false, err
+	}
+	node, ok := rooms[rid]
+	return node, ok, nil
+}
+
+// load reads the rooms from the file, a missing file means an empty directory
+func (d *FileDirectory) load() (map[uint64]string, error) {
+	rooms := make(map[uint64]string)
+
+	data, err := os.ReadFile(d.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return rooms, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if len(data) == 0 {
+		return rooms, nil
+	}
+
+	// json object keys are strings
+	raw := make(map[string]string)
+	if err = json.Unmarshal(data, &raw); err != nil {
+		return nil, fmt.Errorf("room directory[%s] is broken: %w", d.path, err)
+	}
+	for k, v := range raw {
+		rid, err := strconv.ParseUint(k, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("room directory[%s] has a bad room id[%s]", d.path, k)
+		}
+		rooms[rid] = v
+	}
+	return rooms, nil
+}
+
+// save writes the rooms to a temporary file and renames it, so that readers never see a partial file
+func (d *FileDirectory) save(rooms map[uint64]string) error {
+	raw := make(map[string]string, len(rooms))
+	for rid, node := range rooms {
+		raw[strconv.FormatUint(rid, 10)] = node
+	}
+	data, err := json.Marshal(raw)
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(d.path), filepath.Base(d.path)+".tmp*")
+	if err != nil {
+		return err
+	}
+	if _, err = tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err = tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), d.path)
+}
+
+func register(rooms map[uint64]string, rid uint64, node string) error {
+	if owner, ok := rooms[rid]; ok && owner != node {
+		return fmt.Errorf("%w: room id[%d] node[%s]", ErrRoomOwned, rid, owner)
+	}
+	rooms[rid] = node
+	return nil
+}
+
+func unregister(rooms map[uint64]string, rid uint64, node string) {
+	if owner, ok := rooms[rid]; ok && owner == node {
+		delete(rooms, rid)
+	}
+}
@@ -0,0 +1,155 @@
+package logic
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+)
+
+func testRoomDirectory(t *testing.T, d RoomDirectory) {
+	if _, ok, err := d.Lookup(1); err != nil || ok {
+		t.Fatalf("empty directory lookup: ok[%v] err[%v]", ok, err)
+	}
+
+	if err := d.Register(1, "node-a:10086"); err != nil {
+		t.Fatal(err)
+	}
+	// registering again on the same node is allowed
+	if err := d.Register(1, "node-a:10086"); err != nil {
+		t.Errorf("register again on the owner: %v", err)
+	}
+	if err := d.Register(1, "node-b:10086"); !errors.Is(err, ErrRoomOwned) {
+		t.Errorf("err[%v] should be [%v]", err, ErrRoomOwned)
+	}
+
+	node, ok, err := d.Lookup(1)
+	if err != nil || !ok || node != "node-a:10086" {
+		t.Errorf("want: node-a:10086, got: node[%s] ok[%v] err[%v]", node, ok, err)
+	}
+
+	// only the owner can release the room
+	if err = d.Unregister(1, "node-b:10086"); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok, _ = d.Lookup(1); !ok {
+		t.Error("room should still be owned by node-a")
+	}
+	if err = d.Unregister(1, "node-a:10086"); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok, _ = d.Lookup(1); ok {
+		t.Error("room should be released")
+	}
+	if err = d.Register(1, "node-b:10086"); err != nil {
+		t.Errorf("released room should be registered by another node: %v", err)
+	}
+}
+
+func Test_MemoryDirectory(t *testing.T) {
+	testRoomDirectory(t, NewMemoryDirectory())
+}
+
+func Test_FileDirectory(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "rooms.json")
+	testRoomDirectory(t, NewFileDirectory(path))
+
+	// another process sees the same rooms
+	other := NewFileDirectory(path)
+	node, ok, err := other.Lookup(1)
+	if err != nil || !ok || node != "node-b:10086" {
+		t.Errorf("want: node-b:10086, got: node[%s] ok[%v] err[%v]", node, ok, err)
+	}
+}
+
+func Test_RoomManagerDirectory(t *testing.T) {
+	d := NewMemoryDirectory()
+	a := NewRoomManager(&Config{Directory: d, NodeAddress: "node-a:10086"})
+	defer a.Stop()
+	b := NewRoomManager(&Config{Directory: d, NodeAddress: "node-b:10086"})
+	defer b.Stop()
+
+	if _, err := a.CreateRoom(1, 0, []uint64{1, 2}, 0, "test"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := b.CreateRoom(1, 0, []uint64{1, 2}, 0, "test"); !errors.Is(err, ErrRoomOwned) {
+		t.Errorf("err[%v] should be [%v]", err, ErrRoomOwned)
+	}
+
+	if node, ok := b.RoomOwner(1); !ok || node != "node-a:10086" {
+		t.Errorf("want: node-a:10086, got: node[%s] ok[%v]", node, ok)
+	}
+	if _, ok := a.RoomOwner(1); ok {
+		t.Error("the owner should not redirect to itself")
+	}
+
+	if err := a.CancelRoom(1); err != nil {
+		t.Fatal(err)
+	}
+	waitRoomNum(t, a, 0)
+	if _, ok := b.RoomOwner(1); ok {
+		t.Error("room should be released once it quits")
+	}
+}
+
+// blockingDirectory blocks Register until it is released, and fails it if err is set
+type blockingDirectory struct {
+	*MemoryDirectory
+	entered chan struct{}
+	release chan struct{}
+	err     error
+}
+
+func (d *blockingDirectory) Register(rid uint64, node string) error {
+	d.entered <- struct{}{}
+	<-d.release
+	if d.err != nil {
+		return d.err
+	}
+	return d.MemoryDirectory.Register(rid, node)
+}
+
+func Test_RoomManagerRegisterUnlocked(t *testing.T) {
+	d := &blockingDirectory{
+		MemoryDirectory: NewMemoryDirectory(),
+		entered:         make(chan struct{}),
+		release:         make(chan struct{}),
+		err:             errors.New("register failed"),
+	}
+	rm := NewRoomManager(&Config{Directory: d, NodeAddress: "node-a:10086", MaxRooms: 1})
+	defer rm.Stop()
+
+	errs := make(chan error)
+	go func() {
+		_, err := rm.CreateRoom(1, 0, []uint64{1, 2}, 0, "test")
+		errs <- err
+	}()
+	<-d.entered
+
+	// the lookups go on while the room is registered, and the room reserved is counted
+	if r := rm.GetRoom(1); r != nil {
+		t.Error("the room registering should not be found")
+	}
+	if _, err := rm.CreateRoom(1, 0, []uint64{1, 2}, 0, "test"); err == nil {
+		t.Error("the room registering should not be created twice")
+	}
+	if n := rm.PlayerNum(); n != 2 {
+		t.Errorf("PlayerNum[%d] should be [2]", n)
+	}
+
+	// the reservation is given back once the registration fails
+	close(d.release)
+	if err := <-errs; err != d.err {
+		t.Errorf("err[%v] should be [%v]", err, d.err)
+	}
+	if n := rm.PlayerNum(); n != 0 {
+		t.Errorf("PlayerNum[%d] should be [0]", n)
+	}
+	d.err = nil
+	go func() { <-d.entered }()
+	if _, err := rm.CreateRoom(1, 0, []uint64{1, 2}, 0, "test"); err != nil {
+		t.Fatal(err)
+	}
+	if node, ok, _ := d.Lookup(1); !ok || node != "node-a:10086" {
+		t.Errorf("want: node-a:10086, got: node[%s] ok[%v]", node, ok)
+	}
+}
@@ -0,0 +1,56 @@
+package game
+
+import (
+	"math/rand"
+
+	"github.com/hedon954/go-lock-step-server/pb"
+	"google.golang.org/protobuf/proto"
+)
+
+// Bot plays the seat of a player who has never connected or has disconnected,
+// the seat is handed back to the player once they connect again
+type Bot interface {
+	// OnFrame is given every frame once it is over, it returns the input of the seat for the next frame,
+	// nil means no input
+	OnFrame(frame *pb.FrameData) *pb.C2S_InputMsg
+}
+
+// BotFactory creates the bot taking over the seat of the player, a nil bot leaves the seat empty
+type BotFactory func(pid uint64, seat int32) Bot
+
+// RandomBot inputs a random position every interval frames
+type RandomBot struct {
+	rand     *rand.Rand
+	interval uint32
+	sid      int32
+}
+
+// NewRandomBot creates a random bot, an interval of 0 is taken as 1
+func NewRandomBot(seed int64, interval uint32) *RandomBot {
+	if interval == 0 {
+		interval = 1
+	}
+	return &RandomBot{
+		rand:     rand.New(rand.NewSource(seed)),
+		interval: interval,
+	}
+}
+
+// RandomBots creates a RandomBot seeded by the player id for every seat
+func RandomBots(interval uint32) BotFactory {
+	return func(pid uint64, _ int32) Bot {
+		return NewRandomBot(int64(pid), interval)
+	}
+}
+
+func (b *RandomBot) OnFrame(frame *pb.FrameData) *pb.C2S_InputMsg {
+	if frame.GetFrameID()%b.interval != 0 {
+		return nil
+	}
+	b.sid++
+	return &pb.C2S_InputMsg{
+		Sid: proto.Int32(b.sid),
+		X:   proto.Int32(b.rand.Int31n(1000)),
+		Y:   proto.Int32(b.rand.Int31n(1000)),
+	}
+}
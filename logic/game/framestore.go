@@ -0,0 +1,172 @@
+package game
+
+import (
+	"errors"
+	"os"
+
+	"github.com/hedon954/go-lock-step-server/pb"
+	"google.golang.org/protobuf/proto"
+)
+
+// DefaultMemoryFrames is the number of the latest frames a SpillStore keeps in memory if memFrames is 0,
+// it is 10 seconds of the room's ticks
+const DefaultMemoryFrames = 300
+
+var ErrFrameLost = errors.New("frame is lost as it failed to spill")
+
+// FrameStore keeps the inputs of the frames over, a game appends them in order from frame 0,
+// and reads them back to broadcast them and to catch up the players reconnecting.
+// A game never has more than MaxGameFrame+1 frames
+type FrameStore interface {
+	// Append keeps the inputs of the frame Len(), nil means no input
+	Append(cmds []*pb.InputData) error
+
+	// Get returns the inputs of the frame, nil if it has no input or it is not appended yet
+	Get(frameID uint32) ([]*pb.InputData, error)
+
+	// Len returns the number of the frames appended
+	Len() uint32
+
+	// Reset drops all the frames
+	Reset() error
+
+	// Close releases the store
+	Close() error
+}
+
+// SliceStore keeps all the frames in memory indexed by their ids, a frame without input takes a nil slice only
+type SliceStore struct {
+	frames [][]*pb.InputData
+}
+
+func NewSliceStore() *SliceStore {
+	return &SliceStore{}
+}
+
+func (s *SliceStore) Append(cmds []*pb.InputData) error {
+	s.frames = append(s.frames, cmds)
+	return nil
+}
+
+func (s *SliceStore) Get(frameID uint32) ([]*pb.InputData, error) {
+	if frameID >= uint32(len(s.frames)) {
+		return nil, nil
+	}
+	return s.frames[frameID], nil
+}
+
+func (s *SliceStore) Len() uint32 {
+	return uint32(len(s.frames))
+}
+
+func (s *SliceStore) Reset() error {
+	s.frames = s.frames[:0]
+	return nil
+}
+
+func (s *SliceStore) Close() error {
+	s.frames = nil
+	return nil
+}
+
+// SpillStore keeps the latest frames in a ring in memory, and spills the older ones to a temporary file,
+// the memory holds the ring and an offset of every frame spilled.
+// A frame failing to spill is lost, the frames broadcast are read from the ring still
+type SpillStore struct {
+	file    *os.File
+	ring    [][]*pb.InputData // frame i is ring[i%len(ring)]
+	count   uint32
+	offsets []int64 // frame i spilled is in the file [offsets[i], offsets[i+1]), an empty range means lost
+	buff    []byte
+}
+
+// NewSpillStore creates a store spilling to a temporary file in dir, the file is removed once closed.
+// memFrames is the number of the latest frames kept in memory, 0 means DefaultMemoryFrames
+func NewSpillStore(dir string, memFrames int) (*SpillStore, error) {
+	if memFrames <= 0 {
+		memFrames = DefaultMemoryFrames
+	}
+	f, err := os.CreateTemp(dir, "frames-*")
+	if err != nil {
+		return nil, err
+	}
+	return &SpillStore{
+		file:    f,
+		ring:    make([][]*pb.InputData, memFrames),
+		offsets: []int64{0},
+	}, nil
+}
+
+func (s *SpillStore) Append(cmds []*pb.InputData) error {
+	var err error
+	slot := int(s.count % uint32(len(s.ring)))
+	if s.count >= uint32(len(s.ring)) {
+		// the frame leaving the ring is the next one to spill
+		err = s.spill(s.count-uint32(len(s.ring)), s.ring[slot])
+	}
+	s.ring[slot] = cmds
+	s.count++
+	return err
+}
+
+func (s *SpillStore) spill(frameID uint32, cmds []*pb.InputData) error {
+	end := s.offsets[len(s.offsets)-1]
+	buff, err := proto.MarshalOptions{}.MarshalAppend(s.buff[:0], &pb.FrameData{
+		FrameID: proto.Uint32(frameID),
+		Input:   cmds,
+	})
+	if err == nil {
+		s.buff = buff
+		_, err = s.file.WriteAt(buff, end)
+	}
+	if err != nil {
+		s.offsets = append(s.offsets, end)
+		return err
+	}
+	s.offsets = append(s.offsets, end+int64(len(buff)))
+	return nil
+}
+
+func (s *SpillStore) Get(frameID uint32) ([]*pb.InputData, error) {
+	if frameID >= s.count {
+		return nil, nil
+	}
+	if frameID+uint32(len(s.ring)) >= s.count {
+		return s.ring[frameID%uint32(len(s.ring))], nil
+	}
+
+	from, to := s.offsets[frameID], s.offsets[frameID+1]
+	if from == to {
+		return nil, ErrFrameLost
+	}
+	buff := make([]byte, to-from)
+	if n, err := s.file.ReadAt(buff, from); n < len(buff) {
+		return nil, err
+	}
+	frame := &pb.FrameData{}
+	if err := proto.Unmarshal(buff, frame); err != nil {
+		return nil, err
+	}
+	return frame.GetInput(), nil
+}
+
+func (s *SpillStore) Len() uint32 {
+	return s.count
+}
+
+func (s *SpillStore) Reset() error {
+	for i := range s.ring {
+		s.ring[i] = nil
+	}
+	s.count = 0
+	s.offsets = s.offsets[:1]
+	return s.file.Truncate(0)
+}
+
+func (s *SpillStore) Close() error {
+	err := s.file.Close()
+	if e := os.Remove(s.file.Name()); err == nil {
+		err = e
+	}
+	return err
+}
@@ -0,0 +1,251 @@
+package game
+
+import (
+	"math"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/hedon954/go-lock-step-server/pb"
+	"github.com/hedon954/go-lock-step-server/pkg/packet/pb_packet"
+	"google.golang.org/protobuf/proto"
+)
+
+// mapStore keeps the frames with inputs in a map, as the lockstep did before FrameStore
+type mapStore struct {
+	frames map[uint32][]*pb.InputData
+	count  uint32
+}
+
+func newMapStore() *mapStore {
+	return &mapStore{frames: make(map[uint32][]*pb.InputData)}
+}
+
+func (s *mapStore) Append(cmds []*pb.InputData) error {
+	if cmds != nil {
+		s.frames[s.count] = cmds
+	}
+	s.count++
+	return nil
+}
+
+func (s *mapStore) Get(frameID uint32) ([]*pb.InputData, error) {
+	return s.frames[frameID], nil
+}
+
+func (s *mapStore) Len() uint32 {
+	return s.count
+}
+
+func (s *mapStore) Reset() error {
+	s.frames = make(map[uint32][]*pb.InputData)
+	s.count = 0
+	return nil
+}
+
+func (s *mapStore) Close() error {
+	return nil
+}
+
+// testInputs returns the inputs of the frame, the players input every 3 frames in turn
+func testInputs(frameID uint32, players int) []*pb.InputData {
+	var ret []*pb.InputData
+	for i := 0; i < players; i++ {
+		if (frameID+uint32(i))%3 != 0 {
+			continue
+		}
+		ret = append(ret, &pb.InputData{
+			Id:         proto.Uint64(uint64(i + 1)),
+			Sid:        proto.Int32(int32(frameID)),
+			X:          proto.Int32(int32(i)),
+			Roomseatid: proto.Int32(int32(i + 1)),
+		})
+	}
+	return ret
+}
+
+func testFrameStore(t *testing.T, s FrameStore) {
+	const frames = 1000
+	for round := 0; round < 2; round++ {
+		for i := uint32(0); i < frames; i++ {
+			if err := s.Append(testInputs(i, 4)); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if s.Len() != frames {
+			t.Fatalf("len got: %d", s.Len())
+		}
+		for i := uint32(0); i <= frames; i++ {
+			cmds, err := s.Get(i)
+			if err != nil {
+				t.Fatal(err)
+			}
+			want := testInputs(i, 4)
+			if i == frames {
+				want = nil
+			}
+			if len(cmds) != len(want) {
+				t.Fatalf("frame[%d] got %d inputs, want %d", i, len(cmds), len(want))
+			}
+			for j := range want {
+				if !proto.Equal(cmds[j], want[j]) {
+					t.Fatalf("frame[%d] got %v, want %v", i, cmds[j], want[j])
+				}
+			}
+		}
+		if err := s.Reset(); err != nil {
+			t.Fatal(err)
+		}
+		if cmds, _ := s.Get(0); s.Len() != 0 || cmds != nil {
+			t.Fatal("the frames are not dropped by reset")
+		}
+	}
+}
+
+func Test_SliceStore(t *testing.T) {
+	testFrameStore(t, NewSliceStore())
+}
+
+func Test_SpillStore(t *testing.T) {
+	dir := t.TempDir()
+	s, err := NewSpillStore(dir, 64)
+	if err != nil {
+		t.Fatal(err)
+	}
+	testFrameStore(t, s)
+
+	// the frames failing to spill are lost, the ones in memory are not
+	s.file.Close()
+	for i := uint32(0); i < 65; i++ {
+		_ = s.Append(testInputs(i, 4))
+	}
+	if _, err = s.Get(0); err != ErrFrameLost {
+		t.Errorf("spill failed got: %v", err)
+	}
+	if cmds, err := s.Get(1); err != nil || len(cmds) != len(testInputs(1, 4)) {
+		t.Errorf("frame in memory got: %v %v", cmds, err)
+	}
+
+	_ = s.Close()
+	if entries, _ := os.ReadDir(dir); len(entries) != 0 {
+		t.Errorf("the spill file is not removed: %v", entries)
+	}
+}
+
+// lossyStore loses a frame like a SpillStore failing to spill it
+type lossyStore struct {
+	*SliceStore
+	lost uint32
+}
+
+func (s *lossyStore) Get(frameID uint32) ([]*pb.InputData, error) {
+	if frameID == s.lost {
+		return nil, ErrFrameLost
+	}
+	return s.SliceStore.Get(frameID)
+}
+
+func Test_FrameLost(t *testing.T) {
+	g := NewGame(1, []uint64{1, 2}, 0, nopListener{}, &Config{Frames: &lossyStore{NewSliceStore(), 1}})
+	g.JoinGame(1, &testConn{extraData: uint64(1)}, "ticket")
+	g.JoinGame(2, &testConn{extraData: uint64(2)}, "")
+	now := time.Now().Unix()
+	for i := 0; i < 4; i++ {
+		g.Tick(now)
+	}
+
+	// the catch up fails rather than sending the frames without the lost one
+	c := &testConn{extraData: uint64(1)}
+	g.ResumeGame(1, c, "ticket", 0, "resumed")
+	if ids := c.ids(); !c.closed || len(ids) != 1 || ids[0] != pb.ID_MSG_Connect {
+		t.Errorf("resume from the lost frame got: %v closed: %v", ids, c.closed)
+	}
+
+	// the frames after the lost one are still caught up
+	c = &testConn{extraData: uint64(1)}
+	g.ResumeGame(1, c, "resumed", 2, "")
+	if ids := c.ids(); c.closed || len(ids) != 2 || ids[1] != pb.ID_MSG_Frame {
+		t.Errorf("resume after the lost frame got: %v closed: %v", ids, c.closed)
+	}
+}
+
+// hugeStore returns a frame too large to be sent in a packet
+type hugeStore struct {
+	*SliceStore
+	huge uint32
+}
+
+func (s *hugeStore) Get(frameID uint32) ([]*pb.InputData, error) {
+	if frameID != s.huge {
+		return s.SliceStore.Get(frameID)
+	}
+	cmds := make([]*pb.InputData, pb_packet.MaxExtPacketLen/8)
+	for i := range cmds {
+		cmds[i] = &pb.InputData{Id: proto.Uint64(math.MaxUint64), Sid: proto.Int32(1)}
+	}
+	return cmds, nil
+}
+
+func Test_FrameTooLarge(t *testing.T) {
+	g := NewGame(1, []uint64{1, 2}, 0, nopListener{}, &Config{Frames: &hugeStore{NewSliceStore(), 1}})
+	g.JoinGame(1, &testConn{extraData: uint64(1)}, "ticket")
+	g.JoinGame(2, &testConn{extraData: uint64(2)}, "")
+	now := time.Now().Unix()
+	for i := 0; i < 4; i++ {
+		g.Tick(now)
+	}
+
+	// the catch up fails rather than skipping the frame
+	c := &testConn{extraData: uint64(1)}
+	g.ResumeGame(1, c, "ticket", 0, "resumed")
+	if ids := c.ids(); !c.closed || len(ids) != 1 || ids[0] != pb.ID_MSG_Connect {
+		t.Errorf("resume with the huge frame got: %v closed: %v", ids, c.closed)
+	}
+}
+
+// benchmarkFrameStore plays a full game of 4 players, the recent frames are read every tick to broadcast,
+// and a player catches up all the frames every 10 seconds
+func benchmarkFrameStore(b *testing.B, newStore func() FrameStore) {
+	frames := make([][]*pb.InputData, MaxGameFrame)
+	for i := range frames {
+		frames[i] = testInputs(uint32(i), 4)
+	}
+	b.ReportAllocs()
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		s := newStore()
+		for i, cmds := range frames {
+			_ = s.Append(cmds)
+			for j := i - BroadcastOffsetFrames + 1; j <= i; j++ {
+				if j >= 0 {
+					_, _ = s.Get(uint32(j))
+				}
+			}
+			if i%300 == 299 {
+				for j := uint32(0); j <= uint32(i); j++ {
+					_, _ = s.Get(j)
+				}
+			}
+		}
+		_ = s.Close()
+	}
+}
+
+func BenchmarkFrameStore_Map(b *testing.B) {
+	benchmarkFrameStore(b, func() FrameStore { return newMapStore() })
+}
+
+func BenchmarkFrameStore_Slice(b *testing.B) {
+	benchmarkFrameStore(b, func() FrameStore { return NewSliceStore() })
+}
+
+func BenchmarkFrameStore_Spill(b *testing.B) {
+	dir := b.TempDir()
+	benchmarkFrameStore(b, func() FrameStore {
+		s, err := NewSpillStore(dir, 0)
+		if err != nil {
+			b.Fatal(err)
+		}
+		return s
+	})
+}
@@ -1,12 +1,16 @@
 package game
 
 import (
+	"errors"
+	"sort"
 	"time"
 
 	"github.com/alecthomas/log4go"
 	"github.com/hedon954/go-lock-step-server/pb"
+	"github.com/hedon954/go-lock-step-server/pkg/clock"
 	"github.com/hedon954/go-lock-step-server/pkg/network"
 	"github.com/hedon954/go-lock-step-server/pkg/packet/pb_packet"
+	"google.golang.org/protobuf/encoding/protowire"
 	"google.golang.org/protobuf/proto"
 )
 
@@ -32,8 +36,12 @@ const (
 	// the interval of broadcast frame data
 	BroadcastOffsetFrames = 3
 
-	// frame data each message packet contains at most
-	kMaxFrameDataPerMsg = 60
+	// loadout bytes a seat submits at most
+	MaxLoadoutSize = 1024
+
+	// frame data bytes each message packet contains at most,
+	// it is further limited by the protocol version of the player
+	kMaxFrameBytesPerMsg = 256 * 1024
 
 	// If no heartbeat packet is received during this time period,
 	// the network is considered to be poor,
@@ -43,11 +51,51 @@ const (
 	kBadNetworkThreshold = 2
 )
 
+var (
+	ErrGameState     = errors.New("game is neither ready nor gaming")
+	ErrSeatInvalid   = errors.New("seat does not exist")
+	ErrSeatOccupied  = errors.New("seat is occupied by a player online")
+	ErrPlayerExists  = errors.New("player is in the game already")
+	ErrSimulated     = errors.New("seats are locked while the game is simulated")
+	ErrFrameTooLarge = errors.New("frame message is out of the packet limit")
+)
+
+// Config is the configuration of a game
+type Config struct {
+	// Clock tells the time of the game, nil means clock.Real
+	Clock clock.Clock
+
+	// Bots creates the bots taking over the seats of the players who are not connected while gaming,
+	// nil leaves the seats empty
+	Bots BotFactory
+
+	// Validator checks the inputs of the players, nil accepts any input
+	Validator InputValidator
+
+	// KickThreshold kicks the player out once the violations of the inputs reach it, 0 means never
+	KickThreshold int
+
+	// Timing is the thresholds of the timing anomalies of the players, nil means the defaults
+	Timing *TimingConfig
+
+	// Simulation validates the state hashes of the players and decides the winner, nil relays only
+	Simulation Simulation
+
+	// Frames keeps the frames of the game, it is closed with the game, nil means a SliceStore
+	Frames FrameStore
+
+	// ResumeTTL is how long a player can resume the session once their conn is lost, 0 means no limit
+	ResumeTTL time.Duration
+}
+
 type gameListener interface {
 	OnJoinGame(gid uint64, pid uint64)
 	OnGameStart(gid uint64)
 	OnLeaveGame(gid uint64, pid uint64)
 	OnGameOver(gid uint64)
+
+	// OnTimingAnomaly is told once the timing score of the player reaches every TimingConfig.ReportScore
+	OnTimingAnomaly(gid uint64, pid uint64, stats TimingStats)
 }
 
 // Game represents a game
@@ -62,29 +110,63 @@ type Game struct {
 	result           map[uint64]uint64
 	listener         gameListener
 	dirty            bool
+	clock            clock.Clock
+	bots             BotFactory
+	loadouts         []*pb.Loadout // locked at start
+	validator        InputValidator
+	kickThreshold    int
+	timing           TimingConfig
+	simulation       Simulation
+	hashes           []uint64                           // the state hashes of the simulation after every frame
+	batches          map[frameBatch][]*pb_packet.Packet // the frame messages shared while broadcasting
+	resumeTTL        time.Duration
+}
+
+// frameBatch is the frames [from, to) split into the messages of maxBytes at most
+type frameBatch struct {
+	from, to uint32
+	maxBytes int
 }
 
-// NewGame builds a game meta
-func NewGame(id uint64, players []uint64, randomSeed int32, listener gameListener) *Game {
+// NewGame builds a game meta, a nil config means the default one
+func NewGame(id uint64, players []uint64, randomSeed int32, listener gameListener, config *Config) *Game {
+	var c Config
+	if config != nil {
+		c = *config
+	}
+	clk := clock.OrReal(c.Clock)
+	if c.Frames == nil {
+		c.Frames = NewSliceStore()
+	}
+
 	g := &Game{
-		id:         id,
-		players:    make(map[uint64]*Player),
-		logic:      newLockStep(),
-		startTime:  time.Now().Unix(),
-		randomSeed: randomSeed,
-		listener:   listener,
-		result:     make(map[uint64]uint64),
+		id:            id,
+		players:       make(map[uint64]*Player),
+		logic:         newLockStep(c.Frames),
+		startTime:     clk.Now().Unix(),
+		randomSeed:    randomSeed,
+		listener:      listener,
+		result:        make(map[uint64]uint64),
+		clock:         clk,
+		bots:          c.Bots,
+		validator:     c.Validator,
+		kickThreshold: c.KickThreshold,
+		timing:        c.Timing.withDefaults(),
+		simulation:    c.Simulation,
+		resumeTTL:     c.ResumeTTL,
 	}
 
 	for i, pid := range players {
-		g.players[pid] = NewPlayer(pid, int32(i+1))
+		p := NewPlayer(pid, int32(i+1))
+		p.clock = clk
+		g.players[pid] = p
 	}
 
 	return g
 }
 
-// JoinGame user joins game
-func (g *Game) JoinGame(pid uint64, conn *network.Conn) bool {
+// JoinGame user joins game, ticket is the resume ticket issued to the player
+func (g *Game) JoinGame(pid uint64, conn PlayerConn, ticket string) bool {
 
 	msg := &pb.S2C_ConnectMsg{
 		ErrorCode: pb.ERRORCODE_ERR_ok.Enum(),
@@ -96,6 +178,15 @@ func (g *Game) JoinGame(pid uint64, conn *network.Conn) bool {
 		return false
 	}
 
+	if p.violations.Kicked {
+		// the conn is detached, so that its messages are not taken as the player's
+		conn.PutExtraData(nil)
+		msg.ErrorCode = pb.ERRORCODE_ERR_Kicked.Enum()
+		conn.AsyncWritePacket(pb_packet.NewPacket(uint8(pb.ID_MSG_Connect), msg), 0)
+		log4go.Error("[game(%d)] player[%d] has been kicked", g.id, pid)
+		return true
+	}
+
 	if g.State != k_Ready && g.State != k_Gaming {
 		msg.ErrorCode = pb.ERRORCODE_ERR_RoomState.Enum()
 		p.SendMessage(pb_packet.NewPacket(uint8(pb.ID_MSG_Connect), msg))
@@ -105,16 +196,121 @@ func (g *Game) JoinGame(pid uint64, conn *network.Conn) bool {
 
 	// replace the current player
 	if p.client != nil {
-		p.client.PutExtraData(nil)
 		log4go.Warn("[game(%d)] player[%d] replace", g.id, pid)
 	}
 
-	p.Connect(conn)
-	p.SendMessage(pb_packet.NewPacket(uint8(pb.ID_MSG_Connect), msg))
+	g.connect(p, conn, ticket)
 	g.listener.OnJoinGame(g.id, pid)
 	return true
 }
 
+// ResumeGame attaches the conn presenting the resume ticket to the player, the previous conn is closed,
+// and the frames are caught up from frameID. newTicket replaces the ticket presented,
+// the conn is detached from the player if the ticket has been replaced or expired or the game is over.
+// It returns false if the player is not in the game, the conn is neither replied nor attached then
+func (g *Game) ResumeGame(pid uint64, conn PlayerConn, ticket string, frameID uint32, newTicket string) bool {
+	p, ok := g.players[pid]
+	if !ok {
+		log4go.Error("[game(%d)] player[%d] resume failed", g.id, pid)
+		return false
+	}
+
+	errCode := pb.ERRORCODE_ERR_ok
+	if p.violations.Kicked {
+		errCode = pb.ERRORCODE_ERR_Kicked
+	} else if g.State != k_Ready && g.State != k_Gaming {
+		errCode = pb.ERRORCODE_ERR_RoomState
+	} else if ticket == "" || ticket != p.ticket || g.resumeExpired(p) {
+		errCode = pb.ERRORCODE_ERR_Ticket
+	}
+	if errCode != pb.ERRORCODE_ERR_ok {
+		conn.PutExtraData(nil)
+		conn.AsyncWritePacket(pb_packet.NewPacket(uint8(pb.ID_MSG_Connect), &pb.S2C_ConnectMsg{
+			ErrorCode: errCode.Enum(),
+		}), 0)
+		log4go.Error("[game(%d)] player[%d] resume rejected [%s]", g.id, pid, errCode)
+		return true
+	}
+
+	g.connect(p, conn, newTicket)
+	if g.State == k_Gaming {
+		if frameID > g.clientFrameCount {
+			frameID = g.clientFrameCount
+		}
+		g.catchUp(p, frameID, g.clientFrameCount)
+	}
+	g.listener.OnJoinGame(g.id, pid)
+	return true
+}
+
+// resumeExpired checks if the player has been offline for longer than the resume ttl
+func (g *Game) resumeExpired(p *Player) bool {
+	return g.resumeTTL > 0 && !p.IsOnline() && g.clock.Now().Sub(p.offlineTime) > g.resumeTTL
+}
+
+// connect attaches the conn to the player and replies MSG_Connect
+func (g *Game) connect(p *Player, conn PlayerConn, ticket string) {
+	p.Connect(conn)
+	p.ticket = ticket
+	p.SendMessage(pb_packet.NewPacket(uint8(pb.ID_MSG_Connect), &pb.S2C_ConnectMsg{
+		ErrorCode:       pb.ERRORCODE_ERR_ok.Enum(),
+		ProtocolVersion: proto.Uint32(p.ProtocolVersion()),
+		Checksum:        pb_packet.ChecksumOf(conn).Enum(),
+		ResumeTicket:    proto.String(ticket),
+	}))
+}
+
+// AssignSeat assigns the seat to the player while the game is ready or gaming,
+// the seat next to the last one is added, and the player offline on the seat is replaced.
+// It returns the id of the player replaced, or 0 if the seat is added.
+// The player catches up all the frames once connected, and a bot plays the seat until then while gaming.
+// The seats of a simulated game are locked once it starts
+func (g *Game) AssignSeat(seat int32, pid uint64) (uint64, error) {
+	if g.State != k_Ready && g.State != k_Gaming {
+		return 0, ErrGameState
+	}
+	if g.State == k_Gaming && g.simulation != nil {
+		// the simulation has started with the players by seat
+		return 0, ErrSimulated
+	}
+	if _, ok := g.players[pid]; ok {
+		return 0, ErrPlayerExists
+	}
+	if seat < 1 || int(seat) > len(g.players)+1 {
+		return 0, ErrSeatInvalid
+	}
+
+	var replaced uint64
+	for _, p := range g.players {
+		if p.idx != seat {
+			continue
+		}
+		if p.IsOnline() {
+			return 0, ErrSeatOccupied
+		}
+		p.Cleanup()
+		delete(g.players, p.id)
+		delete(g.result, p.id)
+		replaced = p.id
+		break
+	}
+
+	p := NewPlayer(pid, seat)
+	p.clock = g.clock
+	g.players[pid] = p
+	if g.State == k_Gaming {
+		g.takeOver(p)
+	}
+
+	g.broadcast(pb_packet.NewPacket(uint8(pb.ID_MSG_Roster), &pb.S2C_RosterMsg{
+		Roomseatid: proto.Int32(seat),
+		Id:         proto.Uint64(pid),
+		Replaced:   proto.Uint64(replaced),
+	}))
+	log4go.Info("[game(%d)] seat[%d] assigned to player[%d] replaced=[%d]", g.id, seat, pid, replaced)
+	return replaced, nil
+}
+
 // LeaveGame user leaves game
 func (g *Game) LeaveGame(pid uint64) bool {
 	p, ok := g.players[pid]
@@ -122,6 +318,9 @@ func (g *Game) LeaveGame(pid uint64) bool {
 		return false
 	}
 	p.Cleanup()
+	if g.State == k_Gaming {
+		g.takeOver(p)
+	}
 	g.listener.OnLeaveGame(g.id, pid)
 	return true
 }
@@ -130,10 +329,17 @@ func (g *Game) LeaveGame(pid uint64) bool {
 func (g *Game) ProcessMsg(pid uint64, msg *pb_packet.Packet) {
 	player, ok := g.players[pid]
 	if !ok {
-		log4go.Error("[game(%d)] processMsg player[%d] msg=[%d]", g.id, player.id, msg.GetMessageID())
+		log4go.Error("[game(%d)] processMsg player[%d] msg=[%d]", g.id, pid, msg.GetMessageID())
+		return
+	}
+	if player.violations.Kicked {
+		log4go.Warn("[game(%d)] processMsg player[%d] msg=[%d] player has been kicked", g.id, pid, msg.GetMessageID())
 		return
 	}
 	log4go.Info("[game(%d)] processMsg player[%d] msg=[%d]", g.id, player.id, msg.GetMessageID())
+	if g.State == k_Gaming {
+		player.timing.onMessage(&g.timing, g.clock.Now())
+	}
 
 	msgID := pb.ID(msg.GetMessageID())
 	switch msgID {
@@ -148,6 +354,9 @@ func (g *Game) ProcessMsg(pid uint64, msg *pb_packet.Packet) {
 			}
 			jrMsg.Others = append(jrMsg.Others, p.id)
 			jrMsg.Pros = append(jrMsg.Pros, p.loadingProgress)
+			if p.loadout != nil {
+				jrMsg.Loadouts = append(jrMsg.Loadouts, p.getLoadout())
+			}
 		}
 		player.SendMessage(pb_packet.NewPacket(uint8(pb.ID_MSG_JoinRoom), jrMsg))
 
@@ -155,7 +364,7 @@ func (g *Game) ProcessMsg(pid uint64, msg *pb_packet.Packet) {
 		if g.State > k_Ready {
 			break
 		}
-		m := &pb.S2C_ProgressMsg{}
+		m := &pb.C2S_ProgressMsg{}
 		if err := msg.UnmarshalPB(m); err != nil {
 			log4go.Error("[game(%d)] processMsg player[%d] msg=[%d] UnmarshalPB error:[%s]", g.id, player.id,
 				msg.GetMessageID(), err.Error())
@@ -168,9 +377,33 @@ func (g *Game) ProcessMsg(pid uint64, msg *pb_packet.Packet) {
 		})
 		g.broadcastExclude(pMsg, player.id)
 
+	case pb.ID_MSG_Loadout:
+		if g.State != k_Ready {
+			log4go.Warn("[game(%d)] ID_MSG_Loadout player[%d] the loadouts are locked", g.id, player.id)
+			break
+		}
+		m := &pb.C2S_LoadoutMsg{}
+		if err := msg.UnmarshalPB(m); err != nil {
+			log4go.Error("[game(%d)] processMsg player[%d] msg=[%d] UnmarshalPB error:[%s]", g.id, player.id,
+				msg.GetMessageID(), err.Error())
+			return
+		}
+		if len(m.GetData()) > MaxLoadoutSize {
+			log4go.Warn("[game(%d)] ID_MSG_Loadout player[%d] loadout size[%d] is out of limit", g.id, player.id,
+				len(m.GetData()))
+			break
+		}
+		player.loadout = append([]byte{}, m.GetData()...)
+		g.broadcast(pb_packet.NewPacket(uint8(pb.ID_MSG_Loadout), &pb.S2C_LoadoutMsg{
+			Loadout: player.getLoadout(),
+		}))
+
 	case pb.ID_MSG_Heartbeat:
 		player.SendMessage(pb_packet.NewPacket(uint8(pb.ID_MSG_Heartbeat), nil))
 		player.RefreshHeartbeat()
+		if g.State == k_Gaming {
+			g.reportTiming(player, player.timing.onHeartbeat(&g.timing, g.clock.Now()))
+		}
 
 	case pb.ID_MSG_Ready:
 		if g.State == k_Ready {
@@ -190,6 +423,12 @@ func (g *Game) ProcessMsg(pid uint64, msg *pb_packet.Packet) {
 				msg.GetMessageID(), err.Error())
 			return
 		}
+		if g.State == k_Gaming {
+			g.reportTiming(player, player.timing.onInput(&g.timing, g.clock.Now(), g.logic.getFrameCount(), m.FrameID))
+		}
+		if !g.validateInput(player, m) {
+			break
+		}
 		if !g.pushInput(player, m) {
 			log4go.Warn("[game(%d)] processMsg player[%d] msg=[%d] pushInput failed", g.id, player.id,
 				msg.GetMessageID())
@@ -198,6 +437,18 @@ func (g *Game) ProcessMsg(pid uint64, msg *pb_packet.Packet) {
 		// mandatory broadcast of the next frame (required by the client)
 		g.dirty = true
 
+	case pb.ID_MSG_Checksum:
+		if g.simulation == nil || g.State != k_Gaming {
+			break
+		}
+		m := &pb.C2S_ChecksumMsg{}
+		if err := msg.UnmarshalPB(m); err != nil {
+			log4go.Error("[game(%d)] processMsg player[%d] msg=[%d] UnmarshalPB error:[%s]", g.id, player.id,
+				msg.GetMessageID(), err.Error())
+			return
+		}
+		g.checkHash(player, m.GetFrameID(), m.GetHash())
+
 	case pb.ID_MSG_Result:
 		m := &pb.C2S_ResultMsg{}
 		if err := msg.UnmarshalPB(m); err != nil {
@@ -207,6 +458,10 @@ func (g *Game) ProcessMsg(pid uint64, msg *pb_packet.Packet) {
 		}
 		g.result[player.id] = m.GetWinnerID()
 		log4go.Info("[game(%d)] ID_MSG_Result player[%d] winner=[%d]", g.id, player.id, m.GetWinnerID())
+		if winner := g.Winner(); winner != 0 && winner != m.GetWinnerID() {
+			log4go.Warn("[game(%d)] ID_MSG_Result player[%d] winner=[%d] disputes the simulated winner[%d]", g.id,
+				player.id, m.GetWinnerID(), winner)
+		}
 		player.SendMessage(pb_packet.NewPacket(uint8(pb.ID_MSG_Result), nil))
 	default:
 		log4go.Warn("[game(%d)] processMsg unknown message id[%d]", msgID)
@@ -250,8 +505,12 @@ func (g *Game) Tick(now int64) bool {
 			return true
 		}
 
-		g.logic.tick()
+		if _, err := g.logic.tick(); err != nil {
+			log4go.Error("[game(%d)] store frame[%d] error:[%s]", g.id, g.logic.getFrameCount()-1, err.Error())
+		}
+		g.stepSimulation()
 		g.broadcastFrameData()
+		g.tickBots()
 		return true
 
 	case k_Over:
@@ -282,6 +541,67 @@ func (g *Game) broadcastExclude(msg network.Packet, pid uint64) {
 	}
 }
 
+// validateInput records the violation of the input and kicks the player out once the violations reach
+// the threshold, it returns false if the input is rejected
+func (g *Game) validateInput(p *Player, msg *pb.C2S_InputMsg) bool {
+	if g.validator == nil {
+		return true
+	}
+	frameID := g.logic.getFrameCount()
+	verdict, reason := g.validator.Validate(p.id, frameID, msg)
+	if verdict == VerdictAccept {
+		return true
+	}
+
+	v := &p.violations
+	v.Count++
+	v.Latest = append(v.Latest, Violation{
+		FrameID:  frameID,
+		Sid:      msg.GetSid(),
+		X:        msg.GetX(),
+		Y:        msg.GetY(),
+		Reason:   reason,
+		Rejected: verdict == VerdictReject,
+	})
+	if len(v.Latest) > MaxViolationsKept {
+		v.Latest = append(v.Latest[:0], v.Latest[1:]...)
+	}
+	log4go.Warn("[game(%d)] player[%d] invalid input frame=[%d] reason=[%s] rejected=[%v] count=[%d]", g.id, p.id,
+		frameID, reason, verdict == VerdictReject, v.Count)
+
+	if g.kickThreshold > 0 && v.Count >= g.kickThreshold {
+		g.kick(p)
+		return false
+	}
+	return verdict != VerdictReject
+}
+
+// reportTiming logs the timing anomalies of the player,
+// and reports the player to the listener once the score reaches the next step
+func (g *Game) reportTiming(p *Player, anomalies []Anomaly) {
+	if len(anomalies) == 0 {
+		return
+	}
+	for _, a := range anomalies {
+		log4go.Warn("[game(%d)] player[%d] timing anomaly[%s] score=[%.0f]", g.id, p.id, a, p.timing.stats.Score)
+	}
+	if p.timing.report(&g.timing) {
+		g.listener.OnTimingAnomaly(g.id, p.id, p.timing.stats)
+	}
+}
+
+// kick kicks the player out of the game, the player cannot join again, and a bot takes the seat while gaming
+func (g *Game) kick(p *Player) {
+	p.violations.Kicked = true
+	if p.client != nil {
+		// the conn is closed, and its leave is ignored by the room as it is detached from the player,
+		// the player learns ERR_Kicked when connecting again
+		p.client.PutExtraData(nil)
+	}
+	log4go.Error("[game(%d)] player[%d] kicked for %d invalid inputs", g.id, p.id, p.violations.Count)
+	g.LeaveGame(p.id)
+}
+
 // pushInput pushes input msg to lock step server
 func (g *Game) pushInput(p *Player, msg *pb.C2S_InputMsg) bool {
 	cmd := &pb.InputData{
@@ -309,37 +629,12 @@ func (g *Game) doReady(player *Player) {
 func (g *Game) doReconnect(player *Player) {
 	msg := &pb.S2C_StartMsg{
 		TimeStamp: proto.Int64(g.startTime),
+		Loadouts:  g.loadouts,
 	}
 	ret := pb_packet.NewPacket(uint8(pb.ID_MSG_Start), msg)
 	player.SendMessage(ret)
 
-	frameCount := g.clientFrameCount
-	c := 0
-
-	frameMsg := &pb.S2C_FrameMsg{}
-	var i uint32 = 0
-	for i = 0; i < frameCount; i++ {
-		fd := g.logic.getFrame(i)
-		if fd == nil && i != (frameCount-1) {
-			continue
-		}
-		f := &pb.FrameData{
-			FrameID: proto.Uint32(i),
-		}
-		if fd != nil {
-			f.Input = fd.cmds
-		}
-		frameMsg.Frames = append(frameMsg.Frames, f)
-		c++
-
-		if c >= kMaxFrameDataPerMsg || i == (frameCount-1) {
-			player.SendMessage(pb_packet.NewPacket(uint8(pb.ID_MSG_Frame), frameMsg))
-			c = 0
-			frameMsg = &pb.S2C_FrameMsg{}
-		}
-	}
-
-	player.SetSendFrameCount(g.clientFrameCount)
+	g.catchUp(player, 0, g.clientFrameCount)
 }
 
 // checkReady checks if the game is ready
@@ -356,20 +651,129 @@ func (g *Game) checkReady() bool {
 // doStart starts the game
 func (g *Game) doStart() {
 	g.clientFrameCount = 0
-	g.logic.reset()
+	if err := g.logic.reset(); err != nil {
+		log4go.Error("[game(%d)] reset frames error:[%s]", g.id, err.Error())
+	}
 	for _, p := range g.players {
 		p.isReady = true
 		p.loadingProgress = 100
+		if !p.IsOnline() {
+			g.takeOver(p)
+		}
+	}
+	g.startTime = g.clock.Now().Unix()
+	g.loadouts = g.lockLoadouts()
+	if g.simulation != nil {
+		g.hashes = g.hashes[:0]
+		g.simulation.Start(g.randomSeed, g.playersBySeat(), g.loadouts)
 	}
-	g.startTime = time.Now().Unix()
 	msg := &pb.S2C_StartMsg{
 		TimeStamp: proto.Int64(g.startTime),
+		Loadouts:  g.loadouts,
 	}
 	ret := pb_packet.NewPacket(uint8(pb.ID_MSG_Start), msg)
 	g.broadcast(ret)
 	g.listener.OnGameStart(g.id)
 }
 
+// takeOver lets a bot play the seat of the player until they connect again
+func (g *Game) takeOver(p *Player) {
+	if g.bots == nil || p.bot != nil {
+		return
+	}
+	if p.bot = g.bots(p.id, p.idx); p.bot != nil {
+		log4go.Info("[game(%d)] player[%d] taken over by bot", g.id, p.id)
+	}
+}
+
+// tickBots gives the frame just over to the bots, their inputs are pushed into the next frame
+func (g *Game) tickBots() {
+	var frame *pb.FrameData
+	for _, p := range g.players {
+		if p.bot == nil {
+			continue
+		}
+		if frame == nil {
+			var err error
+			if frame, err = g.frameData(g.logic.getFrameCount() - 1); err != nil {
+				log4go.Error("[game(%d)] load frame[%d] for bots error:[%s]", g.id, frame.GetFrameID(), err.Error())
+				return
+			}
+		}
+		msg := p.bot.OnFrame(frame)
+		if msg == nil {
+			continue
+		}
+		if g.pushInput(p, msg) {
+			g.dirty = true
+		}
+	}
+}
+
+// frameData returns the inputs of the frame, the frame has no input if it fails to load
+func (g *Game) frameData(frameID uint32) (*pb.FrameData, error) {
+	cmds, err := g.logic.getFrame(frameID)
+	return &pb.FrameData{
+		FrameID: proto.Uint32(frameID),
+		Input:   cmds,
+	}, err
+}
+
+// stepSimulation steps the simulation with the frame just over and keeps its hash
+func (g *Game) stepSimulation() {
+	if g.simulation == nil {
+		return
+	}
+	frame, err := g.frameData(g.logic.getFrameCount() - 1)
+	if err != nil {
+		// the frame is stepped still, so that the hashes stay indexed by frame
+		log4go.Error("[game(%d)] load frame[%d] for simulation error:[%s]", g.id, frame.GetFrameID(), err.Error())
+	}
+	g.simulation.Step(frame)
+	g.hashes = append(g.hashes, g.simulation.Hash())
+}
+
+// checkHash compares the state hash of the player with the simulation's,
+// the hash of the simulation is replied if they differ
+func (g *Game) checkHash(p *Player, frameID uint32, hash uint64) {
+	if int(frameID) >= len(g.hashes) {
+		log4go.Warn("[game(%d)] player[%d] checksum frame[%d] is not simulated yet", g.id, p.id, frameID)
+		return
+	}
+	want := g.hashes[frameID]
+	if hash == want {
+		return
+	}
+	p.desyncs++
+	log4go.Warn("[game(%d)] player[%d] desync frame=[%d] hash=[%x] simulated=[%x] count=[%d]", g.id, p.id, frameID,
+		hash, want, p.desyncs)
+	p.SendMessage(pb_packet.NewPacket(uint8(pb.ID_MSG_Checksum), &pb.S2C_ChecksumMsg{
+		FrameID: proto.Uint32(frameID),
+		Hash:    proto.Uint64(want),
+	}))
+}
+
+// playersBySeat returns the players, the seat of players[i] is i+1
+func (g *Game) playersBySeat() []uint64 {
+	ret := make([]uint64, len(g.players))
+	for _, p := range g.players {
+		ret[p.idx-1] = p.id
+	}
+	return ret
+}
+
+// lockLoadouts returns the loadouts submitted ordered by seat
+func (g *Game) lockLoadouts() []*pb.Loadout {
+	var ret []*pb.Loadout
+	for _, p := range g.players {
+		if p.loadout != nil {
+			ret = append(ret, p.getLoadout())
+		}
+	}
+	sort.Slice(ret, func(i, j int) bool { return ret[i].GetRoomseatid() < ret[j].GetRoomseatid() })
+	return ret
+}
+
 // doGameOver game over
 func (g *Game) doGameOver() {
 	g.listener.OnGameOver(g.id)
@@ -425,7 +829,18 @@ func (g *Game) broadcastFrameData() {
 		g.clientFrameCount = frameCount
 	}()
 
-	now := time.Now().Unix()
+	now := g.clock.Now().Unix()
+
+	// the players at the same frames share the messages
+	g.batches = make(map[frameBatch][]*pb_packet.Packet)
+	defer func() {
+		for _, packets := range g.batches {
+			for _, msg := range packets {
+				msg.Release()
+			}
+		}
+		g.batches = nil
+	}()
 
 	for _, p := range g.players {
 		if !p.isOnline {
@@ -438,33 +853,105 @@ func (g *Game) broadcastFrameData() {
 			continue
 		}
 
-		i := p.GetSendFrameCount()
-		c := 0
-		msg := &pb.S2C_FrameMsg{}
-		for ; i < frameCount; i++ {
-			fd := g.logic.getFrame(i)
-			if fd == nil && i != (frameCount-1) {
-				continue
-			}
+		g.catchUp(p, p.GetSendFrameCount(), frameCount)
+	}
+}
 
-			f := &pb.FrameData{
-				FrameID: proto.Uint32(i),
-			}
+// catchUp sends the frames in [from, to) to the player, the conn of the player is closed
+// if the frames fail to load, rather than sending a history with the frames missing
+func (g *Game) catchUp(p *Player, from, to uint32) {
+	if err := g.sendFrames(p, from, to); err != nil {
+		log4go.Error("[game(%d)] player[%d] send frames[%d, %d) error:[%s]", g.id, p.id, from, to, err.Error())
+		if p.client != nil {
+			p.client.Close()
+		}
+		return
+	}
+	p.SetSendFrameCount(to)
+}
 
-			if fd != nil {
-				f.Input = fd.cmds
-			}
-			msg.Frames = append(msg.Frames, f)
-			c++
+// sendFrames sends the frames in [from, to) to the player,
+// the empty frames are skipped except the last one, and the messages are shared by the players while broadcasting.
+// Nothing is sent if any of the frames fails to load or to encode
+func (g *Game) sendFrames(p *Player, from, to uint32) error {
+	maxBytes := pb_packet.MaxDataLen(p.ProtocolVersion())
+	if maxBytes > kMaxFrameBytesPerMsg {
+		maxBytes = kMaxFrameBytesPerMsg
+	}
+	batch := frameBatch{from: from, to: to, maxBytes: maxBytes}
+	packets, ok := g.batches[batch]
+	if !ok {
+		var err error
+		if packets, err = g.encodeFrames(batch); err != nil {
+			return err
+		}
+		if g.batches != nil {
+			g.batches[batch] = packets
+		} else {
+			defer func() {
+				for _, msg := range packets {
+					msg.Release()
+				}
+			}()
+		}
+	}
+	for _, msg := range packets {
+		p.SendMessage(msg)
+	}
+	return nil
+}
 
-			if i == (frameCount-1) || c >= kMaxFrameDataPerMsg {
-				p.SendMessage(pb_packet.NewPacket(uint8(pb.ID_MSG_Frame), msg))
-				c = 0
-				msg = &pb.S2C_FrameMsg{}
+// encodeFrames encodes the messages of the frames, the caller holds a reference of each of them.
+// It fails if any message is out of the packet limit, rather than skipping its frames
+func (g *Game) encodeFrames(batch frameBatch) ([]*pb_packet.Packet, error) {
+	frames := make([]*pb.FrameData, 0, batch.to-batch.from)
+	for i := batch.from; i < batch.to; i++ {
+		f, err := g.frameData(i)
+		if err != nil {
+			return nil, err
+		}
+		if len(f.Input) == 0 && i != (batch.to-1) {
+			continue
+		}
+		frames = append(frames, f)
+	}
+
+	msgs := splitFrames(frames, batch.maxBytes)
+	packets := make([]*pb_packet.Packet, 0, len(msgs))
+	for _, msg := range msgs {
+		p := pb_packet.NewSharedPacket(uint8(pb.ID_MSG_Frame), msg)
+		if p == nil {
+			for _, p = range packets {
+				p.Release()
 			}
+			return nil, ErrFrameTooLarge
+		}
+		packets = append(packets, p)
+	}
+	return packets, nil
+}
+
+// splitFrames splits the frames into messages whose encoded size is at most maxBytes,
+// a frame larger than maxBytes is sent in a message alone
+func splitFrames(frames []*pb.FrameData, maxBytes int) []*pb.S2C_FrameMsg {
+	var ret []*pb.S2C_FrameMsg
+	msg := &pb.S2C_FrameMsg{}
+	size := 0
+	for _, f := range frames {
+		// field `frames` is a length-delimited field numbered 1
+		n := protowire.SizeTag(1) + protowire.SizeBytes(proto.Size(f))
+		if size > 0 && size+n > maxBytes {
+			ret = append(ret, msg)
+			msg = &pb.S2C_FrameMsg{}
+			size = 0
 		}
-		p.SetSendFrameCount(frameCount)
+		msg.Frames = append(msg.Frames, f)
+		size += n
 	}
+	if len(msg.Frames) > 0 {
+		ret = append(ret, msg)
+	}
+	return ret
 }
 
 // Result returns the game result
@@ -472,6 +959,62 @@ func (g *Game) Result() map[uint64]uint64 {
 	return g.result
 }
 
+// FrameCount returns the number of the frames since the game started
+func (g *Game) FrameCount() uint32 {
+	return g.logic.getFrameCount()
+}
+
+// Violations returns the violations of the players who have sent invalid inputs
+func (g *Game) Violations() map[uint64]PlayerViolations {
+	ret := make(map[uint64]PlayerViolations)
+	for pid, p := range g.players {
+		if p.violations.Count == 0 {
+			continue
+		}
+		v := p.violations
+		v.Latest = append([]Violation(nil), v.Latest...)
+		ret[pid] = v
+	}
+	return ret
+}
+
+// Winner returns the winner decided by the simulation, 0 if there is no simulation or it is undecided
+func (g *Game) Winner() uint64 {
+	if g.simulation == nil || g.State == k_Ready {
+		return 0
+	}
+	seat := g.simulation.Winner()
+	for _, p := range g.players {
+		if p.idx == seat {
+			return p.id
+		}
+	}
+	return 0
+}
+
+// Desyncs returns the numbers of the state hashes differing from the simulation's of the players who have sent them
+func (g *Game) Desyncs() map[uint64]int {
+	ret := make(map[uint64]int)
+	for pid, p := range g.players {
+		if p.desyncs > 0 {
+			ret[pid] = p.desyncs
+		}
+	}
+	return ret
+}
+
+// Timing returns the timing statistics of the players who have sent inputs or heartbeats while gaming
+func (g *Game) Timing() map[uint64]TimingStats {
+	ret := make(map[uint64]TimingStats)
+	for pid, p := range g.players {
+		if p.timing.stats.Inputs == 0 && p.timing.stats.Heartbeats == 0 {
+			continue
+		}
+		ret[pid] = p.timing.stats
+	}
+	return ret
+}
+
 // Close closes the game
 func (g *Game) Close() {
 	msg := pb_packet.NewPacket(uint8(pb.ID_MSG_Close), nil)
@@ -484,5 +1027,7 @@ func (g *Game) Cleanup() {
 		p.Cleanup()
 	}
 	g.players = make(map[uint64]*Player)
-
+	if err := g.logic.close(); err != nil {
+		log4go.Error("[game(%d)] close frames error:[%s]", g.id, err.Error())
+	}
 }
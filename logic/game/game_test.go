@@ -0,0 +1,446 @@
+package game
+
+import (
+	"testing"
+	"time"
+
+	"github.com/hedon954/go-lock-step-server/pb"
+	"github.com/hedon954/go-lock-step-server/pkg/network"
+	"github.com/hedon954/go-lock-step-server/pkg/packet/pb_packet"
+	"google.golang.org/protobuf/proto"
+)
+
+// testConn is a PlayerConn recording the packets written to it
+type testConn struct {
+	extraData interface{}
+	packets   []*pb_packet.Packet
+	closed    bool
+}
+
+func (c *testConn) AsyncWritePacket(p network.Packet, _ time.Duration) error {
+	if c.closed {
+		return network.ErrConnClosing
+	}
+	if sp, ok := p.(network.SharedPacket); ok {
+		// the packet is kept as PlayerConn requires, and never released so that the test reads it
+		sp.Retain()
+	}
+	c.packets = append(c.packets, p.(*pb_packet.Packet))
+	return nil
+}
+
+func (c *testConn) Close() {
+	c.closed = true
+}
+
+func (c *testConn) GetExtraData() interface{} {
+	return c.extraData
+}
+
+func (c *testConn) PutExtraData(data interface{}) {
+	c.extraData = data
+}
+
+// ids returns the ids of the packets written since the last call
+func (c *testConn) ids() []pb.ID {
+	var ret []pb.ID
+	for _, p := range c.packets {
+		ret = append(ret, pb.ID(p.GetMessageID()))
+	}
+	c.packets = nil
+	return ret
+}
+
+type nopListener struct{}
+
+func (nopListener) OnJoinGame(uint64, uint64)                   {}
+func (nopListener) OnGameStart(uint64)                          {}
+func (nopListener) OnLeaveGame(uint64, uint64)                  {}
+func (nopListener) OnGameOver(uint64)                           {}
+func (nopListener) OnTimingAnomaly(uint64, uint64, TimingStats) {}
+
+func Test_PlayerConn(t *testing.T) {
+	g := NewGame(1, []uint64{1, 2}, 0, nopListener{}, nil)
+	c1 := &testConn{extraData: uint64(1)}
+	c2 := &testConn{extraData: uint64(2)}
+	if !g.JoinGame(1, c1, "t1") || !g.JoinGame(2, c2, "t2") {
+		t.Fatal("join failed")
+	}
+	ret := &pb.S2C_ConnectMsg{}
+	if err := c1.packets[0].UnmarshalPB(ret); err != nil {
+		t.Fatal(err)
+	}
+	if ret.GetErrorCode() != pb.ERRORCODE_ERR_ok || ret.GetProtocolVersion() != pb_packet.ProtocolVersion1 ||
+		ret.GetChecksum() != pb.CHECKSUM_CHK_None || ret.GetResumeTicket() != "t1" {
+		t.Errorf("connect got: %v", ret)
+	}
+
+	g.Tick(time.Now().Unix())
+	g.ProcessMsg(1, pb_packet.NewPacket(uint8(pb.ID_MSG_Input), &pb.C2S_InputMsg{Sid: proto.Int32(1)}))
+	g.Tick(time.Now().Unix())
+	for _, c := range []*testConn{c1, c2} {
+		if ids := c.ids(); len(ids) != 3 || ids[1] != pb.ID_MSG_Start || ids[2] != pb.ID_MSG_Frame {
+			t.Errorf("received: %v", ids)
+		}
+	}
+
+	// the replaced conn is closed and detached from the player
+	c3 := &testConn{extraData: uint64(1)}
+	g.JoinGame(1, c3, "t3")
+	if !c1.closed || c1.GetExtraData() != nil {
+		t.Error("the replaced conn should be closed and detached")
+	}
+	if ids := c3.ids(); len(ids) != 1 || ids[0] != pb.ID_MSG_Connect {
+		t.Errorf("received: %v", ids)
+	}
+}
+
+func Test_SplitFrames(t *testing.T) {
+	var frames []*pb.FrameData
+	for i := uint32(0); i < 1000; i++ {
+		f := &pb.FrameData{FrameID: proto.Uint32(i)}
+		for j := uint32(0); j < i%8; j++ {
+			f.Input = append(f.Input, &pb.InputData{
+				Id:         proto.Uint64(uint64(i)),
+				Sid:        proto.Int32(int32(j)),
+				X:          proto.Int32(int32(i * j)),
+				Y:          proto.Int32(-int32(i * j)),
+				Roomseatid: proto.Int32(int32(j + 1)),
+			})
+		}
+		frames = append(frames, f)
+	}
+
+	const maxBytes = 1024
+	msgs := splitFrames(frames, maxBytes)
+	if len(msgs) < 2 {
+		t.Fatalf("frames should be split, got [%d] messages", len(msgs))
+	}
+
+	next := uint32(0)
+	for i, msg := range msgs {
+		if size := proto.Size(msg); size > maxBytes {
+			t.Errorf("message[%d] size[%d] exceeds [%d]", i, size, maxBytes)
+		}
+		for _, f := range msg.Frames {
+			if f.GetFrameID() != next {
+				t.Fatalf("frame[%d] should be [%d]", f.GetFrameID(), next)
+			}
+			next++
+		}
+	}
+	if next != uint32(len(frames)) {
+		t.Errorf("got [%d] frames, want [%d]", next, len(frames))
+	}
+
+	// a frame larger than the limit is still sent alone
+	msgs = splitFrames(frames[7:8], 1)
+	if len(msgs) != 1 || len(msgs[0].Frames) != 1 {
+		t.Errorf("a single frame should make a single message")
+	}
+
+	if msgs = splitFrames(nil, maxBytes); len(msgs) != 0 {
+		t.Errorf("no frames should make no messages")
+	}
+}
+
+// inputsOf returns the inputs of the player in the frames written to the conn since the last call
+func inputsOf(t *testing.T, c *testConn, pid uint64) int {
+	n := 0
+	for _, p := range c.packets {
+		if pb.ID(p.GetMessageID()) != pb.ID_MSG_Frame {
+			continue
+		}
+		msg := &pb.S2C_FrameMsg{}
+		if err := p.UnmarshalPB(msg); err != nil {
+			t.Fatal(err)
+		}
+		for _, f := range msg.GetFrames() {
+			for _, in := range f.GetInput() {
+				if in.GetId() == pid {
+					n++
+				}
+			}
+		}
+	}
+	c.packets = nil
+	return n
+}
+
+func Test_Bot(t *testing.T) {
+	g := NewGame(1, []uint64{1, 2}, 0, nopListener{}, &Config{Bots: RandomBots(1)})
+	c1 := &testConn{extraData: uint64(1)}
+	g.JoinGame(1, c1, "")
+
+	// player 2 never connects, the bot takes the seat at the forced start
+	now := time.Now().Unix() + MaxReadyTime
+	for i := 0; i < 10; i++ {
+		g.Tick(now)
+	}
+	if g.State != k_Gaming || g.getPlayer(2).bot == nil {
+		t.Fatal("the bot should take over the seat of player 2")
+	}
+	if n := inputsOf(t, c1, 2); n < 8 {
+		t.Errorf("the bot inputs %d times in 9 frames", n)
+	}
+
+	// the player takes the seat back
+	c2 := &testConn{extraData: uint64(2)}
+	g.JoinGame(2, c2, "")
+	g.Tick(now)
+	c1.packets = nil
+	for i := 0; i < 10; i++ {
+		g.Tick(now)
+	}
+	if n := inputsOf(t, c1, 2); n != 0 {
+		t.Errorf("the bot inputs %d times after the player connects", n)
+	}
+
+	// the bot takes the seat again once the player leaves
+	g.LeaveGame(2)
+	for i := 0; i < 10; i++ {
+		g.Tick(now)
+	}
+	if n := inputsOf(t, c1, 2); n < 8 {
+		t.Errorf("the bot inputs %d times after the player leaves", n)
+	}
+}
+
+func Test_Loadout(t *testing.T) {
+	g := NewGame(1, []uint64{1, 2}, 0, nopListener{}, nil)
+	c1 := &testConn{extraData: uint64(1)}
+	c2 := &testConn{extraData: uint64(2)}
+	g.JoinGame(1, c1, "")
+	g.JoinGame(2, c2, "")
+	c1.ids()
+	c2.ids()
+
+	loadout := func(pid uint64, data []byte) {
+		g.ProcessMsg(pid, pb_packet.NewPacket(uint8(pb.ID_MSG_Loadout), &pb.C2S_LoadoutMsg{Data: data}))
+	}
+	loadout(2, []byte("red"))
+	loadout(2, make([]byte, MaxLoadoutSize+1))
+	loadout(1, []byte("blue"))
+	loadout(1, []byte("green"))
+	if ids := c2.ids(); len(ids) != 3 || ids[0] != pb.ID_MSG_Loadout {
+		t.Fatalf("received: %v", ids)
+	}
+
+	g.ProcessMsg(1, pb_packet.NewPacket(uint8(pb.ID_MSG_JoinRoom), nil))
+	jrMsg := &pb.S2C_JoinRoomMsg{}
+	if err := c1.packets[len(c1.packets)-1].UnmarshalPB(jrMsg); err != nil {
+		t.Fatal(err)
+	}
+	if len(jrMsg.GetLoadouts()) != 1 || string(jrMsg.GetLoadouts()[0].GetData()) != "red" {
+		t.Errorf("join room got: %v", jrMsg)
+	}
+	c1.ids()
+
+	// the loadouts are locked at start
+	g.Tick(time.Now().Unix())
+	loadout(2, []byte("yellow"))
+	check := func(c *testConn) {
+		msg := &pb.S2C_StartMsg{}
+		if len(c.packets) != 1 || c.packets[0].UnmarshalPB(msg) != nil {
+			t.Fatalf("received: %v", c.ids())
+		}
+		c.ids()
+		loadouts := msg.GetLoadouts()
+		if len(loadouts) != 2 || loadouts[0].GetRoomseatid() != 1 || string(loadouts[0].GetData()) != "green" ||
+			loadouts[1].GetId() != 2 || string(loadouts[1].GetData()) != "red" {
+			t.Errorf("start got: %v", loadouts)
+		}
+	}
+	check(c1)
+	check(c2)
+
+	// the player getting ready again in the running game receives the same loadouts
+	g.ProcessMsg(1, pb_packet.NewPacket(uint8(pb.ID_MSG_Ready), nil))
+	c1.packets = c1.packets[:1]
+	check(c1)
+}
+
+func Test_Kick(t *testing.T) {
+	g := NewGame(1, []uint64{1, 2}, 0, nopListener{}, &Config{
+		Validator:     &RulesValidator{MaxX: 100, MaxY: 100},
+		KickThreshold: 3,
+	})
+	c1 := &testConn{extraData: uint64(1)}
+	c2 := &testConn{extraData: uint64(2)}
+	g.JoinGame(1, c1, "")
+	g.JoinGame(2, c2, "")
+	g.Tick(time.Now().Unix())
+
+	input := func(x int32) {
+		g.ProcessMsg(1, pb_packet.NewPacket(uint8(pb.ID_MSG_Input), &pb.C2S_InputMsg{
+			Sid: proto.Int32(1),
+			X:   proto.Int32(x),
+		}))
+	}
+	c2.packets = nil
+	for i := int32(0); i < 2; i++ {
+		input(101 + i)
+		g.Tick(time.Now().Unix())
+	}
+	if n := inputsOf(t, c2, 1); n != 0 {
+		t.Errorf("%d invalid inputs are broadcast", n)
+	}
+	input(50)
+	g.Tick(time.Now().Unix())
+	if n := inputsOf(t, c2, 1); n != 1 {
+		t.Errorf("%d valid inputs are broadcast", n)
+	}
+
+	v := g.Violations()[1]
+	if v.Count != 2 || v.Kicked || len(v.Latest) != 2 || v.Latest[1].X != 102 || !v.Latest[1].Rejected {
+		t.Fatalf("violations: %+v", v)
+	}
+
+	// the player is kicked out at the threshold, and cannot join again
+	input(-1)
+	if v = g.Violations()[1]; v.Count != 3 || !v.Kicked {
+		t.Fatalf("violations: %+v", v)
+	}
+	if !c1.closed || c1.GetExtraData() != nil || g.getPlayer(1).IsOnline() {
+		t.Error("the conn of the player kicked should be closed and detached")
+	}
+	c3 := &testConn{extraData: uint64(1)}
+	g.JoinGame(1, c3, "")
+	ret := &pb.S2C_ConnectMsg{}
+	if len(c3.packets) != 1 || c3.packets[0].UnmarshalPB(ret) != nil || ret.GetErrorCode() != pb.ERRORCODE_ERR_Kicked {
+		t.Errorf("join again got: %v", ret)
+	}
+	if c3.GetExtraData() != nil || g.getPlayer(1).IsOnline() {
+		t.Error("the conn of the player kicked should be detached")
+	}
+}
+
+func Test_SharedFrames(t *testing.T) {
+	g := NewGame(1, []uint64{1, 2, 3}, 0, nopListener{}, nil)
+	conns := make([]*testConn, 3)
+	for i := range conns {
+		conns[i] = &testConn{extraData: uint64(i + 1)}
+		g.JoinGame(uint64(i+1), conns[i], "")
+	}
+	now := time.Now().Unix()
+	g.Tick(now)
+	for i := 0; i < BroadcastOffsetFrames; i++ {
+		g.Tick(now)
+	}
+	// player 3 misses the broadcast
+	g.getPlayer(3).lastHeartbeatTime = now - kBadNetworkThreshold
+	for _, c := range conns {
+		c.packets = nil
+	}
+	g.pushInput(g.getPlayer(1), &pb.C2S_InputMsg{Sid: proto.Int32(1)})
+	for i := 0; i < BroadcastOffsetFrames; i++ {
+		g.Tick(now)
+	}
+
+	// the players at the same frames share the message
+	if len(conns[0].packets) != 1 || len(conns[1].packets) != 1 || conns[0].packets[0] != conns[1].packets[0] {
+		t.Fatalf("the frame messages are not shared: %v %v", conns[0].packets, conns[1].packets)
+	}
+	if len(conns[2].packets) != 0 {
+		t.Fatalf("player 3 got: %v", conns[2].ids())
+	}
+
+	// the player behind catches up the frames by its own message
+	g.getPlayer(3).RefreshHeartbeat()
+	for _, c := range conns {
+		c.packets = nil
+	}
+	for i := 0; i < BroadcastOffsetFrames; i++ {
+		g.Tick(now)
+	}
+	if len(conns[0].packets) != 1 || conns[0].packets[0] != conns[1].packets[0] ||
+		len(conns[2].packets) != 1 || conns[2].packets[0] == conns[0].packets[0] {
+		t.Error("the frame messages should be shared by the players at the same frames only")
+	}
+	msg := &pb.S2C_FrameMsg{}
+	if err := conns[2].packets[0].UnmarshalPB(msg); err != nil || len(msg.GetFrames()) != 2 ||
+		len(msg.GetFrames()[0].GetInput()) != 1 {
+		t.Errorf("player 3 got: %v %v", msg, err)
+	}
+}
+
+// discardConn encodes the packets and discards them, the packets are not kept after the call,
+// so the shared ones are neither retained nor released
+type discardConn struct {
+	testConn
+	protocol *pb_packet.MsgProtocol
+}
+
+func (c *discardConn) AsyncWritePacket(p network.Packet, _ time.Duration) error {
+	_, _ = c.protocol.EncodePacket(p)
+	return nil
+}
+
+// benchmarkBroadcast broadcasts the frames of the players inputting every frame,
+// the messages are encoded for every player unless shared
+func benchmarkBroadcast(b *testing.B, players int, shared bool) {
+	pids := make([]uint64, players)
+	for i := range pids {
+		pids[i] = uint64(i + 1)
+	}
+	g := NewGame(1, pids, 0, nopListener{}, nil)
+	for _, pid := range pids {
+		g.JoinGame(pid, &discardConn{testConn: testConn{extraData: pid}, protocol: &pb_packet.MsgProtocol{}}, "")
+	}
+	now := time.Now().Unix()
+	g.Tick(now)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		if g.logic.getFrameCount() >= MaxGameFrame {
+			b.StopTimer()
+			_ = g.logic.reset()
+			g.clientFrameCount = 0
+			for _, p := range g.players {
+				p.SetSendFrameCount(0)
+			}
+			b.StartTimer()
+		}
+		for _, pid := range pids {
+			g.pushInput(g.players[pid], &pb.C2S_InputMsg{Sid: proto.Int32(1), X: proto.Int32(int32(n))})
+		}
+		_, _ = g.logic.tick()
+		g.dirty = true
+		if shared {
+			g.broadcastFrameData()
+			continue
+		}
+		// every player marshals its own messages as before the sharing
+		to := g.logic.getFrameCount()
+		for _, p := range g.players {
+			var frames []*pb.FrameData
+			for i := p.GetSendFrameCount(); i < to; i++ {
+				if f, _ := g.frameData(i); len(f.Input) > 0 || i == to-1 {
+					frames = append(frames, f)
+				}
+			}
+			for _, msg := range splitFrames(frames, kMaxFrameBytesPerMsg) {
+				p.SendMessage(pb_packet.NewPacket(uint8(pb.ID_MSG_Frame), msg))
+			}
+			p.SetSendFrameCount(to)
+		}
+		g.clientFrameCount = to
+	}
+}
+
+func BenchmarkBroadcast_8Players(b *testing.B) {
+	benchmarkBroadcast(b, 8, true)
+}
+
+func BenchmarkBroadcast_8PlayersUnshared(b *testing.B) {
+	benchmarkBroadcast(b, 8, false)
+}
+
+func BenchmarkBroadcast_16Players(b *testing.B) {
+	benchmarkBroadcast(b, 16, true)
+}
+
+func BenchmarkBroadcast_16PlayersUnshared(b *testing.B) {
+	benchmarkBroadcast(b, 16, false)
+}
@@ -4,72 +4,55 @@ import (
 	"github.com/hedon954/go-lock-step-server/pb"
 )
 
-type frameData struct {
-	idx  uint32
-	cmds []*pb.InputData
-}
-
-func newFrameData(index uint32) *frameData {
-	return &frameData{
-		idx:  index,
-		cmds: make([]*pb.InputData, 0),
-	}
-}
-
 type lockstep struct {
-	frames     map[uint32]*frameData
+	store      FrameStore      // the frames over
+	current    []*pb.InputData // the inputs of the frame frameCount
 	frameCount uint32
 }
 
-func newLockStep() *lockstep {
+func newLockStep(store FrameStore) *lockstep {
 	return &lockstep{
-		frames: make(map[uint32]*frameData),
+		store: store,
 	}
 }
 
-func (l *lockstep) reset() {
-	l.frames = make(map[uint32]*frameData)
+func (l *lockstep) reset() error {
+	l.current = nil
 	l.frameCount = 0
+	return l.store.Reset()
 }
 
 func (l *lockstep) getFrameCount() uint32 {
 	return l.frameCount
 }
 
-func (l *lockstep) tick() uint32 {
+// tick closes the current frame, the frame goes on even if the store fails
+func (l *lockstep) tick() (uint32, error) {
+	err := l.store.Append(l.current)
+	l.current = nil
 	l.frameCount++
-	return l.frameCount
+	return l.frameCount, err
 }
 
-func (l *lockstep) getFrame(idx uint32) *frameData {
-	return l.frames[idx]
-}
-
-func (l *lockstep) getRangeFrames(from, to uint32) []*frameData {
-	ret := make([]*frameData, 0, to-from)
-	for ; from <= to && from <= l.frameCount; from++ {
-		f, ok := l.frames[from]
-		if !ok {
-			continue
-		}
-		ret = append(ret, f)
+// getFrame returns the inputs of the frame, nil if it has no input
+func (l *lockstep) getFrame(idx uint32) ([]*pb.InputData, error) {
+	if idx == l.frameCount {
+		return l.current, nil
 	}
-	return ret
+	return l.store.Get(idx)
 }
 
 func (l *lockstep) pushCmd(cmd *pb.InputData) bool {
-	f, ok := l.frames[l.frameCount]
-	if !ok {
-		f = newFrameData(l.frameCount)
-		l.frames[l.frameCount] = f
-	}
-
 	// check if the same frame sent two operations
-	for _, c := range f.cmds {
+	for _, c := range l.current {
 		if c.Id == cmd.Id {
 			return false
 		}
 	}
-	f.cmds = append(f.cmds, cmd)
+	l.current = append(l.current, cmd)
 	return true
 }
+
+func (l *lockstep) close() error {
+	return l.store.Close()
+}
@@ -3,9 +3,26 @@ package game
 import (
 	"time"
 
+	"github.com/hedon954/go-lock-step-server/pb"
+	"github.com/hedon954/go-lock-step-server/pkg/clock"
 	"github.com/hedon954/go-lock-step-server/pkg/network"
+	"github.com/hedon954/go-lock-step-server/pkg/packet/pb_packet"
+	"google.golang.org/protobuf/proto"
 )
 
+// PlayerConn is the connection occupying the seat of a player, network.Conn is the one of the remote players,
+// the extra data is the id of the player, and it is cleared once the connection is detached from the player.
+// The frames are written as network.SharedPacket whose buffer is reused once the game releases it, so AsyncWritePacket
+// must Retain a shared packet it keeps after returning, and Release it once done as network.Conn does
+type PlayerConn interface {
+	AsyncWritePacket(p network.Packet, timeout time.Duration) error
+	Close()
+	GetExtraData() interface{}
+	PutExtraData(data interface{})
+}
+
+var _ PlayerConn = (*network.Conn)(nil)
+
 // Player defines a player state
 type Player struct {
 	id                uint64
@@ -15,30 +32,64 @@ type Player struct {
 	loadingProgress   int32
 	lastHeartbeatTime int64
 	sendFrameCount    uint32
-	client            *network.Conn
+	protocolVersion   uint32
+	ticket            string
+	offlineTime       time.Time // when the conn was lost
+	client            PlayerConn
+	clock             clock.Clock
+	bot               Bot
+	loadout           []byte
+	violations        PlayerViolations
+	timing            timingTracker
+	desyncs           int
 }
 
 // NewPlayer creates a new player state
 func NewPlayer(id uint64, idx int32) *Player {
 	return &Player{
-		id:  id,
-		idx: idx,
+		id:    id,
+		idx:   idx,
+		clock: clock.Real,
 	}
 }
 
-func (p *Player) Connect(conn *network.Conn) {
+// Connect attaches the conn to the player, the previous conn is closed,
+// and its leave is ignored by the room as it is detached from the player.
+// The player takes the seat back from the bot
+func (p *Player) Connect(conn PlayerConn) {
+	if p.client != nil && p.client != conn {
+		p.client.PutExtraData(nil)
+		p.client.Close()
+	}
 	p.client = conn
+	p.bot = nil
+	p.protocolVersion = pb_packet.VersionOf(conn)
 	p.isOnline = true
 	p.isReady = true
-	p.lastHeartbeatTime = time.Now().Unix()
+	p.lastHeartbeatTime = p.clock.Now().Unix()
+	p.timing.reset()
 }
 
 func (p *Player) IsOnline() bool {
 	return p.client != nil && p.isOnline
 }
 
+// ProtocolVersion returns the protocol version negotiated by the connection of the player
+func (p *Player) ProtocolVersion() uint32 {
+	return p.protocolVersion
+}
+
+// getLoadout returns the loadout submitted by the player
+func (p *Player) getLoadout() *pb.Loadout {
+	return &pb.Loadout{
+		Roomseatid: proto.Int32(p.idx),
+		Id:         proto.Uint64(p.id),
+		Data:       p.loadout,
+	}
+}
+
 func (p *Player) RefreshHeartbeat() {
-	p.lastHeartbeatTime = time.Now().Unix()
+	p.lastHeartbeatTime = p.clock.Now().Unix()
 }
 
 func (p *Player) GetLastHeartbeatTime() int64 {
@@ -66,6 +117,9 @@ func (p *Player) Cleanup() {
 	if p.client != nil {
 		p.client.Close()
 	}
+	if p.isOnline {
+		p.offlineTime = p.clock.Now()
+	}
 	p.client = nil
 	p.isOnline = false
 	p.isReady = false
@@ -0,0 +1,21 @@
+package game
+
+import (
+	"github.com/hedon954/go-lock-step-server/pb"
+)
+
+// Simulation is the deterministic simulation of a type of game, the server steps it with the inputs of every frame,
+// so that its state hash validates the checksums of the clients, and its winner settles the results reported
+type Simulation interface {
+	// Start is called once the game starts, the seat of players[i] is i+1
+	Start(randomSeed int32, players []uint64, loadouts []*pb.Loadout)
+
+	// Step advances the state by the frame, the frames are stepped in order from 0
+	Step(frame *pb.FrameData)
+
+	// Hash returns the hash of the state after the latest frame stepped
+	Hash() uint64
+
+	// Winner returns the seat of the winner, 0 if it is undecided
+	Winner() int32
+}
@@ -0,0 +1,95 @@
+package game
+
+import (
+	"testing"
+	"time"
+
+	"github.com/hedon954/go-lock-step-server/pb"
+	"github.com/hedon954/go-lock-step-server/pkg/packet/pb_packet"
+	"google.golang.org/protobuf/proto"
+)
+
+// sumSimulation sums the x of the inputs, the seat sending x of 100 wins
+type sumSimulation struct {
+	players []uint64
+	frames  uint32
+	sum     int64
+	winner  int32
+}
+
+func (s *sumSimulation) Start(_ int32, players []uint64, _ []*pb.Loadout) {
+	s.players = players
+}
+
+func (s *sumSimulation) Step(frame *pb.FrameData) {
+	if frame.GetFrameID() != s.frames {
+		panic("the frames are not stepped in order")
+	}
+	s.frames++
+	for _, in := range frame.GetInput() {
+		s.sum += int64(in.GetX())
+		if in.GetX() == 100 && s.winner == 0 {
+			s.winner = in.GetRoomseatid()
+		}
+	}
+}
+
+func (s *sumSimulation) Hash() uint64 {
+	return uint64(s.sum)<<32 | uint64(s.frames)
+}
+
+func (s *sumSimulation) Winner() int32 {
+	return s.winner
+}
+
+func Test_Simulation(t *testing.T) {
+	sim := &sumSimulation{}
+	g := NewGame(1, []uint64{1, 2}, 0, nopListener{}, &Config{Simulation: sim})
+	c1 := &testConn{extraData: uint64(1)}
+	c2 := &testConn{extraData: uint64(2)}
+	g.JoinGame(1, c1, "")
+	g.JoinGame(2, c2, "")
+	g.Tick(time.Now().Unix())
+	if len(sim.players) != 2 || sim.players[0] != 1 || sim.players[1] != 2 {
+		t.Fatalf("players got: %v", sim.players)
+	}
+
+	send := func(pid uint64, id pb.ID, msg proto.Message) {
+		g.ProcessMsg(pid, pb_packet.NewPacket(uint8(id), msg))
+	}
+	send(1, pb.ID_MSG_Input, &pb.C2S_InputMsg{X: proto.Int32(7)})
+	g.Tick(time.Now().Unix()) // frame 0
+	send(2, pb.ID_MSG_Input, &pb.C2S_InputMsg{X: proto.Int32(100)})
+	g.Tick(time.Now().Unix()) // frame 1
+	if sim.frames != 2 || sim.sum != 107 || g.Winner() != 2 {
+		t.Fatalf("simulation got: %+v, winner: %d", sim, g.Winner())
+	}
+
+	// the roster of the simulation can not change
+	if _, err := g.AssignSeat(3, 3); err != ErrSimulated {
+		t.Errorf("assign seat got: %v", err)
+	}
+
+	c1.packets, c2.packets = nil, nil
+	send(1, pb.ID_MSG_Checksum, &pb.C2S_ChecksumMsg{FrameID: proto.Uint32(0), Hash: proto.Uint64(7<<32 | 1)})
+	send(2, pb.ID_MSG_Checksum, &pb.C2S_ChecksumMsg{FrameID: proto.Uint32(1), Hash: proto.Uint64(7<<32 | 2)})
+	send(2, pb.ID_MSG_Checksum, &pb.C2S_ChecksumMsg{FrameID: proto.Uint32(5), Hash: proto.Uint64(0)})
+	if len(c1.packets) != 0 {
+		t.Errorf("player 1 got: %v", c1.ids())
+	}
+	ret := &pb.S2C_ChecksumMsg{}
+	if len(c2.packets) != 1 || c2.packets[0].UnmarshalPB(ret) != nil || ret.GetFrameID() != 1 ||
+		ret.GetHash() != 107<<32|2 {
+		t.Errorf("player 2 got: %v", ret)
+	}
+	if d := g.Desyncs(); len(d) != 1 || d[2] != 1 {
+		t.Errorf("desyncs got: %v", d)
+	}
+
+	// the simulated winner settles the results disputed
+	send(1, pb.ID_MSG_Result, &pb.C2S_ResultMsg{WinnerID: proto.Uint64(1)})
+	send(2, pb.ID_MSG_Result, &pb.C2S_ResultMsg{WinnerID: proto.Uint64(2)})
+	if g.Result()[1] != 1 || g.Winner() != 2 {
+		t.Errorf("result got: %v, winner: %d", g.Result(), g.Winner())
+	}
+}
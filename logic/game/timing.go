@@ -0,0 +1,228 @@
+package game
+
+import (
+	"time"
+)
+
+const (
+	// DefaultSilence is the gap without any message taken as a silence if TimingConfig.Silence is 0
+	DefaultSilence = time.Second
+
+	// DefaultBurstInputs is the number of the inputs clustered after a silence taken as a burst
+	// if TimingConfig.BurstInputs is 0
+	DefaultBurstInputs = 5
+
+	// DefaultBurstWindow is how long the inputs of a burst arrive within if TimingConfig.BurstWindow is 0
+	DefaultBurstWindow = time.Second / 10
+
+	// DefaultMaxAheadFrames is how many frames an input can request ahead of the server
+	// if TimingConfig.MaxAheadFrames is 0
+	DefaultMaxAheadFrames = BroadcastOffsetFrames
+
+	// DefaultHeartbeatGap is the longest gap between the heartbeats if TimingConfig.HeartbeatGap is 0
+	DefaultHeartbeatGap = time.Second * 3
+
+	// DefaultMaxJitter is the highest jitter in frames of the input arrivals if TimingConfig.MaxJitter is 0
+	DefaultMaxJitter = 3
+
+	// DefaultReportScore is the score step reported to the listener if TimingConfig.ReportScore is 0
+	DefaultReportScore = 10
+)
+
+// Anomaly is a suspicious pattern of the timing of a player
+type Anomaly int
+
+const (
+	AnomalyJitter       Anomaly = iota // the input arrivals jitter more than MaxJitter
+	AnomalyHeartbeatGap                // no heartbeat arrives for longer than HeartbeatGap
+	AnomalyBurst                       // the inputs are clustered after a silence, like a lag switch
+	AnomalyAhead                       // the input requests a frame too far ahead, like a speed hack
+)
+
+var anomalyStrings = []string{"jitter", "heartbeat-gap", "burst", "ahead"}
+
+func (a Anomaly) String() string {
+	if a < 0 || int(a) >= len(anomalyStrings) {
+		return "unknown"
+	}
+	return anomalyStrings[a]
+}
+
+// TimingConfig is the thresholds of the timing anomalies, the zero fields mean the defaults
+type TimingConfig struct {
+	Silence        time.Duration
+	BurstInputs    int
+	BurstWindow    time.Duration
+	MaxAheadFrames uint32
+	HeartbeatGap   time.Duration
+	MaxJitter      float64
+
+	// ReportScore reports the player to the listener once the score reaches it, and every time it grows by it again
+	ReportScore float64
+}
+
+func (c *TimingConfig) withDefaults() TimingConfig {
+	var ret TimingConfig
+	if c != nil {
+		ret = *c
+	}
+	if ret.Silence == 0 {
+		ret.Silence = DefaultSilence
+	}
+	if ret.BurstInputs == 0 {
+		ret.BurstInputs = DefaultBurstInputs
+	}
+	if ret.BurstWindow == 0 {
+		ret.BurstWindow = DefaultBurstWindow
+	}
+	if ret.MaxAheadFrames == 0 {
+		ret.MaxAheadFrames = DefaultMaxAheadFrames
+	}
+	if ret.HeartbeatGap == 0 {
+		ret.HeartbeatGap = DefaultHeartbeatGap
+	}
+	if ret.MaxJitter == 0 {
+		ret.MaxJitter = DefaultMaxJitter
+	}
+	if ret.ReportScore == 0 {
+		ret.ReportScore = DefaultReportScore
+	}
+	return ret
+}
+
+// TimingStats is the statistics of when the inputs and the heartbeats of a player arrive while gaming
+type TimingStats struct {
+	Inputs          int
+	Heartbeats      int
+	Jitter          float64 // the smoothed variation of the frames the inputs arrive behind the frames requested
+	MaxHeartbeatGap time.Duration
+	MaxAhead        uint32 // the most frames an input has requested ahead of the server
+
+	// the numbers of the anomalies
+	JitterSpikes  int
+	HeartbeatGaps int
+	Bursts        int
+	AheadInputs   int
+
+	// Score weighs the anomalies, a burst scores 3, an input ahead 2, and the others 1
+	Score float64
+}
+
+// timingTracker tracks the timing of a player, the gaps across the connections are counted as well
+type timingTracker struct {
+	stats         TimingStats
+	lags          int // the inputs the lags are known of since connected
+	lastLag       int64
+	lastHeartbeat time.Time
+	lastActivity  time.Time // the latest message of any kind
+	burstStart    time.Time // the first message after the latest silence, zero if the burst window is over
+	burstInputs   int
+	jitterHigh    bool
+	reported      int // the score steps reported
+}
+
+// reset forgets the lag of the inputs, it is called when the player connects,
+// so that the first heartbeat and message on the new connection measure the gap since the previous one
+func (t *timingTracker) reset() {
+	t.lags = 0
+}
+
+// onMessage tracks the arrival of a message of any kind, it is called before onInput and onHeartbeat,
+// the first message after a silence opens the burst window
+func (t *timingTracker) onMessage(c *TimingConfig, now time.Time) {
+	if !t.lastActivity.IsZero() && now.Sub(t.lastActivity) >= c.Silence {
+		t.burstStart = now
+		t.burstInputs = 0
+	}
+	t.lastActivity = now
+}
+
+// onInput tracks the input arriving in the frame current of the server, frameID is the frame requested by it,
+// nil if unknown. It returns the anomalies found
+func (t *timingTracker) onInput(c *TimingConfig, now time.Time, current uint32, frameID *uint32) []Anomaly {
+	var ret []Anomaly
+	t.stats.Inputs++
+
+	if frameID != nil {
+		lag := int64(current) - int64(*frameID)
+		if t.lags++; t.lags > 1 {
+			// the jitter is smoothed as RFC 3550 does
+			d := float64(lag - t.lastLag)
+			if d < 0 {
+				d = -d
+			}
+			t.stats.Jitter += (d - t.stats.Jitter) / 16
+			high := t.stats.Jitter > c.MaxJitter
+			if high && !t.jitterHigh {
+				ret = append(ret, AnomalyJitter)
+			}
+			t.jitterHigh = high
+		}
+		t.lastLag = lag
+
+		if ahead := -lag; ahead > 0 {
+			if uint32(ahead) > t.stats.MaxAhead {
+				t.stats.MaxAhead = uint32(ahead)
+			}
+			if uint32(ahead) > c.MaxAheadFrames {
+				ret = append(ret, AnomalyAhead)
+			}
+		}
+	}
+
+	if !t.burstStart.IsZero() {
+		if now.Sub(t.burstStart) > c.BurstWindow {
+			t.burstStart = time.Time{}
+		} else if t.burstInputs++; t.burstInputs == c.BurstInputs {
+			ret = append(ret, AnomalyBurst)
+		}
+	}
+	return t.count(ret)
+}
+
+// onHeartbeat tracks the heartbeat, it returns the anomalies found
+func (t *timingTracker) onHeartbeat(c *TimingConfig, now time.Time) []Anomaly {
+	var ret []Anomaly
+	t.stats.Heartbeats++
+	if !t.lastHeartbeat.IsZero() {
+		gap := now.Sub(t.lastHeartbeat)
+		if gap > t.stats.MaxHeartbeatGap {
+			t.stats.MaxHeartbeatGap = gap
+		}
+		if gap > c.HeartbeatGap {
+			ret = append(ret, AnomalyHeartbeatGap)
+		}
+	}
+	t.lastHeartbeat = now
+	return t.count(ret)
+}
+
+func (t *timingTracker) count(anomalies []Anomaly) []Anomaly {
+	for _, a := range anomalies {
+		switch a {
+		case AnomalyJitter:
+			t.stats.JitterSpikes++
+			t.stats.Score++
+		case AnomalyHeartbeatGap:
+			t.stats.HeartbeatGaps++
+			t.stats.Score++
+		case AnomalyBurst:
+			t.stats.Bursts++
+			t.stats.Score += 3
+		case AnomalyAhead:
+			t.stats.AheadInputs++
+			t.stats.Score += 2
+		}
+	}
+	return anomalies
+}
+
+// report returns true if the score has reached a step not reported yet
+func (t *timingTracker) report(c *TimingConfig) bool {
+	steps := int(t.stats.Score / c.ReportScore)
+	if steps <= t.reported {
+		return false
+	}
+	t.reported = steps
+	return true
+}
@@ -0,0 +1,136 @@
+package game
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/hedon954/go-lock-step-server/pb"
+	"github.com/hedon954/go-lock-step-server/pkg/clock"
+	"github.com/hedon954/go-lock-step-server/pkg/packet/pb_packet"
+	"google.golang.org/protobuf/proto"
+)
+
+func Test_TimingTracker(t *testing.T) {
+	c := (*TimingConfig)(nil).withDefaults()
+	now := time.Unix(1700000000, 0)
+	at := func(d time.Duration) time.Time { return now.Add(d) }
+	tr := &timingTracker{}
+
+	// the messages arrive like ProcessMsg tracks them
+	heartbeat := func(d time.Duration) []Anomaly {
+		tr.onMessage(&c, at(d))
+		return tr.onHeartbeat(&c, at(d))
+	}
+	input := func(d time.Duration, current uint32, frameID *uint32) []Anomaly {
+		tr.onMessage(&c, at(d))
+		return tr.onInput(&c, at(d), current, frameID)
+	}
+
+	// heartbeat gaps
+	heartbeat(0)
+	if a := heartbeat(time.Second); len(a) != 0 {
+		t.Errorf("heartbeat got: %v", a)
+	}
+	if a := heartbeat(time.Second * 5); !reflect.DeepEqual(a, []Anomaly{AnomalyHeartbeatGap}) {
+		t.Errorf("heartbeat gap got: %v", a)
+	}
+
+	// the inputs clustered after a silence, the silence is broken by the first of them
+	var found []Anomaly
+	for i := 0; i < DefaultBurstInputs; i++ {
+		found = append(found, input(time.Second*7+time.Millisecond*time.Duration(i), 0, nil)...)
+	}
+	if !reflect.DeepEqual(found, []Anomaly{AnomalyBurst}) {
+		t.Errorf("burst got: %v", found)
+	}
+	if a := input(time.Second*8, 0, nil); len(a) != 0 {
+		t.Errorf("input got: %v", a)
+	}
+
+	// the inputs requesting the frames ahead of the server
+	if a := input(time.Second*8, 10, proto.Uint32(12)); len(a) != 0 {
+		t.Errorf("input got: %v", a)
+	}
+	if a := input(time.Second*8, 10, proto.Uint32(20)); !reflect.DeepEqual(a, []Anomaly{AnomalyAhead}) {
+		t.Errorf("ahead got: %v", a)
+	}
+
+	want := TimingStats{
+		Inputs:          DefaultBurstInputs + 3,
+		Heartbeats:      3,
+		Jitter:          0.5, // |-10 - -2| / 16
+		MaxHeartbeatGap: time.Second * 4,
+		MaxAhead:        10,
+		HeartbeatGaps:   1,
+		Bursts:          1,
+		AheadInputs:     1,
+		Score:           6,
+	}
+	if tr.stats != want {
+		t.Errorf("stats want: %+v, got: %+v", want, tr.stats)
+	}
+
+	// the silence broken by a heartbeat opens the burst window as well
+	found = heartbeat(time.Second * 10)
+	for i := 1; i <= DefaultBurstInputs; i++ {
+		found = append(found, input(time.Second*10+time.Millisecond*time.Duration(i), 0, nil)...)
+	}
+	if !reflect.DeepEqual(found, []Anomaly{AnomalyHeartbeatGap, AnomalyBurst}) {
+		t.Errorf("burst after heartbeat got: %v", found)
+	}
+
+	// the lags swinging between 0 and 20 frames, the spike is found once
+	tr = &timingTracker{}
+	found = nil
+	for i := 0; i < 10; i++ {
+		found = append(found, input(time.Second*time.Duration(i), uint32(20*(i%2)), proto.Uint32(0))...)
+	}
+	if !reflect.DeepEqual(found, []Anomaly{AnomalyJitter}) || tr.stats.Jitter <= DefaultMaxJitter {
+		t.Errorf("jitter got: %v %.2f", found, tr.stats.Jitter)
+	}
+
+	// the gap across the connections is counted, and the first message on the new one opens the burst window
+	heartbeat(time.Second * 10)
+	tr.reset()
+	if a := heartbeat(time.Minute); !reflect.DeepEqual(a, []Anomaly{AnomalyHeartbeatGap}) {
+		t.Errorf("heartbeat after reconnecting got: %v", a)
+	}
+	if tr.burstStart != at(time.Minute) {
+		t.Errorf("burst window after reconnecting got: %v", tr.burstStart)
+	}
+}
+
+type timingListener struct {
+	nopListener
+	reports []TimingStats
+}
+
+func (l *timingListener) OnTimingAnomaly(_ uint64, _ uint64, stats TimingStats) {
+	l.reports = append(l.reports, stats)
+}
+
+func Test_TimingReport(t *testing.T) {
+	fake := clock.NewFake(time.Unix(1700000000, 0))
+	l := &timingListener{}
+	g := NewGame(1, []uint64{1}, 0, l, &Config{Clock: fake, Timing: &TimingConfig{ReportScore: 4}})
+	g.JoinGame(1, &testConn{extraData: uint64(1)}, "")
+	g.Tick(fake.Now().Unix())
+
+	for i := 0; i < 5; i++ {
+		g.ProcessMsg(1, pb_packet.NewPacket(uint8(pb.ID_MSG_Input), &pb.C2S_InputMsg{
+			Sid:     proto.Int32(1),
+			FrameID: proto.Uint32(100),
+		}))
+		fake.Advance(time.Second / 30)
+		g.Tick(fake.Now().Unix())
+	}
+
+	// every input ahead scores 2, so the player is reported at the scores 4 and 8
+	if len(l.reports) != 2 || l.reports[0].Score != 4 || l.reports[1].Score != 8 {
+		t.Fatalf("reports got: %+v", l.reports)
+	}
+	if s := g.Timing()[1]; s.Inputs != 5 || s.AheadInputs != 5 || s.Score != 10 {
+		t.Errorf("timing got: %+v", s)
+	}
+}
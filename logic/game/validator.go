@@ -0,0 +1,110 @@
+package game
+
+import (
+	"fmt"
+
+	"github.com/hedon954/go-lock-step-server/pb"
+)
+
+// MaxViolationsKept is the number of the latest violations kept for every player
+const MaxViolationsKept = 16
+
+// Verdict is the decision of an InputValidator on an input
+type Verdict int
+
+const (
+	VerdictAccept Verdict = iota // the input is pushed into the frame
+	VerdictFlag                  // the input is pushed into the frame, and the violation is recorded
+	VerdictReject                // the input is dropped, and the violation is recorded
+)
+
+// InputValidator checks the inputs of the players before they are pushed into the frames,
+// it is created for every game and called in the room's goroutine
+type InputValidator interface {
+	// Validate returns the verdict on the input of the player for the frame, and the reason if it is not accepted
+	Validate(pid uint64, frameID uint32, msg *pb.C2S_InputMsg) (Verdict, string)
+}
+
+// Violation is an input not accepted by the InputValidator
+type Violation struct {
+	FrameID  uint32
+	Sid      int32
+	X        int32
+	Y        int32
+	Reason   string
+	Rejected bool
+}
+
+// PlayerViolations are the violations of a player
+type PlayerViolations struct {
+	Count  int         // the number of all the violations
+	Latest []Violation // the latest MaxViolationsKept violations
+	Kicked bool        // the player has been kicked out for reaching Config.KickThreshold
+}
+
+// RulesValidator validates the inputs by bounds, an action whitelist and an action rate
+type RulesValidator struct {
+	MinX, MaxX int32 // the bounds of x, no bounds if both are 0
+	MinY, MaxY int32 // the bounds of y, no bounds if both are 0
+
+	// Actions are the sids accepted, nil means any
+	Actions map[int32]bool
+
+	// RateLimit is the number of the inputs a player can send every RateFrames frames, 0 means no limit
+	RateLimit  int
+	RateFrames uint32
+
+	// FlagOnly flags the violations instead of rejecting them
+	FlagOnly bool
+
+	windows map[uint64]*rateWindow
+}
+
+// rateWindow counts the inputs of a player in the frames [start, start+RateFrames)
+type rateWindow struct {
+	start uint32
+	count int
+}
+
+func (v *RulesValidator) Validate(pid uint64, frameID uint32, msg *pb.C2S_InputMsg) (Verdict, string) {
+	reason := v.check(pid, frameID, msg)
+	if reason == "" {
+		return VerdictAccept, ""
+	}
+	if v.FlagOnly {
+		return VerdictFlag, reason
+	}
+	return VerdictReject, reason
+}
+
+func (v *RulesValidator) check(pid uint64, frameID uint32, msg *pb.C2S_InputMsg) string {
+	if v.RateLimit > 0 {
+		frames := v.RateFrames
+		if frames == 0 {
+			frames = 1
+		}
+		if v.windows == nil {
+			v.windows = make(map[uint64]*rateWindow)
+		}
+		w, ok := v.windows[pid]
+		if !ok || frameID >= w.start+frames || frameID < w.start {
+			w = &rateWindow{start: frameID - frameID%frames}
+			v.windows[pid] = w
+		}
+		// the inputs over the limit are counted as well
+		w.count++
+		if w.count > v.RateLimit {
+			return fmt.Sprintf("rate %d > %d in %d frames", w.count, v.RateLimit, frames)
+		}
+	}
+	if v.Actions != nil && !v.Actions[msg.GetSid()] {
+		return fmt.Sprintf("action %d not allowed", msg.GetSid())
+	}
+	if (v.MinX != 0 || v.MaxX != 0) && (msg.GetX() < v.MinX || msg.GetX() > v.MaxX) {
+		return fmt.Sprintf("x %d out of [%d, %d]", msg.GetX(), v.MinX, v.MaxX)
+	}
+	if (v.MinY != 0 || v.MaxY != 0) && (msg.GetY() < v.MinY || msg.GetY() > v.MaxY) {
+		return fmt.Sprintf("y %d out of [%d, %d]", msg.GetY(), v.MinY, v.MaxY)
+	}
+	return ""
+}
@@ -0,0 +1,49 @@
+package game
+
+import (
+	"testing"
+
+	"github.com/hedon954/go-lock-step-server/pb"
+	"google.golang.org/protobuf/proto"
+)
+
+func Test_RulesValidator(t *testing.T) {
+	input := func(sid, x, y int32) *pb.C2S_InputMsg {
+		return &pb.C2S_InputMsg{Sid: proto.Int32(sid), X: proto.Int32(x), Y: proto.Int32(y)}
+	}
+	v := &RulesValidator{
+		MinX: -10, MaxX: 10,
+		MaxY:       10,
+		Actions:    map[int32]bool{1: true, 2: true},
+		RateLimit:  2,
+		RateFrames: 10,
+	}
+
+	tests := []struct {
+		pid     uint64
+		frameID uint32
+		msg     *pb.C2S_InputMsg
+		verdict Verdict
+	}{
+		{1, 0, input(1, -10, 10), VerdictAccept},
+		{1, 1, input(3, 0, 0), VerdictReject},
+		{1, 2, input(2, 0, 0), VerdictReject}, // the third input in the frames [0, 10)
+		{2, 2, input(2, 11, 0), VerdictReject},
+		{2, 3, input(2, 0, -1), VerdictReject},
+		{1, 10, input(2, 10, 0), VerdictAccept},
+		{1, 19, input(1, 0, 0), VerdictAccept},
+		{1, 19, input(1, 0, 0), VerdictReject},
+		{1, 25, input(1, 0, 0), VerdictAccept},
+	}
+	for i, tt := range tests {
+		verdict, reason := v.Validate(tt.pid, tt.frameID, tt.msg)
+		if verdict != tt.verdict || (verdict == VerdictAccept) != (reason == "") {
+			t.Errorf("#%d want: %d, got: (%d, %s)", i, tt.verdict, verdict, reason)
+		}
+	}
+
+	v = &RulesValidator{MaxX: 10, FlagOnly: true}
+	if verdict, _ := v.Validate(1, 0, input(0, 11, 100)); verdict != VerdictFlag {
+		t.Errorf("want: %d, got: %d", VerdictFlag, verdict)
+	}
+}
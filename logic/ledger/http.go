@@ -0,0 +1,72 @@
+package ledger
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+)
+
+// PlayerJSON is the rating and the latest matches of a player replied by the http api
+type PlayerJSON struct {
+	Player  uint64   `json:"player"`
+	Rating  Rating   `json:"rating"`
+	History []*Match `json:"history,omitempty"`
+}
+
+// Handler returns the http api of the ledger:
+//
+//	/rating?player=1            replies PlayerJSON without history
+//	/history?player=1&limit=10  replies PlayerJSON with the latest matches, a limit of 0 means all
+//	/match?seq=1                replies the match
+func (l *Ledger) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/rating", l.handleRating)
+	mux.HandleFunc("/history", l.handleHistory)
+	mux.HandleFunc("/match", l.handleMatch)
+	return mux
+}
+
+func (l *Ledger) handleRating(w http.ResponseWriter, r *http.Request) {
+	pid, err := strconv.ParseUint(r.URL.Query().Get("player"), 10, 64)
+	if err != nil {
+		http.Error(w, "bad player", http.StatusBadRequest)
+		return
+	}
+	writeJSON(w, &PlayerJSON{Player: pid, Rating: l.Rating(pid)})
+}
+
+func (l *Ledger) handleHistory(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	pid, err := strconv.ParseUint(query.Get("player"), 10, 64)
+	if err != nil {
+		http.Error(w, "bad player", http.StatusBadRequest)
+		return
+	}
+	var limit int
+	if s := query.Get("limit"); s != "" {
+		if limit, err = strconv.Atoi(s); err != nil {
+			http.Error(w, "bad limit", http.StatusBadRequest)
+			return
+		}
+	}
+	writeJSON(w, &PlayerJSON{Player: pid, Rating: l.Rating(pid), History: l.History(pid, limit)})
+}
+
+func (l *Ledger) handleMatch(w http.ResponseWriter, r *http.Request) {
+	seq, err := strconv.ParseUint(r.URL.Query().Get("seq"), 10, 64)
+	if err != nil {
+		http.Error(w, "bad seq", http.StatusBadRequest)
+		return
+	}
+	m, ok := l.Match(seq)
+	if !ok {
+		http.Error(w, "match not found", http.StatusNotFound)
+		return
+	}
+	writeJSON(w, m)
+}
+
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(v)
+}
@@ -0,0 +1,248 @@
+// Package ledger records the finished matches and rates the players by their results
+package ledger
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/alecthomas/log4go"
+	"github.com/hedon954/go-lock-step-server/logic/room"
+)
+
+// Match is a match recorded by the ledger
+type Match struct {
+	Seq      uint64                  `json:"seq"` // the sequence number in the ledger, from 1
+	RoomID   uint64                  `json:"room"`
+	TypeID   int32                   `json:"type"`
+	Reason   string                  `json:"reason"`
+	Players  []uint64                `json:"players"`
+	Teams    map[uint64]int32        `json:"teams"`
+	Winner   uint64                  `json:"winner,omitempty"` // a player of the winning team, 0 means a draw
+	Start    int64                   `json:"start"`            // unix seconds the room was created
+	End      int64                   `json:"end"`              // unix seconds the room quit
+	Duration time.Duration           `json:"duration"`         // the game time of the frames
+	Frames   uint32                  `json:"frames"`
+	Replay   string                  `json:"replay,omitempty"`
+	Ratings  map[uint64]RatingChange `json:"ratings,omitempty"` // empty if the match is not rated
+
+	// Violations are the numbers of the invalid inputs of the players who have sent them
+	Violations map[uint64]int `json:"violations,omitempty"`
+	Kicked     []uint64       `json:"kicked,omitempty"` // the players kicked out for the invalid inputs
+
+	// Desyncs are the numbers of the state hashes of the players differing from the server's simulation
+	Desyncs map[uint64]int `json:"desyncs,omitempty"`
+}
+
+// RatingChange is the rating of a player before and after a match
+type RatingChange struct {
+	Before Rating `json:"before"`
+	After  Rating `json:"after"`
+}
+
+// Config is the configuration of a ledger
+type Config struct {
+	// Store keeps the matches, nil means a MemoryStore
+	Store Store
+
+	// Rating rates the players of the finished matches, nil means Elo with DefaultEloK
+	Rating RatingSystem
+
+	// Teams returns the team of every player, nil means every player is a team of its own
+	Teams func(result *room.Result) map[uint64]int32
+
+	// Replay returns the reference of the replay of the room, nil means no replay
+	Replay func(result *room.Result) string
+}
+
+// Ledger records the finished matches and the ratings of the players,
+// it is a room.ResultReporter
+type Ledger struct {
+	config Config
+
+	mu      sync.RWMutex
+	matches []*Match
+	history map[uint64][]*Match // the matches of every player
+	ratings map[uint64]Rating
+}
+
+// New creates a ledger and loads the matches of the store, a nil config means the default one
+func New(config *Config) (*Ledger, error) {
+	l := &Ledger{
+		history: make(map[uint64][]*Match),
+		ratings: make(map[uint64]Rating),
+	}
+	if config != nil {
+		l.config = *config
+	}
+	if l.config.Store == nil {
+		l.config.Store = &MemoryStore{}
+	}
+	if l.config.Rating == nil {
+		l.config.Rating = &Elo{}
+	}
+
+	matches, err := l.config.Store.Load()
+	if err != nil {
+		return nil, err
+	}
+	for _, m := range matches {
+		l.add(m)
+	}
+	return l, nil
+}
+
+// ReportResult records the match of the room, the rooms no one has played are ignored,
+// and the players are rated if the game is over
+func (l *Ledger) ReportResult(result *room.Result) {
+	if result.Reason == room.ReasonNoShow || result.Reason == room.ReasonCancelled {
+		return
+	}
+
+	m := &Match{
+		RoomID:   result.RoomID,
+		TypeID:   result.TypeID,
+		Reason:   result.Reason.String(),
+		Players:  append([]uint64(nil), result.Players...),
+		Teams:    l.teams(result),
+		Winner:   winnerOf(result),
+		Start:    result.CreateTime,
+		End:      result.EndTime,
+		Duration: time.Duration(result.Frames) * room.TickTimer,
+		Frames:   result.Frames,
+	}
+	if l.config.Replay != nil {
+		m.Replay = l.config.Replay(result)
+	}
+	for pid, v := range result.Violations {
+		if m.Violations == nil {
+			m.Violations = make(map[uint64]int)
+		}
+		m.Violations[pid] = v.Count
+		if v.Kicked {
+			m.Kicked = append(m.Kicked, pid)
+		}
+	}
+	sort.Slice(m.Kicked, func(i, j int) bool { return m.Kicked[i] < m.Kicked[j] })
+	if len(result.Desyncs) > 0 {
+		m.Desyncs = result.Desyncs
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	m.Seq = uint64(len(l.matches)) + 1
+	if result.Reason == room.ReasonFinished {
+		before := make(map[uint64]Rating, len(m.Players))
+		for _, pid := range m.Players {
+			before[pid] = l.rating(pid)
+		}
+		after := l.config.Rating.Update(before, m.Teams, m.Winner)
+		m.Ratings = make(map[uint64]RatingChange, len(before))
+		for pid, r := range before {
+			m.Ratings[pid] = RatingChange{Before: r, After: after[pid]}
+		}
+	}
+
+	if err := l.config.Store.Append(m); err != nil {
+		log4go.Error("[ledger] store the match of room[%d] error: %v", m.RoomID, err)
+		return
+	}
+	l.add(m)
+	log4go.Info("[ledger] match[%d] room[%d] recorded winner=[%d]", m.Seq, m.RoomID, m.Winner)
+}
+
+// Rating returns the current rating of the player
+func (l *Ledger) Rating(pid uint64) Rating {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	return l.rating(pid)
+}
+
+// History returns the latest matches of the player, the newest first, a limit of 0 means all
+func (l *Ledger) History(pid uint64, limit int) []*Match {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	matches := l.history[pid]
+	if limit <= 0 || limit > len(matches) {
+		limit = len(matches)
+	}
+	ret := make([]*Match, 0, limit)
+	for i := len(matches) - 1; i >= len(matches)-limit; i-- {
+		ret = append(ret, matches[i])
+	}
+	return ret
+}
+
+// Match returns the match of the sequence number
+func (l *Ledger) Match(seq uint64) (*Match, bool) {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	if seq == 0 || seq > uint64(len(l.matches)) {
+		return nil, false
+	}
+	return l.matches[seq-1], true
+}
+
+// Close closes the store
+func (l *Ledger) Close() error {
+	return l.config.Store.Close()
+}
+
+// add indexes the match, the ratings after it become the current ones
+func (l *Ledger) add(m *Match) {
+	l.matches = append(l.matches, m)
+	for _, pid := range m.Players {
+		l.history[pid] = append(l.history[pid], m)
+	}
+	for pid, change := range m.Ratings {
+		l.ratings[pid] = change.After
+	}
+}
+
+func (l *Ledger) rating(pid uint64) Rating {
+	if r, ok := l.ratings[pid]; ok {
+		return r
+	}
+	return l.config.Rating.Initial()
+}
+
+// teams returns the team of every player
+func (l *Ledger) teams(result *room.Result) map[uint64]int32 {
+	if l.config.Teams != nil {
+		return l.config.Teams(result)
+	}
+	teams := make(map[uint64]int32, len(result.Players))
+	for i, pid := range result.Players {
+		teams[pid] = int32(i + 1)
+	}
+	return teams
+}
+
+// winnerOf returns the winner decided by the simulation of the room if any,
+// otherwise the player reported as the winner by the most players, 0 if there is a tie or nobody reports
+func winnerOf(result *room.Result) uint64 {
+	if result.Winner != 0 {
+		return result.Winner
+	}
+	votes := make(map[uint64]int)
+	for _, winner := range result.Winners {
+		for _, pid := range result.Players {
+			if pid == winner {
+				votes[winner]++
+				break
+			}
+		}
+	}
+	candidates := make([]uint64, 0, len(votes))
+	for winner := range votes {
+		candidates = append(candidates, winner)
+	}
+	sort.Slice(candidates, func(i, j int) bool { return votes[candidates[i]] > votes[candidates[j]] })
+	if len(candidates) == 0 || (len(candidates) > 1 && votes[candidates[0]] == votes[candidates[1]]) {
+		return 0
+	}
+	return candidates[0]
+}
@@ -0,0 +1,167 @@
+package ledger
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/hedon954/go-lock-step-server/logic/game"
+	"github.com/hedon954/go-lock-step-server/logic/room"
+)
+
+func finished(rid uint64, winners map[uint64]uint64, players ...uint64) *room.Result {
+	return &room.Result{
+		RoomID:     rid,
+		Players:    players,
+		Reason:     room.ReasonFinished,
+		Winners:    winners,
+		Frames:     300,
+		CreateTime: 1700000000,
+		EndTime:    1700000012,
+	}
+}
+
+func Test_Ledger(t *testing.T) {
+	l, err := New(&Config{
+		Replay: func(result *room.Result) string { return fmt.Sprintf("replay/%d", result.RoomID) },
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	l.ReportResult(finished(1, map[uint64]uint64{1: 1, 2: 1}, 1, 2))
+	l.ReportResult(finished(2, map[uint64]uint64{1: 1, 2: 2}, 1, 2))
+	l.ReportResult(&room.Result{RoomID: 3, Players: []uint64{1, 2}, Reason: room.ReasonNoShow})
+	l.ReportResult(&room.Result{RoomID: 4, Players: []uint64{1, 3}, Reason: room.ReasonTimeout,
+		Violations: map[uint64]game.PlayerViolations{3: {Count: 5, Kicked: true}, 1: {Count: 1}}})
+
+	history := l.History(1, 0)
+	if len(history) != 3 || history[0].RoomID != 4 || history[2].RoomID != 1 {
+		t.Fatalf("history of player 1: %v", history)
+	}
+	m := history[2]
+	if m.Seq != 1 || m.Winner != 1 || m.Frames != 300 || m.Duration != 300*room.TickTimer || m.Replay != "replay/1" ||
+		m.Teams[1] == m.Teams[2] {
+		t.Errorf("match got: %+v", m)
+	}
+	if m.Ratings[1].Before.Value != DefaultRating || m.Ratings[1].After.Value != 1516 {
+		t.Errorf("ratings got: %+v", m.Ratings)
+	}
+
+	// a tie of the reports is a draw, and the timeout is not rated
+	if history[1].Winner != 0 || history[1].Ratings == nil || history[0].Ratings != nil {
+		t.Errorf("history got: %+v %+v", history[1], history[0])
+	}
+	if v := history[0].Violations; len(v) != 2 || v[3] != 5 || len(history[0].Kicked) != 1 ||
+		history[0].Kicked[0] != 3 {
+		t.Errorf("violations got: %v %v", v, history[0].Kicked)
+	}
+	if r := l.Rating(1); r.Games != 2 || r.Value <= DefaultRating {
+		t.Errorf("rating of player 1: %+v", r)
+	}
+	if r := l.Rating(3); r.Games != 0 || r.Value != DefaultRating {
+		t.Errorf("rating of player 3: %+v", r)
+	}
+	if len(l.History(1, 1)) != 1 || len(l.History(3, 0)) != 1 {
+		t.Error("history limit")
+	}
+	if m, ok := l.Match(3); !ok || m.RoomID != 4 {
+		t.Errorf("match 3 got: %+v", m)
+	}
+
+	s := httptest.NewServer(l.Handler())
+	defer s.Close()
+	resp, err := http.Get(s.URL + "/history?player=2&limit=1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	ret := &PlayerJSON{}
+	if err = json.NewDecoder(resp.Body).Decode(ret); err != nil {
+		t.Fatal(err)
+	}
+	if ret.Player != 2 || ret.Rating != l.Rating(2) || len(ret.History) != 1 || ret.History[0].RoomID != 2 {
+		t.Errorf("http got: %+v", ret)
+	}
+}
+
+func Test_LedgerSimulatedWinner(t *testing.T) {
+	l, err := New(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the winner decided by the simulation settles the reports
+	result := finished(1, map[uint64]uint64{1: 1, 2: 1}, 1, 2)
+	result.Winner = 2
+	result.Desyncs = map[uint64]int{1: 2}
+	l.ReportResult(result)
+
+	m, ok := l.Match(1)
+	if !ok || m.Winner != 2 || m.Desyncs[1] != 2 {
+		t.Fatalf("match got: %+v", m)
+	}
+	if l.Rating(2).Value <= DefaultRating || l.Rating(1).Value >= DefaultRating {
+		t.Errorf("ratings got: %+v %+v", l.Rating(1), l.Rating(2))
+	}
+}
+
+func Test_LedgerTeams(t *testing.T) {
+	l, _ := New(&Config{
+		Rating: &Glicko{},
+		Teams: func(result *room.Result) map[uint64]int32 {
+			teams := make(map[uint64]int32)
+			for i, pid := range result.Players {
+				teams[pid] = int32(i % 2)
+			}
+			return teams
+		},
+	})
+	l.ReportResult(finished(1, map[uint64]uint64{1: 3, 2: 3, 3: 3}, 1, 2, 3, 4))
+	if l.Rating(1) != l.Rating(3) || l.Rating(2) != l.Rating(4) || l.Rating(1).Value <= l.Rating(2).Value {
+		t.Errorf("the team of player 3 should win: %+v %+v", l.Rating(1), l.Rating(2))
+	}
+}
+
+func Test_FileStore(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "ledger.jsonl")
+	store, err := OpenFileStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	l, _ := New(&Config{Store: store})
+	l.ReportResult(finished(1, map[uint64]uint64{1: 2}, 1, 2))
+	l.ReportResult(finished(2, map[uint64]uint64{1: 2}, 1, 2))
+	rating := l.Rating(2)
+	l.Close()
+
+	// a crash leaves a partial line
+	f, _ := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0644)
+	f.WriteString(`{"seq":3,"room":`)
+	f.Close()
+
+	store, err = OpenFileStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if l, err = New(&Config{Store: store}); err != nil {
+		t.Fatal(err)
+	}
+	if l.Rating(2) != rating || len(l.History(1, 0)) != 2 {
+		t.Fatalf("the ledger should be restored, rating: %+v", l.Rating(2))
+	}
+	l.ReportResult(finished(3, map[uint64]uint64{1: 1}, 1, 2))
+	l.Close()
+
+	store, _ = OpenFileStore(path)
+	if l, err = New(&Config{Store: store}); err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	if m, ok := l.Match(3); !ok || m.RoomID != 3 {
+		t.Errorf("match 3 got: %+v", m)
+	}
+}
@@ -0,0 +1,145 @@
+package ledger
+
+import (
+	"math"
+)
+
+const (
+	DefaultEloK        = 32
+	DefaultRating      = 1500
+	DefaultGlickoRD    = 350
+	DefaultGlickoMinRD = 30
+
+	kGlickoQ   = math.Ln10 / 400
+	kScoreWin  = 1.0
+	kScoreDraw = 0.5
+	kScoreLoss = 0.0
+)
+
+// Rating is the rating of a player
+type Rating struct {
+	Value     float64 `json:"value"`
+	Deviation float64 `json:"deviation,omitempty"` // the rating deviation of Glicko
+	Games     int     `json:"games"`               // the number of the rated matches
+}
+
+// RatingSystem updates the ratings of the players of a match
+type RatingSystem interface {
+	// Initial returns the rating of a new player
+	Initial() Rating
+
+	// Update returns the ratings after the match, the players of the winner's team win against the others,
+	// a winner of 0 means a draw
+	Update(ratings map[uint64]Rating, teams map[uint64]int32, winner uint64) map[uint64]Rating
+}
+
+// opponent is a result of a player against a player of another team
+type opponent struct {
+	rating Rating
+	score  float64
+}
+
+// opponentsOf returns the results of every player against the players of the other teams
+func opponentsOf(ratings map[uint64]Rating, teams map[uint64]int32, winner uint64) map[uint64][]opponent {
+	winningTeam, hasWinner := teams[winner]
+	ret := make(map[uint64][]opponent, len(ratings))
+	for pid := range ratings {
+		for other, rating := range ratings {
+			if teams[other] == teams[pid] {
+				continue
+			}
+			score := kScoreDraw
+			if hasWinner && winner != 0 {
+				if teams[pid] == winningTeam {
+					score = kScoreWin
+				} else if teams[other] == winningTeam {
+					score = kScoreLoss
+				}
+			}
+			ret[pid] = append(ret[pid], opponent{rating: rating, score: score})
+		}
+	}
+	return ret
+}
+
+// Elo rates by the average result against the opponents
+type Elo struct {
+	K float64 // the maximum change of a match, 0 means DefaultEloK
+}
+
+func (e *Elo) Initial() Rating {
+	return Rating{Value: DefaultRating}
+}
+
+func (e *Elo) Update(ratings map[uint64]Rating, teams map[uint64]int32, winner uint64) map[uint64]Rating {
+	k := e.K
+	if k <= 0 {
+		k = DefaultEloK
+	}
+	ret := make(map[uint64]Rating, len(ratings))
+	for pid, opponents := range opponentsOf(ratings, teams, winner) {
+		r := ratings[pid]
+		var delta float64
+		for _, o := range opponents {
+			expected := 1 / (1 + math.Pow(10, (o.rating.Value-r.Value)/400))
+			delta += o.score - expected
+		}
+		if len(opponents) > 0 {
+			r.Value += k * delta / float64(len(opponents))
+			r.Games++
+		}
+		ret[pid] = r
+	}
+	return ret
+}
+
+// Glicko rates by Glicko-1, every match is a rating period
+type Glicko struct {
+	// C grows the deviation before every match, 0 means the deviation only shrinks
+	C float64
+}
+
+func (g *Glicko) Initial() Rating {
+	return Rating{Value: DefaultRating, Deviation: DefaultGlickoRD}
+}
+
+func (g *Glicko) Update(ratings map[uint64]Rating, teams map[uint64]int32, winner uint64) map[uint64]Rating {
+	ret := make(map[uint64]Rating, len(ratings))
+	for pid, opponents := range opponentsOf(ratings, teams, winner) {
+		ret[pid] = g.rate(ratings[pid], opponents)
+	}
+	return ret
+}
+
+// rate returns the rating after the results against the opponents in a rating period
+func (g *Glicko) rate(r Rating, opponents []opponent) Rating {
+	if len(opponents) == 0 {
+		return r
+	}
+	rd := g.deviation(r)
+
+	var sum, dInv float64
+	for _, o := range opponents {
+		gj := glickoG(g.deviation(o.rating))
+		expected := 1 / (1 + math.Pow(10, -gj*(r.Value-o.rating.Value)/400))
+		sum += gj * (o.score - expected)
+		dInv += kGlickoQ * kGlickoQ * gj * gj * expected * (1 - expected)
+	}
+	denominator := 1/(rd*rd) + dInv
+	r.Value += kGlickoQ / denominator * sum
+	r.Deviation = math.Max(math.Sqrt(1/denominator), DefaultGlickoMinRD)
+	r.Games++
+	return r
+}
+
+// deviation returns the deviation of the rating at the start of the match
+func (g *Glicko) deviation(r Rating) float64 {
+	if r.Deviation <= 0 {
+		return DefaultGlickoRD
+	}
+	return math.Min(math.Sqrt(r.Deviation*r.Deviation+g.C*g.C), DefaultGlickoRD)
+}
+
+func glickoG(rd float64) float64 {
+	return 1 / math.Sqrt(1+3*kGlickoQ*kGlickoQ*rd*rd/(math.Pi*math.Pi))
+}
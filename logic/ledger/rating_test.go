@@ -0,0 +1,47 @@
+package ledger
+
+import (
+	"math"
+	"testing"
+)
+
+func Test_Elo(t *testing.T) {
+	elo := &Elo{}
+	ratings := map[uint64]Rating{1: elo.Initial(), 2: elo.Initial()}
+	teams := map[uint64]int32{1: 1, 2: 2}
+
+	after := elo.Update(ratings, teams, 1)
+	if after[1].Value != 1516 || after[2].Value != 1484 || after[1].Games != 1 {
+		t.Errorf("win got: %+v", after)
+	}
+	after = elo.Update(after, teams, 0)
+	if after[1].Value >= 1516 || after[2].Value <= 1484 || after[1].Value+after[2].Value != 3000 {
+		t.Errorf("draw got: %+v", after)
+	}
+
+	// the teammates are not opponents
+	ratings[3], ratings[4] = elo.Initial(), elo.Initial()
+	teams[3], teams[4] = 1, 2
+	after = elo.Update(ratings, teams, 3)
+	if after[1].Value != 1516 || after[3].Value != 1516 || after[4].Value != 1484 {
+		t.Errorf("team win got: %+v", after)
+	}
+}
+
+func Test_Glicko(t *testing.T) {
+	// the example of Glickman's paper of Glicko
+	g := &Glicko{}
+	r := g.rate(Rating{Value: 1500, Deviation: 200}, []opponent{
+		{rating: Rating{Value: 1400, Deviation: 30}, score: kScoreWin},
+		{rating: Rating{Value: 1550, Deviation: 100}, score: kScoreLoss},
+		{rating: Rating{Value: 1700, Deviation: 300}, score: kScoreLoss},
+	})
+	if math.Abs(r.Value-1464.1) > 0.1 || math.Abs(r.Deviation-151.4) > 0.1 {
+		t.Errorf("got: %+v", r)
+	}
+
+	after := g.Update(map[uint64]Rating{1: g.Initial(), 2: g.Initial()}, map[uint64]int32{1: 1, 2: 2}, 2)
+	if after[2].Value <= DefaultRating || after[1].Value >= DefaultRating || after[1].Deviation >= DefaultGlickoRD {
+		t.Errorf("got: %+v", after)
+	}
+}
@@ -0,0 +1,112 @@
+package ledger
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+
+	"github.com/alecthomas/log4go"
+)
+
+// Store keeps the matches recorded by the ledger
+type Store interface {
+	// Append stores the match after the ones stored
+	Append(m *Match) error
+
+	// Load returns the matches stored in order
+	Load() ([]*Match, error)
+
+	Close() error
+}
+
+// MemoryStore is a Store kept in memory
+type MemoryStore struct {
+	mu      sync.Mutex
+	matches []*Match
+}
+
+func (s *MemoryStore) Append(m *Match) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.matches = append(s.matches, m)
+	return nil
+}
+
+func (s *MemoryStore) Load() ([]*Match, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]*Match(nil), s.matches...), nil
+}
+
+func (s *MemoryStore) Close() error {
+	return nil
+}
+
+// FileStore is a Store appending a json line of every match to a file,
+// it is used by a single process
+type FileStore struct {
+	path string
+	mu   sync.Mutex
+	f    *os.File
+}
+
+// OpenFileStore opens the file of path to append the matches, the file is created if it does not exist
+func OpenFileStore(path string) (*FileStore, error) {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_RDWR, 0644)
+	if err != nil {
+		return nil, err
+	}
+	return &FileStore{path: path, f: f}, nil
+}
+
+func (s *FileStore) Append(m *Match) error {
+	data, err := json.Marshal(m)
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, err = s.f.Write(append(data, '\n'))
+	return err
+}
+
+// Load reads all the matches of the file, a partial line at the end left by a crash is truncated
+func (s *FileStore) Load() ([]*Match, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	data, err := os.ReadFile(s.path)
+	if err != nil {
+		return nil, err
+	}
+
+	var ret []*Match
+	lines := bytes.Split(data, []byte("\n"))
+	for i, line := range lines {
+		if len(line) == 0 {
+			continue
+		}
+		m := &Match{}
+		if err = json.Unmarshal(line, m); err != nil {
+			if i != len(lines)-1 {
+				return nil, fmt.Errorf("ledger[%s] line %d is broken: %w", s.path, i+1, err)
+			}
+			// the last line without a line break is partial
+			log4go.Warn("[ledger] truncate the partial match at the end of [%s]", s.path)
+			if err = s.f.Truncate(int64(len(data) - len(line))); err != nil {
+				return nil, err
+			}
+			break
+		}
+		ret = append(ret, m)
+	}
+	return ret, nil
+}
+
+func (s *FileStore) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.f.Close()
+}
@@ -1,47 +1,113 @@
 package logic
 
 import (
+	"errors"
 	"fmt"
 	"sync"
 
+	"github.com/alecthomas/log4go"
 	"github.com/hedon954/go-lock-step-server/logic/room"
 )
 
+var (
+	ErrManagerStopped = errors.New("room manager is stopped")
+)
+
+// Config is the configuration of the room manager
+type Config struct {
+	MaxRooms   int // the maximum number of concurrent rooms, 0 means unlimited
+	MaxPlayers int // the maximum number of players in all rooms, 0 means unlimited
+
+	// Room is the configuration of every room created by the room manager
+	Room room.Config
+
+	// Directory records the rooms owned by every node of a cluster, it is nil for a single node
+	Directory RoomDirectory
+
+	// NodeAddress is the address the players dial to reach this node, it is recorded in Directory
+	NodeAddress string
+}
+
+// CapacityError is returned when creating a room would exceed the capacity of the room manager
+type CapacityError struct {
+	Resource string // the exhausted resource, "rooms" or "players"
+	Limit    int    // the configured limit
+	Current  int    // the current usage
+}
+
+func (e *CapacityError) Error() string {
+	return fmt.Sprintf("room manager is full: %s %d/%d", e.Resource, e.Current, e.Limit)
+}
+
 // RoomManager is used to manage game rooms
 type RoomManager struct {
-	rooms map[uint64]*room.Room
-	wg    sync.WaitGroup
-	rw    sync.RWMutex
+	config  Config
+	rooms   map[uint64]*room.Room
+	pending map[uint64]struct{} // the rooms reserved while they are registered in the directory
+	players int
+	stopped bool
+	wg      sync.WaitGroup
+	rw      sync.RWMutex
 }
 
 // NewRoomManager creates a new room manager
-func NewRoomManager() *RoomManager {
-	return &RoomManager{
-		rooms: make(map[uint64]*room.Room),
+func NewRoomManager(config *Config) *RoomManager {
+	rm := &RoomManager{
+		rooms:   make(map[uint64]*room.Room),
+		pending: make(map[uint64]struct{}),
+	}
+	if config != nil {
+		rm.config = *config
 	}
+	return rm
 }
 
 // CreateRoom creates a game room
 func (rm *RoomManager) CreateRoom(
 	rid uint64, typeID int32, pid []uint64, randomSeed int32, logicServer string,
 ) (*room.Room, error) {
-	rm.rw.Lock()
-	defer rm.rw.Unlock()
+	if err := rm.reserve(rid, len(pid)); err != nil {
+		return nil, err
+	}
 
-	r, ok := rm.rooms[rid]
-	if ok {
-		return nil, fmt.Errorf("room id[%d] exists", rid)
+	// the directory may do i/o, so the room is registered without the lock
+	if rm.config.Directory != nil {
+		if err := rm.config.Directory.Register(rid, rm.config.NodeAddress); err != nil {
+			rm.release(rid, len(pid))
+			return nil, err
+		}
 	}
 
-	r = room.NewRoom(rid, typeID, pid, randomSeed, logicServer)
+	rm.rw.Lock()
+	if rm.stopped {
+		rm.rw.Unlock()
+		if rm.config.Directory != nil {
+			if err := rm.config.Directory.Unregister(rid, rm.config.NodeAddress); err != nil {
+				log4go.Error("[RoomManager] unregister room[%d] error: %v", rid, err)
+			}
+		}
+		rm.release(rid, len(pid))
+		return nil, ErrManagerStopped
+	}
+	delete(rm.pending, rid)
+	r := room.NewRoom(rid, typeID, pid, randomSeed, logicServer, &rm.config.Room)
 	rm.rooms[rid] = r
+	rm.rw.Unlock()
 
 	go func() {
-		rm.wg.Add(1)
 		defer func() {
 			defer rm.wg.Done()
+			if rm.config.Directory != nil {
+				if err := rm.config.Directory.Unregister(rid, rm.config.NodeAddress); err != nil {
+					log4go.Error("[RoomManager] unregister room[%d] error: %v", rid, err)
+				}
+			}
 			rm.rw.Lock()
-			delete(rm.rooms, rid)
+			if rm.rooms[rid] == r {
+				delete(rm.rooms, rid)
+			}
+			// the seats may be added since the room was created
+			rm.players -= len(r.Players())
 			rm.rw.Unlock()
 		}()
 		r.Run()
@@ -50,6 +116,82 @@ func (rm *RoomManager) CreateRoom(
 	return r, nil
 }
 
+// reserve counts the room and its players before it is registered in the directory,
+// so that a concurrent creation of the same room or beyond the capacity is rejected.
+// Stop waits for the room reserved until it is created or released
+func (rm *RoomManager) reserve(rid uint64, players int) error {
+	rm.rw.Lock()
+	defer rm.rw.Unlock()
+
+	if rm.stopped {
+		return ErrManagerStopped
+	}
+
+	if _, ok := rm.rooms[rid]; ok {
+		return fmt.Errorf("room id[%d] exists", rid)
+	}
+	if _, ok := rm.pending[rid]; ok {
+		return fmt.Errorf("room id[%d] exists", rid)
+	}
+
+	rooms := len(rm.rooms) + len(rm.pending)
+	if rm.config.MaxRooms > 0 && rooms >= rm.config.MaxRooms {
+		return &CapacityError{Resource: "rooms", Limit: rm.config.MaxRooms, Current: rooms}
+	}
+	if rm.config.MaxPlayers > 0 && rm.players+players > rm.config.MaxPlayers {
+		return &CapacityError{Resource: "players", Limit: rm.config.MaxPlayers, Current: rm.players}
+	}
+
+	rm.pending[rid] = struct{}{}
+	rm.players += players
+	rm.wg.Add(1)
+	return nil
+}
+
+// release gives back the reservation of the room failing to be created
+func (rm *RoomManager) release(rid uint64, players int) {
+	rm.rw.Lock()
+	delete(rm.pending, rid)
+	rm.players -= players
+	rm.rw.Unlock()
+	rm.wg.Done()
+}
+
+// AssignSeat assigns the seat of the room to the player while the game is ready or gaming,
+// the seat next to the last one is added, and the player offline on the seat is replaced.
+// It returns the id of the player replaced, or 0 if the seat is added
+func (rm *RoomManager) AssignSeat(rid uint64, seat int32, pid uint64) (uint64, error) {
+	r := rm.GetRoom(rid)
+	if r == nil {
+		return 0, fmt.Errorf("room id[%d] not exists", rid)
+	}
+
+	// the seat added is counted before the room assigns it, so that the concurrent assignments never
+	// exceed MaxPlayers, and the room removes all its players from the count once it quits
+	rm.rw.Lock()
+	reserved := int(seat) > len(r.Players())
+	if reserved {
+		if rm.config.MaxPlayers > 0 && rm.players >= rm.config.MaxPlayers {
+			rm.rw.Unlock()
+			return 0, &CapacityError{Resource: "players", Limit: rm.config.MaxPlayers, Current: rm.players}
+		}
+		rm.players++
+	}
+	rm.rw.Unlock()
+
+	replaced, err := r.AssignSeat(seat, pid)
+	if reserved && (err != nil || replaced != 0) {
+		// the seat is not added
+		rm.rw.Lock()
+		rm.players--
+		rm.rw.Unlock()
+	}
+	if err != nil {
+		return 0, err
+	}
+	return replaced, nil
+}
+
 // GetRoom gets the specific room
 func (rm *RoomManager) GetRoom(id uint64) *room.Room {
 	rm.rw.RLock()
@@ -59,6 +201,44 @@ func (rm *RoomManager) GetRoom(id uint64) *room.Room {
 	return r
 }
 
+// RoomOwner returns the address of the node owning the room if it is not this node
+func (rm *RoomManager) RoomOwner(id uint64) (string, bool) {
+	if rm.config.Directory == nil {
+		return "", false
+	}
+	node, ok, err := rm.config.Directory.Lookup(id)
+	if err != nil {
+		log4go.Error("[RoomManager] lookup room[%d] error: %v", id, err)
+		return "", false
+	}
+	if !ok || node == rm.config.NodeAddress {
+		return "", false
+	}
+	return node, true
+}
+
+// QueueStats returns the statistics of the message queue of every room
+func (rm *RoomManager) QueueStats() map[uint64]room.QueueStats {
+	rm.rw.RLock()
+	defer rm.rw.RUnlock()
+
+	ret := make(map[uint64]room.QueueStats, len(rm.rooms))
+	for rid, r := range rm.rooms {
+		ret[rid] = r.QueueStats()
+	}
+	return ret
+}
+
+// CancelRoom cancels the room which has not been started yet,
+// the room is removed once it quits and its result is reported with room.ReasonCancelled
+func (rm *RoomManager) CancelRoom(id uint64) error {
+	r := rm.GetRoom(id)
+	if r == nil {
+		return fmt.Errorf("room id[%d] not exists", id)
+	}
+	return r.Cancel()
+}
+
 // RoomNum gets the count of the room
 func (rm *RoomManager) RoomNum() int {
 	rm.rw.RLock()
@@ -67,14 +247,28 @@ func (rm *RoomManager) RoomNum() int {
 	return len(rm.rooms)
 }
 
-// Stop stops the room
+// PlayerNum gets the count of the players in all rooms
+func (rm *RoomManager) PlayerNum() int {
+	rm.rw.RLock()
+	defer rm.rw.RUnlock()
+
+	return rm.players
+}
+
+// Stop stops all the rooms and waits for them to quit,
+// no room can be created after the room manager is stopped
 func (rm *RoomManager) Stop() {
 	rm.rw.Lock()
+	rm.stopped = true
+	rooms := make([]*room.Room, 0, len(rm.rooms))
 	for _, r := range rm.rooms {
-		r.Stop()
+		rooms = append(rooms, r)
 	}
-	rm.rooms = make(map[uint64]*room.Room)
 	rm.rw.Unlock()
 
+	for _, r := range rooms {
+		r.Stop()
+	}
+
 	rm.wg.Wait()
 }
@@ -0,0 +1,243 @@
+package logic
+
+import (
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/hedon954/go-lock-step-server/logic/room"
+)
+
+func Test_RoomManagerRoomCapacity(t *testing.T) {
+	rm := NewRoomManager(&Config{MaxRooms: 10})
+	defer rm.Stop()
+
+	var ok, full int32
+	wg := sync.WaitGroup{}
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func(rid uint64) {
+			defer wg.Done()
+			_, err := rm.CreateRoom(rid, 0, []uint64{rid}, 0, "test")
+			var capErr *CapacityError
+			switch {
+			case err == nil:
+				atomic.AddInt32(&ok, 1)
+			case errors.As(err, &capErr) && capErr.Resource == "rooms":
+				atomic.AddInt32(&full, 1)
+			default:
+				t.Errorf("unexpected error: %v", err)
+			}
+		}(uint64(i + 1))
+	}
+	wg.Wait()
+
+	if ok != 10 || full != 10 {
+		t.Errorf("ok[%d] full[%d] should be [10] [10]", ok, full)
+	}
+	if n := rm.RoomNum(); n != 10 {
+		t.Errorf("RoomNum[%d] should be [10]", n)
+	}
+}
+
+func Test_RoomManagerPlayerCapacity(t *testing.T) {
+	rm := NewRoomManager(&Config{MaxPlayers: 3})
+	defer rm.Stop()
+
+	if _, err := rm.CreateRoom(1, 0, []uint64{1, 2}, 0, "test"); err != nil {
+		t.Fatal(err)
+	}
+
+	_, err := rm.CreateRoom(2, 0, []uint64{3, 4}, 0, "test")
+	var capErr *CapacityError
+	if !errors.As(err, &capErr) || capErr.Resource != "players" {
+		t.Fatalf("err[%v] should be a players CapacityError", err)
+	}
+	if capErr.Limit != 3 || capErr.Current != 2 {
+		t.Errorf("want: limit[3] current[2], got: limit[%d] current[%d]", capErr.Limit, capErr.Current)
+	}
+
+	if _, err := rm.CreateRoom(3, 0, []uint64{5}, 0, "test"); err != nil {
+		t.Errorf("room with one player should be created: %v", err)
+	}
+	if n := rm.PlayerNum(); n != 3 {
+		t.Errorf("PlayerNum[%d] should be [3]", n)
+	}
+}
+
+func Test_RoomManagerDuplicateRoom(t *testing.T) {
+	rm := NewRoomManager(nil)
+	defer rm.Stop()
+
+	if _, err := rm.CreateRoom(1, 0, []uint64{1}, 0, "test"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := rm.CreateRoom(1, 0, []uint64{2}, 0, "test"); err == nil {
+		t.Error("creating a duplicate room should fail")
+	}
+}
+
+func Test_RoomManagerConcurrentCreateAndStop(t *testing.T) {
+	for round := 0; round < 10; round++ {
+		rm := NewRoomManager(&Config{MaxRooms: 64})
+
+		wg := sync.WaitGroup{}
+		for i := 0; i < 100; i++ {
+			wg.Add(1)
+			go func(rid uint64) {
+				defer wg.Done()
+				_, err := rm.CreateRoom(rid, 0, []uint64{rid, rid + 1000}, 0, "test")
+				var capErr *CapacityError
+				if err != nil && !errors.Is(err, ErrManagerStopped) && !errors.As(err, &capErr) {
+					t.Errorf("unexpected error: %v", err)
+				}
+			}(uint64(i + 1))
+		}
+
+		rm.Stop()
+		wg.Wait()
+
+		// every room has quit and released its capacity once Stop returns
+		if n := rm.RoomNum(); n != 0 {
+			t.Errorf("round[%d] RoomNum[%d] should be [0]", round, n)
+		}
+		if n := rm.PlayerNum(); n != 0 {
+			t.Errorf("round[%d] PlayerNum[%d] should be [0]", round, n)
+		}
+		if _, err := rm.CreateRoom(10000, 0, nil, 0, "test"); !errors.Is(err, ErrManagerStopped) {
+			t.Errorf("round[%d] err[%v] should be [%v]", round, err, ErrManagerStopped)
+		}
+	}
+}
+
+// resultCollector collects the reported results of the rooms
+type resultCollector chan *room.Result
+
+func (c resultCollector) ReportResult(result *room.Result) {
+	c <- result
+}
+
+func (c resultCollector) wait(t *testing.T, rid uint64, reason room.Reason) {
+	select {
+	case result := <-c:
+		if result.RoomID != rid || result.Reason != reason {
+			t.Errorf("want: room[%d] reason[%s], got: room[%d] reason[%s]", rid, reason, result.RoomID,
+				result.Reason)
+		}
+	case <-time.After(3 * time.Second):
+		t.Errorf("room[%d] has not reported its result", rid)
+	}
+}
+
+// waitRoomNum waits for the rooms to be removed from the room manager
+func waitRoomNum(t *testing.T, rm *RoomManager, n int) {
+	deadline := time.Now().Add(time.Second)
+	for rm.RoomNum() != n && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond * 10)
+	}
+	if num := rm.RoomNum(); num != n {
+		t.Errorf("RoomNum[%d] should be [%d]", num, n)
+	}
+}
+
+func Test_RoomManagerReapIdleRoom(t *testing.T) {
+	results := make(resultCollector, 1)
+	rm := NewRoomManager(&Config{
+		Room: room.Config{
+			IdleTimeout: time.Millisecond * 100,
+			Reporter:    results,
+		},
+	})
+	defer rm.Stop()
+
+	if _, err := rm.CreateRoom(1, 0, []uint64{1, 2}, 0, "test"); err != nil {
+		t.Fatal(err)
+	}
+	results.wait(t, 1, room.ReasonNoShow)
+	waitRoomNum(t, rm, 0)
+
+	// the room id is released
+	if _, err := rm.CreateRoom(1, 0, []uint64{1, 2}, 0, "test"); err != nil {
+		t.Errorf("room id should be reusable after reaping: %v", err)
+	}
+}
+
+func Test_RoomManagerCancelRoom(t *testing.T) {
+	results := make(resultCollector, 1)
+	rm := NewRoomManager(&Config{
+		Room: room.Config{
+			Reporter: results,
+		},
+	})
+	defer rm.Stop()
+
+	if err := rm.CancelRoom(1); err == nil {
+		t.Error("cancelling an unknown room should fail")
+	}
+
+	if _, err := rm.CreateRoom(1, 0, []uint64{1, 2}, 0, "test"); err != nil {
+		t.Fatal(err)
+	}
+	if err := rm.CancelRoom(1); err != nil {
+		t.Fatal(err)
+	}
+	results.wait(t, 1, room.ReasonCancelled)
+	waitRoomNum(t, rm, 0)
+}
+
+func Test_RoomManagerStopReportsResult(t *testing.T) {
+	results := make(resultCollector, 1)
+	rm := NewRoomManager(&Config{
+		Room: room.Config{
+			Reporter: results,
+		},
+	})
+
+	if _, err := rm.CreateRoom(1, 0, []uint64{1, 2}, 0, "test"); err != nil {
+		t.Fatal(err)
+	}
+	rm.Stop()
+	results.wait(t, 1, room.ReasonStopped)
+}
+
+func Test_RoomManagerAssignSeat(t *testing.T) {
+	rm := NewRoomManager(&Config{MaxPlayers: 3})
+
+	if _, err := rm.CreateRoom(1, 0, []uint64{1, 2}, 0, "test"); err != nil {
+		t.Fatal(err)
+	}
+	if replaced, err := rm.AssignSeat(1, 2, 3); err != nil || replaced != 2 {
+		t.Fatalf("replace got: (%d, %v)", replaced, err)
+	}
+	// the count reserved for the seat is given back if it is not added
+	if _, err := rm.AssignSeat(1, 3, 1); err == nil {
+		t.Fatal("assigning a seat to a player in the room should fail")
+	}
+	if n := rm.PlayerNum(); n != 2 {
+		t.Errorf("PlayerNum[%d] should be [2]", n)
+	}
+	if replaced, err := rm.AssignSeat(1, 3, 4); err != nil || replaced != 0 {
+		t.Fatalf("add got: (%d, %v)", replaced, err)
+	}
+	if n := rm.PlayerNum(); n != 3 {
+		t.Errorf("PlayerNum[%d] should be [3]", n)
+	}
+	if r := rm.GetRoom(1); !r.HasPlayer(3) || !r.HasPlayer(4) || r.HasPlayer(2) {
+		t.Errorf("players of the room: %v", r.Players())
+	}
+
+	var capErr *CapacityError
+	if _, err := rm.AssignSeat(1, 4, 5); !errors.As(err, &capErr) {
+		t.Errorf("err[%v] should be a players CapacityError", err)
+	}
+	if _, err := rm.AssignSeat(2, 1, 5); err == nil {
+		t.Error("assigning a seat of a room not exists should fail")
+	}
+
+	rm.Stop()
+	if n := rm.PlayerNum(); n != 0 {
+		t.Errorf("PlayerNum[%d] should be [0] after the rooms quit", n)
+	}
+}
@@ -0,0 +1,160 @@
+package matchmaking
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// StatusJSON is the status of a player replied by the http api
+type StatusJSON struct {
+	State   string   `json:"state"`
+	Type    int32    `json:"type,omitempty"`
+	Rating  int32    `json:"rating,omitempty"`
+	Waited  int64    `json:"waited_ms,omitempty"`
+	Room    uint64   `json:"room,omitempty"`
+	Token   string   `json:"token,omitempty"`
+	Players []uint64 `json:"players,omitempty"`
+	Error   string   `json:"error,omitempty"`
+}
+
+// Handler returns the http api of the matcher:
+//
+//	/enqueue?player=1&key=K&rating=1500&type=0  enqueues the player
+//	/poll?player=1&key=K                        returns the status of the player, the room and the token once matched
+//	/cancel?player=1&key=K                      removes the player waiting
+//
+// the player is proven by Config.Authenticate, the player and key parameters are the default proof.
+// Every api replies StatusJSON
+func (m *Matcher) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/enqueue", m.handleEnqueue)
+	mux.HandleFunc("/poll", m.handlePoll)
+	mux.HandleFunc("/cancel", m.handleCancel)
+	return mux
+}
+
+// PlayerKey returns the key proving the player to the http api, it is issued to the player by the login
+func PlayerKey(secret string, pid uint64) string {
+	buff := make([]byte, 8)
+	binary.BigEndian.PutUint64(buff, pid)
+	h := hmac.New(sha256.New, []byte(secret))
+	h.Write([]byte("matchmaking"))
+	h.Write(buff)
+	return base64.RawURLEncoding.EncodeToString(h.Sum(nil)[:16])
+}
+
+// authenticate returns the player proven by the request
+func (m *Matcher) authenticate(r *http.Request) (uint64, error) {
+	if m.config.Authenticate != nil {
+		return m.config.Authenticate(r)
+	}
+	query := r.URL.Query()
+	pid, err := strconv.ParseUint(query.Get("player"), 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("bad player: %w", err)
+	}
+	if m.config.Secret == "" || !hmac.Equal([]byte(query.Get("key")), []byte(PlayerKey(m.config.Secret, pid))) {
+		return 0, ErrUnauthorized
+	}
+	return pid, nil
+}
+
+// player returns the player of the request, it replies the error if the player is not proven
+func (m *Matcher) player(w http.ResponseWriter, r *http.Request) (uint64, bool) {
+	pid, err := m.authenticate(r)
+	if err != nil {
+		replyError(w, http.StatusUnauthorized, err)
+		return 0, false
+	}
+	return pid, true
+}
+
+func (m *Matcher) handleEnqueue(w http.ResponseWriter, r *http.Request) {
+	pid, ok := m.player(w, r)
+	if !ok {
+		return
+	}
+	query := r.URL.Query()
+	rating, err := strconv.ParseInt(query.Get("rating"), 10, 32)
+	if err != nil {
+		replyError(w, http.StatusBadRequest, fmt.Errorf("bad rating: %w", err))
+		return
+	}
+	var typeID int64
+	if s := query.Get("type"); s != "" {
+		if typeID, err = strconv.ParseInt(s, 10, 32); err != nil {
+			replyError(w, http.StatusBadRequest, fmt.Errorf("bad type: %w", err))
+			return
+		}
+	}
+
+	if err = m.Enqueue(pid, int32(rating), int32(typeID)); err != nil {
+		code := http.StatusConflict
+		if err == ErrRating {
+			code = http.StatusBadRequest
+		}
+		replyError(w, code, err)
+		return
+	}
+	reply(w, http.StatusOK, m.peek(pid))
+}
+
+func (m *Matcher) handlePoll(w http.ResponseWriter, r *http.Request) {
+	pid, ok := m.player(w, r)
+	if !ok {
+		return
+	}
+	reply(w, http.StatusOK, m.Poll(pid))
+}
+
+func (m *Matcher) handleCancel(w http.ResponseWriter, r *http.Request) {
+	pid, ok := m.player(w, r)
+	if !ok {
+		return
+	}
+	if !m.Cancel(pid) {
+		replyError(w, http.StatusNotFound, fmt.Errorf("player[%d] is not waiting", pid))
+		return
+	}
+	reply(w, http.StatusOK, Status{})
+}
+
+// peek returns the status of the player without removing it
+func (m *Matcher) peek(pid uint64) Status {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if e, ok := m.entries[pid]; ok {
+		return e.status
+	}
+	return Status{}
+}
+
+func reply(w http.ResponseWriter, code int, s Status) {
+	writeJSON(w, code, &StatusJSON{
+		State:   s.State.String(),
+		Type:    s.TypeID,
+		Rating:  s.Rating,
+		Waited:  int64(s.Waited / time.Millisecond),
+		Room:    s.RoomID,
+		Token:   s.Token,
+		Players: s.Players,
+	})
+}
+
+func replyError(w http.ResponseWriter, code int, err error) {
+	writeJSON(w, code, &StatusJSON{State: StateNone.String(), Error: err.Error()})
+}
+
+func writeJSON(w http.ResponseWriter, code int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	_ = json.NewEncoder(w).Encode(v)
+}
@@ -0,0 +1,454 @@
+// Package matchmaking groups the players waiting in a queue by rating and creates the rooms for them
+package matchmaking
+
+import (
+	"errors"
+	"math/rand"
+	"net/http"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/alecthomas/log4go"
+	"github.com/hedon954/go-lock-step-server/logic/room"
+	"github.com/hedon954/go-lock-step-server/pkg/clock"
+)
+
+const (
+	DefaultPlayersPerRoom = 2
+	DefaultWindow         = 100
+	DefaultWindowGrowth   = 50
+	DefaultMaxWindow      = 1000
+	DefaultTimeout        = time.Minute
+	DefaultInterval       = time.Second
+
+	// MinRating and MaxRating bound the ratings enqueued
+	MinRating = -1 << 20
+	MaxRating = 1 << 20
+)
+
+var (
+	ErrQueued       = errors.New("player is queued already")
+	ErrStopped      = errors.New("matcher is stopped")
+	ErrUnauthorized = errors.New("player is not authenticated")
+	ErrRating       = errors.New("rating is out of range")
+)
+
+// RoomCreator creates the rooms of the matches, it is implemented by logic.RoomManager
+type RoomCreator interface {
+	CreateRoom(rid uint64, typeID int32, pid []uint64, randomSeed int32, logicServer string) (*room.Room, error)
+}
+
+// Config is the configuration of a matcher
+type Config struct {
+	// PlayersPerRoom is the number of players of a match, 0 means DefaultPlayersPerRoom
+	PlayersPerRoom int
+
+	// Window is the rating difference accepted at first, 0 means DefaultWindow
+	Window int32
+
+	// WindowGrowth widens the window every second a player waits, 0 means DefaultWindowGrowth
+	WindowGrowth int32
+
+	// MaxWindow is the widest window, 0 means DefaultMaxWindow
+	MaxWindow int32
+
+	// Timeout expires the players not matched in time, 0 means DefaultTimeout,
+	// and the matched or expired status is kept for polling as long
+	Timeout time.Duration
+
+	// Interval is how often Run matches the players, 0 means DefaultInterval
+	Interval time.Duration
+
+	// FirstRoomID is the id of the first room created, the ids increase from it
+	FirstRoomID uint64
+
+	// LogicServer is passed to RoomCreator
+	LogicServer string
+
+	// Clock tells the time of Enqueue and Run, nil means clock.Real
+	Clock clock.Clock
+
+	// Authenticate returns the player proven by the http request, the http api serves the player only,
+	// nil means the request carries the player and its key issued by PlayerKey with Secret
+	Authenticate func(r *http.Request) (uint64, error)
+
+	// Secret signs the keys of the players if Authenticate is nil, the http api rejects every request if it is empty
+	Secret string
+}
+
+// State is the state of a player in the matcher
+type State int
+
+const (
+	StateNone    State = iota // the player is not in the matcher
+	StateWaiting              // the player is waiting for a match
+	StateMatched              // the room of the player is created
+	StateExpired              // the player has not been matched in time
+)
+
+func (s State) String() string {
+	switch s {
+	case StateWaiting:
+		return "waiting"
+	case StateMatched:
+		return "matched"
+	case StateExpired:
+		return "expired"
+	default:
+		return "none"
+	}
+}
+
+// Status is the status of a player polled from the matcher
+type Status struct {
+	State   State
+	TypeID  int32
+	Rating  int32
+	Waited  time.Duration // how long the player has waited for the match
+	RoomID  uint64        // the room of the match if State is StateMatched
+	Token   string        // the token connecting to the room if State is StateMatched
+	Players []uint64      // the players of the room by seat if State is StateMatched
+}
+
+// entry is a player in the matcher
+type entry struct {
+	pid      uint64
+	rating   int32
+	typeID   int32
+	enqueued time.Time
+	done     time.Time // when the player has been matched or expired
+	creating bool      // the room of the player is being created
+	status   Status
+}
+
+// pendingRoom is a match whose room is being created
+type pendingRoom struct {
+	rid    uint64
+	typeID int32
+	group  []*entry
+}
+
+// Matcher groups the players of the same type by rating window, and the window widens as they wait
+type Matcher struct {
+	config  Config
+	creator RoomCreator
+	clock   clock.Clock
+
+	mu      sync.Mutex
+	entries map[uint64]*entry
+	nextID  uint64
+	stopped bool
+
+	stopOnce sync.Once
+	running  int32
+	exitChan chan struct{}
+	doneChan chan struct{}
+}
+
+// New creates a matcher, a nil config means the default one
+func New(creator RoomCreator, config *Config) *Matcher {
+	m := &Matcher{
+		creator:  creator,
+		entries:  make(map[uint64]*entry),
+		exitChan: make(chan struct{}),
+		doneChan: make(chan struct{}),
+	}
+	if config != nil {
+		m.config = *config
+	}
+	if m.config.PlayersPerRoom <= 0 {
+		m.config.PlayersPerRoom = DefaultPlayersPerRoom
+	}
+	if m.config.Window <= 0 {
+		m.config.Window = DefaultWindow
+	}
+	if m.config.WindowGrowth <= 0 {
+		m.config.WindowGrowth = DefaultWindowGrowth
+	}
+	if m.config.MaxWindow <= 0 {
+		m.config.MaxWindow = DefaultMaxWindow
+	}
+	if m.config.Timeout <= 0 {
+		m.config.Timeout = DefaultTimeout
+	}
+	if m.config.Interval <= 0 {
+		m.config.Interval = DefaultInterval
+	}
+	if m.config.FirstRoomID == 0 {
+		m.config.FirstRoomID = 1
+	}
+	m.nextID = m.config.FirstRoomID
+	m.clock = clock.OrReal(m.config.Clock)
+	return m
+}
+
+// Enqueue puts the player in the queue of the type, a player matched or expired can enqueue again.
+// The rating must be in [MinRating, MaxRating]
+func (m *Matcher) Enqueue(pid uint64, rating int32, typeID int32) error {
+	if rating < MinRating || rating > MaxRating {
+		return ErrRating
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.stopped {
+		return ErrStopped
+	}
+	if e, ok := m.entries[pid]; ok && e.status.State == StateWaiting {
+		return ErrQueued
+	}
+	m.entries[pid] = &entry{
+		pid:      pid,
+		rating:   rating,
+		typeID:   typeID,
+		enqueued: m.clock.Now(),
+		status:   Status{State: StateWaiting, TypeID: typeID, Rating: rating},
+	}
+	log4go.Info("[matcher] enqueue player[%d] rating=[%d] type=[%d]", pid, rating, typeID)
+	return nil
+}
+
+// Cancel removes the player waiting from the queue, it returns false if the player is not waiting
+// or the room of the player is being created
+func (m *Matcher) Cancel(pid uint64) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	e, ok := m.entries[pid]
+	if !ok || e.status.State != StateWaiting || e.creating {
+		return false
+	}
+	delete(m.entries, pid)
+	log4go.Info("[matcher] cancel player[%d]", pid)
+	return true
+}
+
+// Poll returns the status of the player, the matched or expired status is removed once polled
+func (m *Matcher) Poll(pid uint64) Status {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	e, ok := m.entries[pid]
+	if !ok {
+		return Status{}
+	}
+	status := e.status
+	if status.State == StateWaiting {
+		status.Waited = m.clock.Now().Sub(e.enqueued)
+	} else {
+		status.Waited = e.done.Sub(e.enqueued)
+		delete(m.entries, pid)
+	}
+	return status
+}
+
+// Waiting returns the number of the players waiting
+func (m *Matcher) Waiting() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	n := 0
+	for _, e := range m.entries {
+		if e.status.State == StateWaiting {
+			n++
+		}
+	}
+	return n
+}
+
+// Run matches the players every Config.Interval until Stop is called, it can only be run once
+func (m *Matcher) Run() {
+	if !atomic.CompareAndSwapInt32(&m.running, 0, 1) {
+		return
+	}
+	defer close(m.doneChan)
+	ticker := m.clock.NewTicker(m.config.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-m.exitChan:
+			return
+		case <-ticker.C():
+			m.Tick(m.clock.Now())
+		}
+	}
+}
+
+// Stop rejects the players enqueuing and waits for Run to quit if it is running,
+// it is safe to call it more than once
+func (m *Matcher) Stop() {
+	m.mu.Lock()
+	m.stopped = true
+	m.mu.Unlock()
+	m.stopOnce.Do(func() {
+		close(m.exitChan)
+	})
+	if atomic.LoadInt32(&m.running) != 0 {
+		<-m.doneChan
+	}
+}
+
+// Tick expires the players waiting too long, matches the others and creates their rooms,
+// the rooms are created without the lock so that the players are served meanwhile
+func (m *Matcher) Tick(now time.Time) {
+	for _, p := range m.matchAll(now) {
+		m.createRoom(p, now)
+	}
+}
+
+// matchAll expires the players waiting too long and returns the matches of the others
+func (m *Matcher) matchAll(now time.Time) []*pendingRoom {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	queues := make(map[int32][]*entry)
+	for pid, e := range m.entries {
+		switch {
+		case e.creating:
+			// the player is kept waiting until its room is created
+		case e.status.State == StateWaiting:
+			if now.Sub(e.enqueued) >= m.config.Timeout {
+				e.done = now
+				e.status.State = StateExpired
+				log4go.Warn("[matcher] player[%d] expired", pid)
+				continue
+			}
+			queues[e.typeID] = append(queues[e.typeID], e)
+		default:
+			// the status not polled in time is dropped
+			if now.Sub(e.done) >= m.config.Timeout {
+				delete(m.entries, pid)
+			}
+		}
+	}
+
+	var ret []*pendingRoom
+	for typeID, queue := range queues {
+		for _, group := range m.match(queue, now) {
+			for _, e := range group {
+				e.creating = true
+			}
+			// the id is skipped even if it fails, as it may be taken by a room created by others
+			ret = append(ret, &pendingRoom{rid: m.nextID, typeID: typeID, group: group})
+			m.nextID++
+		}
+	}
+	return ret
+}
+
+// window returns the rating difference the player accepts now
+func (m *Matcher) window(e *entry, now time.Time) int32 {
+	w := int64(m.config.Window) + int64(m.config.WindowGrowth)*int64(now.Sub(e.enqueued)/time.Second)
+	if w > int64(m.config.MaxWindow) {
+		return m.config.MaxWindow
+	}
+	return int32(w)
+}
+
+// match groups the players of a type, the players waiting longest are matched first
+// with the nearest ratings both sides accept
+func (m *Matcher) match(queue []*entry, now time.Time) [][]*entry {
+	sort.Slice(queue, func(i, j int) bool {
+		if !queue[i].enqueued.Equal(queue[j].enqueued) {
+			return queue[i].enqueued.Before(queue[j].enqueued)
+		}
+		return queue[i].pid < queue[j].pid
+	})
+
+	var ret [][]*entry
+	matched := make(map[uint64]bool)
+	for _, anchor := range queue {
+		if matched[anchor.pid] {
+			continue
+		}
+		var candidates []*entry
+		for _, e := range queue {
+			if e != anchor && !matched[e.pid] && m.accepts(anchor, e, now) {
+				candidates = append(candidates, e)
+			}
+		}
+		if len(candidates) < m.config.PlayersPerRoom-1 {
+			continue
+		}
+		sort.SliceStable(candidates, func(i, j int) bool {
+			return ratingDiff(candidates[i], anchor) < ratingDiff(candidates[j], anchor)
+		})
+
+		// every player of the group accepts all the others, not only the anchor
+		group := []*entry{anchor}
+		for _, e := range candidates {
+			if len(group) == m.config.PlayersPerRoom {
+				break
+			}
+			if m.acceptsAll(group, e, now) {
+				group = append(group, e)
+			}
+		}
+		if len(group) < m.config.PlayersPerRoom {
+			continue
+		}
+		for _, e := range group {
+			matched[e.pid] = true
+		}
+		ret = append(ret, group)
+	}
+	return ret
+}
+
+// accepts returns true if the rating difference of the players is in both their windows
+func (m *Matcher) accepts(a, b *entry, now time.Time) bool {
+	diff := ratingDiff(a, b)
+	return diff <= int64(m.window(a, now)) && diff <= int64(m.window(b, now))
+}
+
+// acceptsAll returns true if the player and every player of the group accept each other
+func (m *Matcher) acceptsAll(group []*entry, e *entry, now time.Time) bool {
+	for _, g := range group {
+		if !m.accepts(g, e, now) {
+			return false
+		}
+	}
+	return true
+}
+
+// createRoom creates the room of the match, the players keep waiting if it fails
+func (m *Matcher) createRoom(p *pendingRoom, now time.Time) {
+	players := make([]uint64, 0, len(p.group))
+	for _, e := range p.group {
+		players = append(players, e.pid)
+	}
+	r, err := m.creator.CreateRoom(p.rid, p.typeID, players, rand.Int31(), m.config.LogicServer)
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, e := range p.group {
+		e.creating = false
+	}
+	if err != nil {
+		log4go.Error("[matcher] create room[%d] players=%v error: %v", p.rid, players, err)
+		return
+	}
+
+	for _, e := range p.group {
+		e.done = now
+		e.status.State = StateMatched
+		e.status.RoomID = p.rid
+		e.status.Token = r.PlayerToken(e.pid)
+		e.status.Players = players
+	}
+	log4go.Info("[matcher] room[%d] created type=[%d] players=%v", p.rid, p.typeID, players)
+}
+
+// ratingDiff returns the rating difference of the players, it never overflows
+func ratingDiff(a, b *entry) int64 {
+	d := int64(a.rating) - int64(b.rating)
+	if d < 0 {
+		return -d
+	}
+	return d
+}
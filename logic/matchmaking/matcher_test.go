@@ -0,0 +1,296 @@
+package matchmaking
+
+import (
+	"encoding/json"
+	"errors"
+	"math/rand"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/hedon954/go-lock-step-server/logic"
+	"github.com/hedon954/go-lock-step-server/logic/room"
+	"github.com/hedon954/go-lock-step-server/pkg/clock"
+)
+
+// testCreator records the rooms created without running them
+type testCreator struct {
+	rooms map[uint64]*room.Room
+	types map[uint64]int32
+	fail  map[uint64]bool
+}
+
+func newTestCreator() *testCreator {
+	return &testCreator{
+		rooms: make(map[uint64]*room.Room),
+		types: make(map[uint64]int32),
+		fail:  make(map[uint64]bool),
+	}
+}
+
+func (c *testCreator) CreateRoom(rid uint64, typeID int32, pid []uint64, randomSeed int32,
+	logicServer string) (*room.Room, error) {
+	if c.fail[rid] {
+		return nil, errors.New("room exists")
+	}
+	r := room.NewRoom(rid, typeID, pid, randomSeed, logicServer, nil)
+	c.rooms[rid] = r
+	c.types[rid] = typeID
+	return r, nil
+}
+
+func Test_MatchByRating(t *testing.T) {
+	fake := clock.NewFake(time.Unix(1700000000, 0))
+	c := newTestCreator()
+	m := New(c, &Config{Clock: fake})
+
+	ratings := map[uint64]int32{1: 1500, 2: 2000, 3: 1550, 4: 2040, 5: 3000}
+	for pid := uint64(1); pid <= 5; pid++ {
+		if err := m.Enqueue(pid, ratings[pid], 0); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := m.Enqueue(6, 1500, 1); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.Enqueue(1, 1500, 0); err != ErrQueued {
+		t.Errorf("want: %v, got: %v", ErrQueued, err)
+	}
+	if err := m.Enqueue(7, MaxRating+1, 0); err != ErrRating {
+		t.Errorf("want: %v, got: %v", ErrRating, err)
+	}
+
+	m.Tick(fake.Now())
+	if len(c.rooms) != 2 {
+		t.Fatalf("%d rooms created", len(c.rooms))
+	}
+	for pid, rid := range map[uint64]uint64{1: 1, 3: 1, 2: 2, 4: 2} {
+		s := m.Poll(pid)
+		if s.State != StateMatched || s.RoomID != rid || !c.rooms[rid].HasPlayer(pid) {
+			t.Errorf("player[%d] got: %+v", pid, s)
+		}
+		if !c.rooms[rid].VerifyToken(pid, s.Token) {
+			t.Errorf("player[%d] the token is not issued by the room", pid)
+		}
+		if s = m.Poll(pid); s.State != StateNone {
+			t.Errorf("player[%d] the status should be removed once polled, got: %+v", pid, s)
+		}
+	}
+	for _, pid := range []uint64{5, 6} {
+		if s := m.Poll(pid); s.State != StateWaiting {
+			t.Errorf("player[%d] got: %+v", pid, s)
+		}
+	}
+	if n := m.Waiting(); n != 2 {
+		t.Errorf("Waiting[%d] should be [2]", n)
+	}
+}
+
+func Test_MatchWindow(t *testing.T) {
+	fake := clock.NewFake(time.Unix(1700000000, 0))
+	c := newTestCreator()
+	m := New(c, &Config{Clock: fake, Timeout: time.Second * 10})
+
+	_ = m.Enqueue(1, 1000, 0)
+	_ = m.Enqueue(2, 1400, 0)
+	fake.Advance(time.Second * 3)
+	_ = m.Enqueue(3, 5000, 0)
+
+	// the window is 100 at first, and it widens by 50 every second both players wait
+	fake.Advance(time.Second * 2)
+	m.Tick(fake.Now())
+	if len(c.rooms) != 0 {
+		t.Fatal("the players should not be matched in the window")
+	}
+	fake.Advance(time.Second)
+	m.Tick(fake.Now())
+	if len(c.rooms) != 1 || !c.rooms[1].HasPlayer(1) || !c.rooms[1].HasPlayer(2) {
+		t.Fatalf("the players should be matched in the widened window")
+	}
+	if s := m.Poll(2); s.Waited != time.Second*6 {
+		t.Errorf("waited: %s", s.Waited)
+	}
+
+	// the player is expired if not matched in time
+	fake.Advance(time.Second * 7)
+	m.Tick(fake.Now())
+	if s := m.Poll(3); s.State != StateExpired || s.Waited != time.Second*10 {
+		t.Errorf("got: %+v", s)
+	}
+	if err := m.Enqueue(3, 5000, 0); err != nil {
+		t.Errorf("the player expired should enqueue again: %v", err)
+	}
+	if !m.Cancel(3) || m.Cancel(3) {
+		t.Error("the player waiting should be canceled once")
+	}
+}
+
+func Test_MatchGroup(t *testing.T) {
+	fake := clock.NewFake(time.Unix(1700000000, 0))
+	c := newTestCreator()
+	m := New(c, &Config{Clock: fake, PlayersPerRoom: 3})
+
+	// both the others are in the window of the anchor, but not in the window of each other
+	_ = m.Enqueue(1, 1000, 0)
+	_ = m.Enqueue(2, 900, 0)
+	_ = m.Enqueue(3, 1100, 0)
+	m.Tick(fake.Now())
+	if len(c.rooms) != 0 {
+		t.Fatalf("%d rooms created", len(c.rooms))
+	}
+
+	_ = m.Enqueue(4, 1050, 0)
+	m.Tick(fake.Now())
+	if len(c.rooms) != 1 {
+		t.Fatalf("%d rooms created", len(c.rooms))
+	}
+	for _, pid := range []uint64{1, 3, 4} {
+		if s := m.Poll(pid); s.State != StateMatched {
+			t.Errorf("player[%d] got: %+v", pid, s)
+		}
+	}
+	if s := m.Poll(2); s.State != StateWaiting {
+		t.Errorf("player[2] got: %+v", s)
+	}
+}
+
+func Test_MatchCreateRoomFailure(t *testing.T) {
+	c := newTestCreator()
+	c.fail[1] = true
+	m := New(c, nil)
+
+	_ = m.Enqueue(1, 1000, 0)
+	_ = m.Enqueue(2, 1000, 0)
+	now := time.Now()
+	m.Tick(now)
+	if s := m.Poll(1); s.State != StateWaiting {
+		t.Fatalf("the players should keep waiting, got: %+v", s)
+	}
+	m.Tick(now)
+	if s := m.Poll(1); s.State != StateMatched || s.RoomID != 2 {
+		t.Errorf("the room id taken should be skipped, got: %+v", s)
+	}
+}
+
+// blockingCreator blocks CreateRoom until it is released
+type blockingCreator struct {
+	*testCreator
+	entered chan struct{}
+	release chan struct{}
+}
+
+func (c *blockingCreator) CreateRoom(rid uint64, typeID int32, pid []uint64, randomSeed int32,
+	logicServer string) (*room.Room, error) {
+	c.entered <- struct{}{}
+	<-c.release
+	return c.testCreator.CreateRoom(rid, typeID, pid, randomSeed, logicServer)
+}
+
+func Test_MatchCreateRoomUnlocked(t *testing.T) {
+	c := &blockingCreator{testCreator: newTestCreator(), entered: make(chan struct{}), release: make(chan struct{})}
+	m := New(c, nil)
+	_ = m.Enqueue(1, 1000, 0)
+	_ = m.Enqueue(2, 1000, 0)
+
+	done := make(chan struct{})
+	go func() {
+		m.Tick(time.Now())
+		close(done)
+	}()
+	<-c.entered
+
+	// the players are served while the room is created, and the players matched can not cancel
+	if err := m.Enqueue(3, 1000, 0); err != nil {
+		t.Fatal(err)
+	}
+	if s := m.Poll(1); s.State != StateWaiting {
+		t.Errorf("player[1] got: %+v", s)
+	}
+	if m.Cancel(1) {
+		t.Error("the player whose room is being created should not cancel")
+	}
+	m.Tick(time.Now())
+
+	close(c.release)
+	<-done
+	if s := m.Poll(1); s.State != StateMatched || s.RoomID != 1 {
+		t.Errorf("player[1] got: %+v", s)
+	}
+	if s := m.Poll(3); s.State != StateWaiting {
+		t.Errorf("player[3] got: %+v", s)
+	}
+}
+
+func Test_MatchHTTP(t *testing.T) {
+	rm := logic.NewRoomManager(nil)
+	defer rm.Stop()
+	fake := clock.NewFake(time.Unix(1700000000, 0))
+	m := New(rm, &Config{Clock: fake, PlayersPerRoom: 4, FirstRoomID: 100, Secret: "secret"})
+	s := httptest.NewServer(m.Handler())
+	defer s.Close()
+
+	get := func(path string, code int) *StatusJSON {
+		resp, err := http.Get(s.URL + path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		ret := &StatusJSON{}
+		if err = json.NewDecoder(resp.Body).Decode(ret); err != nil {
+			t.Fatal(err)
+		}
+		if resp.StatusCode != code {
+			t.Errorf("%s status[%d] should be [%d]: %+v", path, resp.StatusCode, code, ret)
+		}
+		return ret
+	}
+
+	player := func(pid int) string {
+		return "player=" + strconv.Itoa(pid) + "&key=" + PlayerKey("secret", uint64(pid))
+	}
+
+	// the synthetic players of random ratings
+	const players = 200
+	rnd := rand.New(rand.NewSource(1))
+	for pid := 1; pid <= players; pid++ {
+		ret := get("/enqueue?"+player(pid)+"&rating="+strconv.Itoa(1000+rnd.Intn(1000))+"&type="+strconv.Itoa(pid%2),
+			http.StatusOK)
+		if ret.State != "waiting" {
+			t.Fatalf("enqueue got: %+v", ret)
+		}
+	}
+	get("/enqueue?"+player(1)+"&rating=1000", http.StatusConflict)
+	get("/enqueue?"+player(1)+"&rating=x", http.StatusBadRequest)
+	get("/enqueue?"+player(201)+"&rating=2147483647", http.StatusBadRequest)
+	get("/enqueue?player=x&rating=1000", http.StatusUnauthorized)
+
+	// the players can not act as the others
+	get("/enqueue?player=201&rating=1000", http.StatusUnauthorized)
+	get("/enqueue?player=201&key="+PlayerKey("secret", 1)+"&rating=1000", http.StatusUnauthorized)
+	get("/poll?player=1&key="+PlayerKey("other", 1), http.StatusUnauthorized)
+	get("/cancel?player=1", http.StatusUnauthorized)
+
+	for i := 0; i < 20; i++ {
+		fake.Advance(time.Second)
+		m.Tick(fake.Now())
+	}
+
+	rooms := make(map[uint64]int)
+	for pid := 1; pid <= players; pid++ {
+		ret := get("/poll?"+player(pid), http.StatusOK)
+		if ret.State != "matched" {
+			t.Fatalf("player[%d] should be matched, got: %+v", pid, ret)
+		}
+		r := rm.GetRoom(ret.Room)
+		if r == nil || !r.HasPlayer(uint64(pid)) || !r.VerifyToken(uint64(pid), ret.Token) || len(ret.Players) != 4 {
+			t.Fatalf("player[%d] got: %+v", pid, ret)
+		}
+		rooms[ret.Room]++
+	}
+	if len(rooms) != players/4 || rm.RoomNum() != players/4 {
+		t.Errorf("%d rooms created, want %d", len(rooms), players/4)
+	}
+	get("/cancel?"+player(1), http.StatusNotFound)
+}
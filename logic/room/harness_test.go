@@ -0,0 +1,203 @@
+package room
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/hedon954/go-lock-step-server/pb"
+	"github.com/hedon954/go-lock-step-server/pkg/clock"
+	"github.com/hedon954/go-lock-step-server/pkg/network"
+	"github.com/hedon954/go-lock-step-server/pkg/packet/pb_packet"
+	"google.golang.org/protobuf/proto"
+)
+
+// harness drives a room frame by frame in simulated time, the main loop of the room is never run,
+// so that every message a player receives is the result of the steps taken by the test
+type harness struct {
+	t       *testing.T
+	r       *Room
+	clock   *clock.Fake
+	clients map[uint64]*testClient
+}
+
+// testClient is the client side of a player connected to the room by a pipe
+type testClient struct {
+	conn    *network.Conn
+	packets chan *pb_packet.Packet
+}
+
+func newHarness(t *testing.T, players []uint64, config *Config) *harness {
+	var c Config
+	if config != nil {
+		c = *config
+	}
+	fake := clock.NewFake(time.Unix(1700000000, 0))
+	c.Clock = fake
+	return &harness{
+		t:       t,
+		r:       NewRoom(1, 0, players, 0, "test", &c),
+		clock:   fake,
+		clients: make(map[uint64]*testClient),
+	}
+}
+
+// connect connects the player like the router, the join is handled by the next step
+func (h *harness) connect(pid uint64) {
+	h.dial(pid, "", 0)
+}
+
+// resume connects the player with the resume ticket like the router, the resume is handled by the next step
+func (h *harness) resume(pid uint64, ticket string, frameID uint32) {
+	h.dial(pid, ticket, frameID)
+}
+
+func (h *harness) dial(pid uint64, ticket string, frameID uint32) {
+	c1, c2 := net.Pipe()
+	h.t.Cleanup(func() {
+		c1.Close()
+		c2.Close()
+	})
+	srv := network.NewServer(&network.Config{
+		PacketSendChanLimit:    1024,
+		PacketReceiveChanLimit: 16,
+		ConnReadTimeout:        time.Hour,
+		ConnWriteTimeout:       time.Hour,
+	}, h.r, &pb_packet.MsgProtocol{})
+
+	c := &testClient{
+		conn:    network.NewConn(c1, srv),
+		packets: make(chan *pb_packet.Packet, 1024),
+	}
+	c.conn.PutExtraData(pid)
+	if ticket != "" {
+		if !h.r.OnResume(c.conn, ticket, frameID) {
+			h.t.Fatalf("player[%d] OnResume failed", pid)
+		}
+		c.conn.SetCallback(&resumeCallback{h.r})
+	}
+	go func() {
+		defer close(c.packets)
+		protocol := pb_packet.NewClientProtocol(nil)
+		for {
+			p, err := protocol.ReadPacket(c2)
+			if err != nil {
+				return
+			}
+			c.packets <- p.(*pb_packet.Packet)
+		}
+	}()
+	h.clients[pid] = c
+	c.conn.Do()
+}
+
+// disconnect closes the connection of the player, the leave is handled by the next step
+func (h *harness) disconnect(pid uint64) {
+	h.clients[pid].conn.Close()
+}
+
+// send queues the message of the player, it is handled by the next step
+func (h *harness) send(pid uint64, id pb.ID, msg proto.Message) {
+	if !h.r.OnMessage(h.clients[pid].conn, pb_packet.NewPacket(uint8(id), msg)) {
+		h.t.Fatalf("player[%d] send message[%s] failed", pid, id)
+	}
+}
+
+// step handles the queued events and messages, then advances the clock by a tick and ticks the room,
+// it returns false if the room should quit
+func (h *harness) step() bool {
+	h.r.handleMessages()
+	h.clock.Advance(TickTimer)
+	return h.r.tick()
+}
+
+// steps steps n times, it fails the test if the room quits
+func (h *harness) steps(n int) {
+	for i := 0; i < n; i++ {
+		if !h.step() {
+			h.t.Fatalf("the room quits at step %d/%d", i+1, n)
+		}
+	}
+}
+
+// received returns the messages the player has received since the last call
+func (h *harness) received(pid uint64) []*pb_packet.Packet {
+	c := h.clients[pid]
+	// the sentinel is written after all the messages sent to the player
+	sentinel := pb_packet.NewPacket(uint8(pb.ID_MSG_END), nil)
+	if c.conn.AsyncWritePacket(sentinel, 0) != nil {
+		return h.drain(c)
+	}
+
+	var ret []*pb_packet.Packet
+	timeout := time.After(time.Second)
+	for {
+		select {
+		case p, ok := <-c.packets:
+			if !ok || pb.ID(p.GetMessageID()) == pb.ID_MSG_END {
+				return ret
+			}
+			ret = append(ret, p)
+		case <-timeout:
+			h.t.Fatalf("player[%d] the sentinel is not received", pid)
+		}
+	}
+}
+
+// drain returns the messages received by the closed connection
+func (h *harness) drain(c *testClient) []*pb_packet.Packet {
+	var ret []*pb_packet.Packet
+	timeout := time.After(time.Second)
+	for {
+		select {
+		case p, ok := <-c.packets:
+			if !ok {
+				return ret
+			}
+			ret = append(ret, p)
+		case <-timeout:
+			h.t.Fatal("the closed connection is not drained")
+		}
+	}
+}
+
+// receivedIDs returns the ids of the messages the player has received since the last call
+func (h *harness) receivedIDs(pid uint64) []pb.ID {
+	var ret []pb.ID
+	for _, p := range h.received(pid) {
+		ret = append(ret, pb.ID(p.GetMessageID()))
+	}
+	return ret
+}
+
+// frames returns the frames the player has received since the last call
+func (h *harness) frames(pid uint64) []*pb.FrameData {
+	var ret []*pb.FrameData
+	for _, p := range h.received(pid) {
+		if pb.ID(p.GetMessageID()) != pb.ID_MSG_Frame {
+			continue
+		}
+		msg := &pb.S2C_FrameMsg{}
+		if err := p.UnmarshalPB(msg); err != nil {
+			h.t.Fatal(err)
+		}
+		ret = append(ret, msg.GetFrames()...)
+	}
+	return ret
+}
+
+// start connects all the players and steps until the game starts
+func (h *harness) start(players ...uint64) {
+	for _, pid := range players {
+		h.connect(pid)
+	}
+	h.steps(1)
+	if !h.r.started {
+		h.t.Fatal("the game should be started")
+	}
+	for _, pid := range players {
+		if ids := h.receivedIDs(pid); len(ids) != 2 || ids[0] != pb.ID_MSG_Connect || ids[1] != pb.ID_MSG_Start {
+			h.t.Fatalf("player[%d] received %v at start", pid, ids)
+		}
+	}
+}
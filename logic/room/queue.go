@@ -0,0 +1,188 @@
+package room
+
+import (
+	"sync"
+
+	"github.com/hedon954/go-lock-step-server/pkg/network"
+)
+
+const (
+	// DefaultQueueSize is the default capacity of the message queue of a room
+	DefaultQueueSize = 2048
+
+	// the maximum number of pending joins of a room
+	kMaxPendingJoins = 64
+)
+
+// OverflowPolicy is the policy applied when the message queue of a room is full
+type OverflowPolicy int
+
+const (
+	OverflowDropOldest OverflowPolicy = iota // drop the oldest queued message to make room for the new one
+	OverflowDropNewest                       // drop the new message
+	OverflowDisconnect                       // drop the new message and disconnect its sender
+)
+
+// QueueStats is the statistics of the message queue of a room
+type QueueStats struct {
+	Depth        int    // the number of queued messages
+	MaxDepth     int    // the maximum number of queued messages ever
+	Capacity     int    // the capacity of the queue
+	Dropped      uint64 // the number of dropped messages
+	Disconnected uint64 // the number of senders disconnected because of overflow
+	PendingConns int    // the number of queued connection events
+}
+
+// msgQueue is a bounded FIFO queue of messages which never blocks the sender
+type msgQueue struct {
+	mu           sync.Mutex
+	items        []*packet
+	head         int
+	size         int
+	maxDepth     int
+	dropped      uint64
+	disconnected uint64
+	policy       OverflowPolicy
+	notify       chan struct{}
+}
+
+func newMsgQueue(capacity int, policy OverflowPolicy) *msgQueue {
+	if capacity <= 0 {
+		capacity = DefaultQueueSize
+	}
+	return &msgQueue{
+		items:  make([]*packet, capacity),
+		policy: policy,
+		notify: make(chan struct{}, 1),
+	}
+}
+
+// push appends p to the queue, it returns false if the sender should be disconnected
+func (q *msgQueue) push(p *packet) bool {
+	q.mu.Lock()
+	if q.size == len(q.items) {
+		switch q.policy {
+		case OverflowDropOldest:
+			q.items[q.head] = nil
+			q.head = (q.head + 1) % len(q.items)
+			q.size--
+			q.dropped++
+		case OverflowDisconnect:
+			q.dropped++
+			q.disconnected++
+			q.mu.Unlock()
+			return false
+		default:
+			q.dropped++
+			q.mu.Unlock()
+			return true
+		}
+	}
+
+	q.items[(q.head+q.size)%len(q.items)] = p
+	q.size++
+	if q.size > q.maxDepth {
+		q.maxDepth = q.size
+	}
+	q.mu.Unlock()
+
+	select {
+	case q.notify <- struct{}{}:
+	default:
+	}
+	return true
+}
+
+// popAll removes and returns all the queued messages
+func (q *msgQueue) popAll() []*packet {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	ret := make([]*packet, 0, q.size)
+	for ; q.size > 0; q.size-- {
+		ret = append(ret, q.items[q.head])
+		q.items[q.head] = nil
+		q.head = (q.head + 1) % len(q.items)
+	}
+	return ret
+}
+
+func (q *msgQueue) stats() QueueStats {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	return QueueStats{
+		Depth:        q.size,
+		MaxDepth:     q.maxDepth,
+		Capacity:     len(q.items),
+		Dropped:      q.dropped,
+		Disconnected: q.disconnected,
+	}
+}
+
+// connEvent is a connection joining or leaving a room
+type connEvent struct {
+	conn   *network.Conn
+	join   bool
+	resume *resumeInfo // the join resumes the session if it is not nil
+}
+
+// resumeInfo is the resume ticket presented by a joining connection
+type resumeInfo struct {
+	ticket  string
+	frameID uint32
+}
+
+// connQueue is a FIFO queue of connection events which never blocks the sender,
+// the number of pending joins is bounded while leaves are always accepted,
+// as there is at most one leave for every accepted join
+type connQueue struct {
+	mu     sync.Mutex
+	events []connEvent
+	joins  int
+	notify chan struct{}
+}
+
+func newConnQueue() *connQueue {
+	return &connQueue{
+		notify: make(chan struct{}, 1),
+	}
+}
+
+// push appends the event to the queue, it returns false if there are too many pending joins
+func (q *connQueue) push(e connEvent) bool {
+	q.mu.Lock()
+	if e.join {
+		if q.joins >= kMaxPendingJoins {
+			q.mu.Unlock()
+			return false
+		}
+		q.joins++
+	}
+	q.events = append(q.events, e)
+	q.mu.Unlock()
+
+	select {
+	case q.notify <- struct{}{}:
+	default:
+	}
+	return true
+}
+
+// popAll removes and returns all the queued events
+func (q *connQueue) popAll() []connEvent {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	ret := q.events
+	q.events = nil
+	q.joins = 0
+	return ret
+}
+
+func (q *connQueue) len() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	return len(q.events)
+}
@@ -0,0 +1,105 @@
+package room
+
+import (
+	"testing"
+)
+
+func pushN(q *msgQueue, from, n int) (disconnects int) {
+	for i := from; i < from+n; i++ {
+		if !q.push(&packet{id: uint64(i)}) {
+			disconnects++
+		}
+	}
+	return
+}
+
+func ids(ps []*packet) []uint64 {
+	ret := make([]uint64, 0, len(ps))
+	for _, p := range ps {
+		ret = append(ret, p.id)
+	}
+	return ret
+}
+
+func Test_MsgQueueDropOldest(t *testing.T) {
+	q := newMsgQueue(3, OverflowDropOldest)
+	if n := pushN(q, 1, 5); n != 0 {
+		t.Errorf("disconnects[%d] should be [0]", n)
+	}
+
+	got := ids(q.popAll())
+	want := []uint64{3, 4, 5}
+	if len(got) != len(want) || got[0] != want[0] || got[1] != want[1] || got[2] != want[2] {
+		t.Errorf("want: %v, got: %v", want, got)
+	}
+
+	stats := q.stats()
+	if stats.Depth != 0 || stats.MaxDepth != 3 || stats.Capacity != 3 || stats.Dropped != 2 {
+		t.Errorf("unexpected stats: %+v", stats)
+	}
+}
+
+func Test_MsgQueueDropNewest(t *testing.T) {
+	q := newMsgQueue(3, OverflowDropNewest)
+	if n := pushN(q, 1, 5); n != 0 {
+		t.Errorf("disconnects[%d] should be [0]", n)
+	}
+
+	got := ids(q.popAll())
+	want := []uint64{1, 2, 3}
+	if len(got) != len(want) || got[0] != want[0] || got[1] != want[1] || got[2] != want[2] {
+		t.Errorf("want: %v, got: %v", want, got)
+	}
+	if stats := q.stats(); stats.Dropped != 2 || stats.Disconnected != 0 {
+		t.Errorf("unexpected stats: %+v", stats)
+	}
+}
+
+func Test_MsgQueueDisconnect(t *testing.T) {
+	q := newMsgQueue(3, OverflowDisconnect)
+	if n := pushN(q, 1, 5); n != 2 {
+		t.Errorf("disconnects[%d] should be [2]", n)
+	}
+	if stats := q.stats(); stats.Depth != 3 || stats.Dropped != 2 || stats.Disconnected != 2 {
+		t.Errorf("unexpected stats: %+v", stats)
+	}
+
+	// the queue accepts messages again once it is drained
+	q.popAll()
+	if n := pushN(q, 6, 1); n != 0 {
+		t.Errorf("disconnects[%d] should be [0]", n)
+	}
+}
+
+func Test_MsgQueueWrapAround(t *testing.T) {
+	q := newMsgQueue(4, OverflowDropNewest)
+	for round := 0; round < 10; round++ {
+		pushN(q, round*3, 3)
+		got := ids(q.popAll())
+		if len(got) != 3 || got[0] != uint64(round*3) || got[2] != uint64(round*3+2) {
+			t.Fatalf("round[%d] got: %v", round, got)
+		}
+	}
+}
+
+func Test_ConnQueuePendingJoins(t *testing.T) {
+	q := newConnQueue()
+	for i := 0; i < kMaxPendingJoins; i++ {
+		if !q.push(connEvent{join: true}) {
+			t.Fatalf("join[%d] should be accepted", i)
+		}
+	}
+	if q.push(connEvent{join: true}) {
+		t.Error("join should be rejected when there are too many pending joins")
+	}
+	if !q.push(connEvent{}) {
+		t.Error("leave should always be accepted")
+	}
+
+	if n := len(q.popAll()); n != kMaxPendingJoins+1 {
+		t.Errorf("events[%d] should be [%d]", n, kMaxPendingJoins+1)
+	}
+	if !q.push(connEvent{join: true}) {
+		t.Error("join should be accepted after the queue is drained")
+	}
+}
@@ -0,0 +1,56 @@
+package room
+
+import (
+	"github.com/hedon954/go-lock-step-server/logic/game"
+)
+
+// Reason is the reason why a room quits
+type Reason int
+
+const (
+	ReasonFinished  Reason = iota // the game is over
+	ReasonNoShow                  // nobody connected to the room
+	ReasonCancelled               // the room was cancelled before the game started
+	ReasonTimeout                 // the room reached TimeoutTime
+	ReasonStopped                 // the room was stopped by force
+)
+
+var reasonStrings = []string{"finished", "no-show", "cancelled", "timeout", "stopped"}
+
+func (r Reason) String() string {
+	if r < 0 || int(r) >= len(reasonStrings) {
+		return "unknown"
+	}
+	return reasonStrings[r]
+}
+
+// Result is the outcome of a room, it is reported once the room quits
+type Result struct {
+	RoomID     uint64
+	TypeID     int32
+	Players    []uint64
+	Reason     Reason
+	Winners    map[uint64]uint64                // the winner reported by each player
+	Winner     uint64                           // the winner decided by the simulation, it settles Winners
+	Frames     uint32                           // the number of the frames played
+	Violations map[uint64]game.PlayerViolations // the invalid inputs of the players who have sent them
+	Timing     map[uint64]game.TimingStats      // the timing of the players while gaming
+	Desyncs    map[uint64]int                   // the state hashes differing from the simulation's
+	CreateTime int64
+	EndTime    int64
+}
+
+// ResultReporter receives the results of the rooms,
+// ReportResult is called in the room's goroutine and should not block for long
+type ResultReporter interface {
+	ReportResult(result *Result)
+}
+
+// Reporters reports the results to every reporter in order
+type Reporters []ResultReporter
+
+func (rs Reporters) ReportResult(result *Result) {
+	for _, r := range rs {
+		r.ReportResult(result)
+	}
+}
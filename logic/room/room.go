@@ -1,12 +1,14 @@
 package room
 
 import (
+	"errors"
 	"sync"
 	"sync/atomic"
 	"time"
 
 	"github.com/alecthomas/log4go"
 	"github.com/hedon954/go-lock-step-server/logic/game"
+	"github.com/hedon954/go-lock-step-server/pkg/clock"
 	"github.com/hedon954/go-lock-step-server/pkg/network"
 	"github.com/hedon954/go-lock-step-server/pkg/packet/pb_packet"
 )
@@ -17,6 +19,63 @@ const (
 	TimeoutTime       = time.Minute * 5
 )
 
+var (
+	ErrRoomStarted = errors.New("room has been started")
+	ErrRoomClosed  = errors.New("room has been closed")
+)
+
+// Config is the configuration of a room
+type Config struct {
+	// IdleTimeout reaps the room if nobody connects to it within this duration,
+	// 0 means the room waits until the game gives up
+	IdleTimeout time.Duration
+
+	// Reporter receives the result when the room quits, it can be nil
+	Reporter ResultReporter
+
+	// QueueSize is the capacity of the message queue, 0 means DefaultQueueSize
+	QueueSize int
+
+	// OverflowPolicy is applied when the message queue is full
+	OverflowPolicy OverflowPolicy
+
+	// ResumeTTL is how long a player can resume the session with the ticket once the conn is lost,
+	// 0 means DefaultResumeTTL, and a negative value disables resumption
+	ResumeTTL time.Duration
+
+	// Clock drives the ticks and the timeouts of the room, nil means clock.Real
+	Clock clock.Clock
+
+	// Bots creates the bots taking over the seats of the players who are not connected while gaming,
+	// nil leaves the seats empty
+	Bots game.BotFactory
+
+	// Validators creates the input validator of the game by the type of the room,
+	// the inputs of the types not in it are not validated
+	Validators map[int32]func() game.InputValidator
+
+	// KickThreshold kicks the player out once the violations of the inputs reach it, 0 means never
+	KickThreshold int
+
+	// Timing is the thresholds of the timing anomalies of the players, nil means the defaults
+	Timing *game.TimingConfig
+
+	// Simulations creates the simulation of the game by the type of the room,
+	// the games of the types not in it relay the frames only, and the seats of the others are locked once started
+	Simulations map[int32]func() game.Simulation
+
+	// Frames creates the store of the frames of the room, nil or an error keeps all the frames in memory
+	Frames func(rid uint64) (game.FrameStore, error)
+}
+
+// seatRequest asks the main loop to assign the seat to the player
+type seatRequest struct {
+	seat     int32
+	pid      uint64
+	replaced uint64
+	reply    chan error
+}
+
 type packet struct {
 	id  uint64
 	msg network.Packet
@@ -24,40 +83,78 @@ type packet struct {
 
 // Room is the Battle Room
 type Room struct {
-	wg sync.WaitGroup
+	stopOnce sync.Once
+	mu       sync.RWMutex // guards players
 
 	roomID      uint64
 	players     []uint64
 	typeID      int32
 	closeFlag   int32
+	running     int32
 	timeStamp   int64
-	secretKey   string
+	createTime  time.Time
+	secretKey   string // signs the tickets and tokens of the room, it never leaves the room
 	logicServer string
+	config      Config
+	joined      bool
+	started     bool
+	reason      Reason
+	clock       clock.Clock
 
-	exitChan chan struct{}
-	msgQ     chan *packet
-	inChan   chan *network.Conn
-	outChan  chan *network.Conn
+	exitChan   chan struct{}
+	doneChan   chan struct{}
+	cancelChan chan chan error
+	seatChan   chan *seatRequest
+	msgQ       *msgQueue
+	connQ      *connQueue
 
 	g *game.Game
 }
 
 // NewRoom creates a game room
-func NewRoom(rid uint64, typeID int32, players []uint64, randomSeed int32, logicServer string) *Room {
+func NewRoom(rid uint64, typeID int32, players []uint64, randomSeed int32, logicServer string, config *Config) *Room {
 	r := &Room{
 		roomID:      rid,
-		players:     players,
+		players:     append([]uint64(nil), players...),
 		typeID:      typeID,
 		exitChan:    make(chan struct{}),
-		msgQ:        make(chan *packet, 2048),
-		outChan:     make(chan *network.Conn, 8),
-		inChan:      make(chan *network.Conn, 8),
-		timeStamp:   time.Now().Unix(),
+		doneChan:    make(chan struct{}),
+		cancelChan:  make(chan chan error),
+		seatChan:    make(chan *seatRequest),
+		connQ:       newConnQueue(),
 		logicServer: logicServer,
-		secretKey:   "test_room",
+		secretKey:   newSecretKey(),
+	}
+	if config != nil {
+		r.config = *config
 	}
+	r.clock = clock.OrReal(r.config.Clock)
+	r.createTime = r.clock.Now()
+	r.timeStamp = r.createTime.Unix()
+	r.msgQ = newMsgQueue(r.config.QueueSize, r.config.OverflowPolicy)
 
-	r.g = game.NewGame(rid, players, randomSeed, r)
+	gameConfig := &game.Config{
+		Clock:         r.clock,
+		Bots:          r.config.Bots,
+		KickThreshold: r.config.KickThreshold,
+		Timing:        r.config.Timing,
+		ResumeTTL:     r.resumeTTL(),
+	}
+	if f := r.config.Validators[typeID]; f != nil {
+		gameConfig.Validator = f()
+	}
+	if f := r.config.Simulations[typeID]; f != nil {
+		gameConfig.Simulation = f()
+	}
+	if r.config.Frames != nil {
+		store, err := r.config.Frames(rid)
+		if err != nil {
+			log4go.Error("[room(%d)] create frame store error:[%s], the frames are kept in memory", rid, err.Error())
+		} else {
+			gameConfig.Frames = store
+		}
+	}
+	r.g = game.NewGame(rid, players, randomSeed, r, gameConfig)
 	return r
 }
 
@@ -65,10 +162,6 @@ func (r *Room) ID() uint64 {
 	return r.roomID
 }
 
-func (r *Room) SecretKey() string {
-	return r.secretKey
-}
-
 func (r *Room) TimeStamp() int64 {
 	return r.timeStamp
 }
@@ -78,6 +171,8 @@ func (r *Room) IsOver() bool {
 }
 
 func (r *Room) HasPlayer(id uint64) bool {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
 	for _, v := range r.players {
 		if v == id {
 			return true
@@ -86,11 +181,20 @@ func (r *Room) HasPlayer(id uint64) bool {
 	return false
 }
 
+// Players returns the players by seat, the seat of players[i] is i+1
+func (r *Room) Players() []uint64 {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return append([]uint64(nil), r.players...)
+}
+
 func (r *Room) OnJoinGame(gid uint64, pid uint64) {
+	r.joined = true
 	log4go.Warn("[room(%d)] onJoinGame %d", gid, pid)
 }
 
 func (r *Room) OnGameStart(gid uint64) {
+	r.started = true
 	log4go.Warn("[room(%d)] onGameStart", gid)
 }
 
@@ -101,19 +205,38 @@ func (r *Room) OnLeaveGame(gid uint64, pid uint64) {
 func (r *Room) OnGameOver(gid uint64) {
 	atomic.StoreInt32(&r.closeFlag, 1)
 	log4go.Warn("[room(%d)] onGameOver", gid)
-	r.wg.Add(1)
-	go func() {
-		defer r.wg.Done()
-		// TODO
-		// http result
-	}()
 }
 
-// OnConnect network.Conn callback
+func (r *Room) OnTimingAnomaly(gid uint64, pid uint64, stats game.TimingStats) {
+	log4go.Warn("[room(%d)] onTimingAnomaly %d score=%.0f jitter=%.1f heartbeat_gaps=%d bursts=%d ahead=%d", gid, pid,
+		stats.Score, stats.Jitter, stats.HeartbeatGaps, stats.Bursts, stats.AheadInputs)
+}
+
+// OnConnect network.Conn callback,
+// the caller should route the other callbacks of conn to the room before calling it
 func (r *Room) OnConnect(conn *network.Conn) bool {
-	conn.SetCallback(r)
-	r.inChan <- conn
-	log4go.Warn("[room(%d)] OnConnect %d", r.roomID, conn.GetExtraData().(uint64))
+	id := conn.GetExtraData()
+	if !r.connQ.push(connEvent{conn: conn, join: true}) {
+		// the leave of the rejected conn must not affect the player
+		conn.PutExtraData(nil)
+		log4go.Error("[room(%d)] OnConnect %d too many pending connections", r.roomID, id)
+		return false
+	}
+	log4go.Warn("[room(%d)] OnConnect %d", r.roomID, id)
+	return true
+}
+
+// OnResume attaches the conn presenting a verified resume ticket to the player slot,
+// the frames are caught up from frameID. The caller should route the callbacks of conn to the room before calling it
+func (r *Room) OnResume(conn *network.Conn, ticket string, frameID uint32) bool {
+	id := conn.GetExtraData()
+	e := connEvent{conn: conn, join: true, resume: &resumeInfo{ticket: ticket, frameID: frameID}}
+	if !r.connQ.push(e) {
+		conn.PutExtraData(nil)
+		log4go.Error("[room(%d)] OnResume %d too many pending connections", r.roomID, id)
+		return false
+	}
+	log4go.Warn("[room(%d)] OnResume %d frame=[%d]", r.roomID, id, frameID)
 	return true
 }
 
@@ -129,13 +252,16 @@ func (r *Room) OnMessage(conn *network.Conn, msg network.Packet) bool {
 		id:  id,
 		msg: msg,
 	}
-	r.msgQ <- p
+	if !r.msgQ.push(p) {
+		log4go.Error("[room(%d)] OnMessage message queue is full, disconnect %d", r.roomID, id)
+		return false
+	}
 	return true
 }
 
 // OnClose network.Conn callback
 func (r *Room) OnClose(conn *network.Conn) {
-	r.outChan <- conn
+	r.connQ.push(connEvent{conn: conn})
 	id, ok := conn.GetExtraData().(uint64)
 	if !ok {
 		log4go.Warn("[room(%d)] OnClose no id", r.roomID)
@@ -144,70 +270,225 @@ func (r *Room) OnClose(conn *network.Conn) {
 	log4go.Warn("[room(%d)] OnClose %d", r.roomID, id)
 }
 
-// Stop force stop
+// QueueStats returns the statistics of the message queue
+func (r *Room) QueueStats() QueueStats {
+	stats := r.msgQ.stats()
+	stats.PendingConns = r.connQ.len()
+	return stats
+}
+
+// Cancel cancels the room if its game has not been started yet
+func (r *Room) Cancel() error {
+	reply := make(chan error, 1)
+	select {
+	case r.cancelChan <- reply:
+		return <-reply
+	case <-r.doneChan:
+		return ErrRoomClosed
+	}
+}
+
+// AssignSeat assigns the seat to the player while the game is ready or gaming, see game.Game.AssignSeat.
+// It returns the id of the player replaced, or 0 if the seat is added
+func (r *Room) AssignSeat(seat int32, pid uint64) (uint64, error) {
+	req := &seatRequest{seat: seat, pid: pid, reply: make(chan error, 1)}
+	select {
+	case r.seatChan <- req:
+		err := <-req.reply
+		return req.replaced, err
+	case <-r.doneChan:
+		return 0, ErrRoomClosed
+	}
+}
+
+// Stop force stop and waits for the main loop to quit if it is running,
+// it is safe to call it more than once
 func (r *Room) Stop() {
-	close(r.exitChan)
-	r.wg.Wait()
+	r.stopOnce.Do(func() {
+		close(r.exitChan)
+	})
+	if atomic.LoadInt32(&r.running) != 0 {
+		<-r.doneChan
+	}
 }
 
-// Run is the main loop
+// Run is the main loop, it can only be run once
 func (r *Room) Run() {
-	r.wg.Add(1)
-	defer r.wg.Done()
+	if !atomic.CompareAndSwapInt32(&r.running, 0, 1) {
+		return
+	}
+	defer close(r.doneChan)
 	defer func() {
+		atomic.StoreInt32(&r.closeFlag, 1)
+		r.report()
 		r.g.Cleanup()
-		log4go.Warn("[room(%d)] quit! total time=[%d]", r.roomID, time.Now().Unix()-r.timeStamp)
+		log4go.Warn("[room(%d)] quit! reason=[%s] total time=[%d]", r.roomID, r.reason,
+			r.clock.Now().Unix()-r.timeStamp)
 	}()
 
-	tickerTick := time.NewTicker(TickTimer)
+	tickerTick := r.clock.NewTicker(TickTimer)
 	defer tickerTick.Stop()
 
-	timeoutTimer := time.NewTimer(TimeoutTime)
+	timeoutTimer := r.clock.NewTimer(TimeoutTime)
+	defer timeoutTimer.Stop()
 	log4go.Info("[room(%d)] running...", r.roomID)
 
 LOOP:
 	for {
 		select {
 		case <-r.exitChan:
+			r.reason = ReasonStopped
 			log4go.Error("[room(%d)] force exit", r.roomID)
 			return
-		case c := <-r.inChan:
-			id, ok := c.GetExtraData().(uint64)
-			if !ok {
-				c.Close()
-				log4go.Error("[room(%d)] inChan don't have id", r.roomID)
-				continue
-			}
-			if r.g.JoinGame(id, c) {
-				log4go.Info("[room(%d)] player[%d] join room ok", r.roomID, id)
-			} else {
-				log4go.Error("[room(%d)] player[%d] join room failed", r.roomID, id)
-				c.Close()
-			}
-		case c := <-r.outChan:
-			id, ok := c.GetExtraData().(uint64)
-			if !ok {
-				c.Close()
-				log4go.Error("[room(%d)] outChan don't have id", r.roomID)
+		case reply := <-r.cancelChan:
+			if r.started {
+				reply <- ErrRoomStarted
 				continue
 			}
-			r.g.LeaveGame(id)
-		case <-tickerTick.C:
-			if !r.g.Tick(time.Now().Unix()) {
-				log4go.Info("[room(%d)] tick over", r.roomID)
+			reply <- nil
+			r.reason = ReasonCancelled
+			log4go.Warn("[room(%d)] cancelled", r.roomID)
+			break LOOP
+		case req := <-r.seatChan:
+			var err error
+			req.replaced, err = r.assignSeat(req.seat, req.pid)
+			req.reply <- err
+		case <-r.connQ.notify:
+			r.handleConnEvents()
+		case <-tickerTick.C():
+			if !r.tick() {
 				break LOOP
 			}
-		case <-timeoutTimer.C:
+		case <-timeoutTimer.C():
+			r.reason = ReasonTimeout
 			log4go.Error("[room(%d)] time out", r.roomID)
 			break LOOP
-		case msg := <-r.msgQ:
-			r.g.ProcessMsg(msg.id, msg.msg.(*pb_packet.Packet))
+		case <-r.msgQ.notify:
+			r.handleMessages()
 		}
 	}
 
+	atomic.StoreInt32(&r.closeFlag, 1)
 	r.g.Close()
+	if !r.joined {
+		// nobody is there to receive the close message
+		return
+	}
 	for i := 3; i > 0; i-- {
-		<-time.After(time.Second)
+		<-r.clock.After(time.Second)
 		log4go.Info("[room(%d)] quiting %d...", r.roomID, i)
 	}
 }
+
+// tick drives the game by one frame, it returns false if the room should quit
+func (r *Room) tick() bool {
+	if r.isIdle() {
+		r.reason = ReasonNoShow
+		log4go.Warn("[room(%d)] reaped, nobody connected in %s", r.roomID, r.config.IdleTimeout)
+		return false
+	}
+	if !r.g.Tick(r.clock.Now().Unix()) {
+		if !r.started {
+			r.reason = ReasonNoShow
+		}
+		log4go.Info("[room(%d)] tick over", r.roomID)
+		return false
+	}
+	return true
+}
+
+// assignSeat assigns the seat in the game and updates the players of the room
+func (r *Room) assignSeat(seat int32, pid uint64) (uint64, error) {
+	replaced, err := r.g.AssignSeat(seat, pid)
+	if err != nil {
+		log4go.Error("[room(%d)] assign seat[%d] to player[%d] error: %v", r.roomID, seat, pid, err)
+		return 0, err
+	}
+
+	r.mu.Lock()
+	if int(seat) > len(r.players) {
+		r.players = append(r.players, pid)
+	} else {
+		r.players[seat-1] = pid
+	}
+	r.mu.Unlock()
+	log4go.Warn("[room(%d)] seat[%d] assigned to player[%d] replaced=[%d]", r.roomID, seat, pid, replaced)
+	return replaced, nil
+}
+
+// handleMessages handles the queued messages
+func (r *Room) handleMessages() {
+	// handle the joins first, so that the messages are never handled before their senders join
+	r.handleConnEvents()
+	for _, msg := range r.msgQ.popAll() {
+		r.g.ProcessMsg(msg.id, msg.msg.(*pb_packet.Packet))
+	}
+}
+
+// handleConnEvents handles the queued connection events
+func (r *Room) handleConnEvents() {
+	for _, e := range r.connQ.popAll() {
+		c := e.conn
+		id, ok := c.GetExtraData().(uint64)
+		if !ok {
+			c.Close()
+			if e.join {
+				log4go.Error("[room(%d)] conn event join=[%v] don't have id", r.roomID, e.join)
+			}
+			// the conn has been replaced by another one of the player otherwise
+			continue
+		}
+		if !e.join {
+			r.g.LeaveGame(id)
+			continue
+		}
+		if e.resume != nil {
+			if r.g.ResumeGame(id, c, e.resume.ticket, e.resume.frameID, r.IssueTicket(id)) {
+				log4go.Info("[room(%d)] player[%d] resume ok", r.roomID, id)
+			} else {
+				log4go.Error("[room(%d)] player[%d] resume failed", r.roomID, id)
+				c.Close()
+			}
+			continue
+		}
+		if r.g.JoinGame(id, c, r.IssueTicket(id)) {
+			log4go.Info("[room(%d)] player[%d] join room ok", r.roomID, id)
+		} else {
+			log4go.Error("[room(%d)] player[%d] join room failed", r.roomID, id)
+			c.Close()
+		}
+	}
+}
+
+// isIdle checks if the room should be reaped because nobody has connected to it
+func (r *Room) isIdle() bool {
+	if r.config.IdleTimeout <= 0 || r.joined {
+		return false
+	}
+	return r.clock.Now().Sub(r.createTime) >= r.config.IdleTimeout
+}
+
+// report reports the result of the room
+func (r *Room) report() {
+	if r.config.Reporter == nil {
+		return
+	}
+	result := &Result{
+		RoomID:     r.roomID,
+		TypeID:     r.typeID,
+		Players:    r.Players(),
+		Reason:     r.reason,
+		Winners:    make(map[uint64]uint64),
+		Frames:     r.g.FrameCount(),
+		Violations: r.g.Violations(),
+		Timing:     r.g.Timing(),
+		Winner:     r.g.Winner(),
+		Desyncs:    r.g.Desyncs(),
+		CreateTime: r.timeStamp,
+		EndTime:    r.clock.Now().Unix(),
+	}
+	for pid, winner := range r.g.Result() {
+		result.Winners[pid] = winner
+	}
+	r.config.Reporter.ReportResult(result)
+}
@@ -0,0 +1,253 @@
+package room
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/hedon954/go-lock-step-server/logic/game"
+	"github.com/hedon954/go-lock-step-server/pb"
+	"github.com/hedon954/go-lock-step-server/pkg/network"
+	"github.com/hedon954/go-lock-step-server/pkg/packet/pb_packet"
+	"google.golang.org/protobuf/proto"
+)
+
+// newTestConn creates a connection of the player which is never started
+func newTestConn(t *testing.T, pid uint64) *network.Conn {
+	c1, c2 := net.Pipe()
+	t.Cleanup(func() {
+		c1.Close()
+		c2.Close()
+	})
+	srv := network.NewServer(&network.Config{}, nil, &pb_packet.MsgProtocol{})
+	conn := network.NewConn(c1, srv)
+	conn.PutExtraData(pid)
+	return conn
+}
+
+// Test_StalledRoom checks that a room whose main loop is stalled never blocks the network goroutines
+func Test_StalledRoom(t *testing.T) {
+	tests := []struct {
+		policy      OverflowPolicy
+		disconnects int
+	}{
+		{OverflowDropOldest, 0},
+		{OverflowDropNewest, 0},
+		{OverflowDisconnect, 100},
+	}
+
+	for _, tt := range tests {
+		// the room is never run
+		r := NewRoom(1, 0, []uint64{1, 2}, 0, "test", &Config{
+			QueueSize:      16,
+			OverflowPolicy: tt.policy,
+		})
+		conn := newTestConn(t, 1)
+		other := newTestConn(t, 2)
+		input := pb_packet.NewPacket(uint8(pb.ID_MSG_Input), nil)
+
+		done := make(chan int)
+		go func() {
+			disconnects := 0
+			for i := 0; i < 100; i++ {
+				r.OnConnect(other)
+				r.OnClose(other)
+			}
+			for i := 0; i < 116; i++ {
+				if !r.OnMessage(conn, input) {
+					disconnects++
+				}
+			}
+			done <- disconnects
+		}()
+
+		select {
+		case disconnects := <-done:
+			if disconnects != tt.disconnects {
+				t.Errorf("policy[%d] disconnects[%d] should be [%d]", tt.policy, disconnects, tt.disconnects)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("policy[%d] the callbacks are blocked by the stalled room", tt.policy)
+		}
+
+		stats := r.QueueStats()
+		if stats.Depth != 16 || stats.MaxDepth != 16 || stats.Dropped != 100 {
+			t.Errorf("policy[%d] unexpected stats: %+v", tt.policy, stats)
+		}
+		if stats.PendingConns == 0 {
+			t.Errorf("policy[%d] the connection events should be pending", tt.policy)
+		}
+	}
+}
+
+func Test_RoomFrames(t *testing.T) {
+	h := newHarness(t, []uint64{1, 2}, nil)
+	h.start(1, 2)
+
+	// the input is broadcast in the next frame
+	h.send(1, pb.ID_MSG_Input, &pb.C2S_InputMsg{Sid: proto.Int32(1), X: proto.Int32(2), Y: proto.Int32(3)})
+	h.steps(1)
+	for _, pid := range []uint64{1, 2} {
+		frames := h.frames(pid)
+		if len(frames) != 1 || len(frames[0].GetInput()) != 1 {
+			t.Fatalf("player[%d] received frames: %v", pid, frames)
+		}
+		in := frames[0].GetInput()[0]
+		if in.GetId() != 1 || in.GetSid() != 1 || in.GetX() != 2 || in.GetY() != 3 || in.GetRoomseatid() != 1 {
+			t.Errorf("player[%d] received input: %v", pid, in)
+		}
+	}
+
+	// the empty frames are broadcast every BroadcastOffsetFrames
+	h.steps(game.BroadcastOffsetFrames - 1)
+	if frames := h.frames(1); len(frames) != 0 {
+		t.Fatalf("received frames: %v", frames)
+	}
+	h.steps(1)
+	if frames := h.frames(1); len(frames) != 1 || frames[0].GetFrameID() != game.BroadcastOffsetFrames {
+		t.Fatalf("received frames: %v", frames)
+	}
+
+	// the game is over once the players online have reported the result
+	h.disconnect(2)
+	h.send(1, pb.ID_MSG_Result, &pb.C2S_ResultMsg{WinnerID: proto.Uint64(1)})
+	h.steps(1)
+	if ids := h.receivedIDs(1); len(ids) != 1 || ids[0] != pb.ID_MSG_Result {
+		t.Fatalf("received: %v", ids)
+	}
+	h.steps(1)
+	if !h.r.IsOver() {
+		t.Fatal("the room should be over")
+	}
+	if h.step() {
+		t.Fatal("the room should quit")
+	}
+	if h.r.reason != ReasonFinished {
+		t.Errorf("reason: %s", h.r.reason)
+	}
+}
+
+func Test_RoomHeartbeat(t *testing.T) {
+	h := newHarness(t, []uint64{1, 2}, nil)
+	h.start(1, 2)
+
+	// only player 1 keeps sending the heartbeats
+	for i := 0; i < 3; i++ {
+		h.send(1, pb.ID_MSG_Heartbeat, nil)
+		h.steps(HeatbeatFrequency)
+	}
+	frames1 := h.frames(1)
+	frames2 := h.frames(2)
+	if len(frames2) == 0 || len(frames2) >= len(frames1) {
+		t.Fatalf("player 2 should stop receiving frames, received %d/%d", len(frames2), len(frames1))
+	}
+	last := frames1[len(frames1)-1].GetFrameID()
+
+	// player 2 catches up the frames with a heartbeat
+	h.send(2, pb.ID_MSG_Heartbeat, nil)
+	h.steps(game.BroadcastOffsetFrames)
+	ids := h.receivedIDs(2)
+	if len(ids) < 2 || ids[0] != pb.ID_MSG_Heartbeat {
+		t.Fatalf("received: %v", ids)
+	}
+	h.steps(game.BroadcastOffsetFrames)
+	frames2 = h.frames(2)
+	frames1 = h.frames(1)
+	if len(frames2) == 0 || frames2[len(frames2)-1].GetFrameID() != frames1[len(frames1)-1].GetFrameID() ||
+		frames1[len(frames1)-1].GetFrameID() <= last {
+		t.Errorf("player 2 should catch up the frames")
+	}
+}
+
+func Test_RoomReadyTimeout(t *testing.T) {
+	h := newHarness(t, []uint64{1, 2}, nil)
+	h.connect(1)
+
+	n := 0
+	for !h.r.started {
+		h.steps(1)
+		n++
+	}
+	// the game is forced to start after game.MaxReadyTime
+	if ready := int(time.Duration(game.MaxReadyTime) * time.Second / TickTimer); n < ready || n > ready+1 {
+		t.Errorf("started at step %d, want %d", n, ready)
+	}
+	if ids := h.receivedIDs(1); len(ids) != 2 || ids[0] != pb.ID_MSG_Connect || ids[1] != pb.ID_MSG_Start {
+		t.Errorf("received: %v", ids)
+	}
+}
+
+func Test_RoomIdle(t *testing.T) {
+	h := newHarness(t, []uint64{1, 2}, &Config{IdleTimeout: time.Second})
+
+	n := 1
+	for h.step() {
+		n++
+	}
+	// the room is reaped at the first tick after the idle timeout
+	if want := int(time.Second/TickTimer) + 1; n != want {
+		t.Errorf("reaped at step %d, want %d", n, want)
+	}
+	if h.r.reason != ReasonNoShow {
+		t.Errorf("reason: %s", h.r.reason)
+	}
+}
+
+func Test_RoomAssignSeat(t *testing.T) {
+	h := newHarness(t, []uint64{1, 2}, nil)
+	h.start(1, 2)
+	h.disconnect(2)
+	h.send(1, pb.ID_MSG_Input, &pb.C2S_InputMsg{Sid: proto.Int32(1)})
+	h.steps(game.BroadcastOffsetFrames)
+	h.received(1)
+
+	tests := []struct {
+		seat     int32
+		pid      uint64
+		replaced uint64
+		err      error
+	}{
+		{1, 3, 0, game.ErrSeatOccupied},
+		{2, 1, 0, game.ErrPlayerExists},
+		{4, 3, 0, game.ErrSeatInvalid},
+		{2, 3, 2, nil},
+		{3, 4, 0, nil},
+	}
+	for _, tt := range tests {
+		replaced, err := h.r.assignSeat(tt.seat, tt.pid)
+		if replaced != tt.replaced || err != tt.err {
+			t.Errorf("seat[%d] pid[%d] want: (%d, %v), got: (%d, %v)", tt.seat, tt.pid, tt.replaced, tt.err,
+				replaced, err)
+		}
+	}
+	if players := h.r.Players(); len(players) != 3 || players[0] != 1 || players[1] != 3 || players[2] != 4 {
+		t.Errorf("players: %v", players)
+	}
+
+	// the others are notified of the roster changes
+	var rosters []*pb.S2C_RosterMsg
+	for _, p := range h.received(1) {
+		msg := &pb.S2C_RosterMsg{}
+		if pb.ID(p.GetMessageID()) != pb.ID_MSG_Roster || p.UnmarshalPB(msg) != nil {
+			t.Fatalf("received: %d", p.GetMessageID())
+		}
+		rosters = append(rosters, msg)
+	}
+	if len(rosters) != 2 || rosters[0].GetRoomseatid() != 2 || rosters[0].GetId() != 3 ||
+		rosters[0].GetReplaced() != 2 || rosters[1].GetRoomseatid() != 3 || rosters[1].GetReplaced() != 0 {
+		t.Errorf("rosters: %v", rosters)
+	}
+
+	// the new player catches up all the frames
+	h.steps(game.BroadcastOffsetFrames)
+	h.connect(3)
+	h.steps(game.BroadcastOffsetFrames)
+	frames1 := h.frames(1)
+	frames3 := h.frames(3)
+	if len(frames3) < 2 || len(frames3[0].GetInput()) != 1 || frames3[0].GetInput()[0].GetId() != 1 {
+		t.Fatalf("player 3 received frames: %v", frames3)
+	}
+	if frames3[len(frames3)-1].GetFrameID() != frames1[len(frames1)-1].GetFrameID() {
+		t.Errorf("player 3 should catch up the frames")
+	}
+}
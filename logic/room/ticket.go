@@ -0,0 +1,114 @@
+package room
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"time"
+)
+
+const (
+	// DefaultResumeTTL is how long a player can resume the session once the conn is lost if Config.ResumeTTL is 0
+	DefaultResumeTTL = time.Minute
+
+	kTicketMACLen = 16
+	kTicketLen    = 8 + 8 + 8 + 8 + kTicketMACLen // room id, player id, issue time, nonce, mac
+)
+
+var (
+	ErrTicketInvalid = errors.New("resume ticket is invalid")
+	ErrTicketRoom    = errors.New("resume ticket is not issued by the room")
+)
+
+/*
+
+resume ticket, encoded with base64.RawURLEncoding
+
+|--roomID(uint64)--|--playerID(uint64)--|--issued(int64, unix nano)--|--nonce(uint64)--|--mac(hmac-sha256[:16])--|
+
+*/
+
+// newSecretKey returns a random key signing the resume tickets of a room
+func newSecretKey() string {
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		panic(err)
+	}
+	return hex.EncodeToString(key)
+}
+
+// ParseTicket returns the room and the player of the resume ticket without verifying it
+func ParseTicket(ticket string) (roomID uint64, playerID uint64, err error) {
+	buff, err := base64.RawURLEncoding.DecodeString(ticket)
+	if err != nil || len(buff) != kTicketLen {
+		return 0, 0, ErrTicketInvalid
+	}
+	return binary.BigEndian.Uint64(buff), binary.BigEndian.Uint64(buff[8:]), nil
+}
+
+// resumeTTL returns how long a player can resume the session once the conn is lost,
+// resumption is disabled if it is negative
+func (r *Room) resumeTTL() time.Duration {
+	if r.config.ResumeTTL == 0 {
+		return DefaultResumeTTL
+	}
+	return r.config.ResumeTTL
+}
+
+// IssueTicket issues a resume ticket of the player,
+// it returns an empty string if resumption is disabled
+func (r *Room) IssueTicket(pid uint64) string {
+	if r.resumeTTL() < 0 {
+		return ""
+	}
+
+	buff := make([]byte, kTicketLen-kTicketMACLen, kTicketLen)
+	binary.BigEndian.PutUint64(buff, r.roomID)
+	binary.BigEndian.PutUint64(buff[8:], pid)
+	binary.BigEndian.PutUint64(buff[16:], uint64(r.clock.Now().UnixNano()))
+	if _, err := rand.Read(buff[24:32]); err != nil {
+		panic(err)
+	}
+	buff = append(buff, r.signTicket(buff)...)
+	return base64.RawURLEncoding.EncodeToString(buff)
+}
+
+// VerifyTicket verifies the signature of the resume ticket and returns its player, the game checks
+// the ticket has not been replaced and the player has not been offline for longer than the ttl when they resume
+func (r *Room) VerifyTicket(ticket string) (uint64, error) {
+	buff, err := base64.RawURLEncoding.DecodeString(ticket)
+	if err != nil || len(buff) != kTicketLen {
+		return 0, ErrTicketInvalid
+	}
+	body, mac := buff[:kTicketLen-kTicketMACLen], buff[kTicketLen-kTicketMACLen:]
+	if binary.BigEndian.Uint64(body) != r.roomID {
+		return 0, ErrTicketRoom
+	}
+	if !hmac.Equal(mac, r.signTicket(body)) {
+		return 0, ErrTicketInvalid
+	}
+	return binary.BigEndian.Uint64(body[8:]), nil
+}
+
+func (r *Room) signTicket(body []byte) []byte {
+	h := hmac.New(sha256.New, []byte(r.secretKey))
+	h.Write(body)
+	return h.Sum(nil)[:kTicketMACLen]
+}
+
+// PlayerToken returns the token of the player connecting to the room, it is signed by the secret key of the room
+func (r *Room) PlayerToken(pid uint64) string {
+	buff := make([]byte, 16)
+	binary.BigEndian.PutUint64(buff, r.roomID)
+	binary.BigEndian.PutUint64(buff[8:], pid)
+	return base64.RawURLEncoding.EncodeToString(r.signTicket(append([]byte("token"), buff...)))
+}
+
+// VerifyToken checks the token is issued by PlayerToken of the room for the player
+func (r *Room) VerifyToken(pid uint64, token string) bool {
+	return hmac.Equal([]byte(token), []byte(r.PlayerToken(pid)))
+}
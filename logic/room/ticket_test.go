@@ -0,0 +1,190 @@
+package room
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/hedon954/go-lock-step-server/pb"
+	"github.com/hedon954/go-lock-step-server/pkg/clock"
+	"github.com/hedon954/go-lock-step-server/pkg/network"
+	"github.com/hedon954/go-lock-step-server/pkg/packet/pb_packet"
+)
+
+func Test_Ticket(t *testing.T) {
+	r := NewRoom(1, 0, []uint64{1, 2}, 0, "test", nil)
+	ticket := r.IssueTicket(2)
+
+	roomID, pid, err := ParseTicket(ticket)
+	if err != nil || roomID != 1 || pid != 2 {
+		t.Fatalf("ParseTicket got: (%d, %d, %v)", roomID, pid, err)
+	}
+	if pid, err = r.VerifyTicket(ticket); err != nil || pid != 2 {
+		t.Fatalf("VerifyTicket got: (%d, %v)", pid, err)
+	}
+	if r.IssueTicket(2) == ticket {
+		t.Error("the tickets issued should be different")
+	}
+
+	// the tickets of another room with the same id are not accepted
+	other := NewRoom(1, 0, []uint64{1, 2}, 0, "test", nil)
+	if _, err = other.VerifyTicket(ticket); err != ErrTicketInvalid {
+		t.Errorf("want: %v, got: %v", ErrTicketInvalid, err)
+	}
+	other = NewRoom(2, 0, []uint64{1, 2}, 0, "test", nil)
+	if _, err = other.VerifyTicket(ticket); err != ErrTicketRoom {
+		t.Errorf("want: %v, got: %v", ErrTicketRoom, err)
+	}
+
+	for _, bad := range []string{"", "!", ticket[:len(ticket)-1], ticket[:10] + "A" + ticket[11:]} {
+		if bad == ticket {
+			continue
+		}
+		if _, err = r.VerifyTicket(bad); err == nil {
+			t.Errorf("ticket[%s] should be rejected", bad)
+		}
+	}
+
+	// the ttl is measured from the disconnect by the game, so an old ticket is still verified
+	fake := clock.NewFake(time.Now())
+	old := NewRoom(1, 0, []uint64{1, 2}, 0, "test", &Config{ResumeTTL: time.Minute, Clock: fake})
+	ticket = old.IssueTicket(1)
+	fake.Advance(time.Hour)
+	if _, err = old.VerifyTicket(ticket); err != nil {
+		t.Errorf("old ticket got: %v", err)
+	}
+
+	disabled := NewRoom(1, 0, []uint64{1, 2}, 0, "test", &Config{ResumeTTL: -1})
+	if ticket = disabled.IssueTicket(1); ticket != "" {
+		t.Errorf("resumption is disabled, got ticket[%s]", ticket)
+	}
+}
+
+// dialRoom connects the player to the running room like the router, and returns the client side of the connection
+func dialRoom(t *testing.T, r *Room, pid uint64, ticket string) net.Conn {
+	c1, c2 := net.Pipe()
+	t.Cleanup(func() {
+		c1.Close()
+		c2.Close()
+	})
+	srv := network.NewServer(&network.Config{
+		PacketSendChanLimit:    16,
+		PacketReceiveChanLimit: 16,
+		ConnReadTimeout:        time.Minute,
+		ConnWriteTimeout:       time.Minute,
+	}, r, &pb_packet.MsgProtocol{})
+	conn := network.NewConn(c1, srv)
+	conn.PutExtraData(pid)
+	if ticket != "" {
+		if !r.OnResume(conn, ticket, 0) {
+			t.Fatal("OnResume failed")
+		}
+		conn.SetCallback(&resumeCallback{r})
+	}
+	conn.Do()
+	return c2
+}
+
+// resumeCallback skips OnConnect as the conn has resumed
+type resumeCallback struct {
+	*Room
+}
+
+func (c *resumeCallback) OnConnect(*network.Conn) bool {
+	return true
+}
+
+func readConnectReply(t *testing.T, c net.Conn) *pb.S2C_ConnectMsg {
+	_ = c.SetReadDeadline(time.Now().Add(time.Second))
+	p, err := pb_packet.NewClientProtocol(nil).ReadPacket(c)
+	if err != nil {
+		t.Fatalf("read error: %v", err)
+	}
+	msg := &pb.S2C_ConnectMsg{}
+	if err = p.(*pb_packet.Packet).UnmarshalPB(msg); err != nil {
+		t.Fatal(err)
+	}
+	return msg
+}
+
+func Test_Resume(t *testing.T) {
+	r := NewRoom(1, 0, []uint64{1, 2}, 0, "test", nil)
+	go r.Run()
+	defer r.Stop()
+
+	first := dialRoom(t, r, 1, "")
+	ret := readConnectReply(t, first)
+	if ret.GetErrorCode() != pb.ERRORCODE_ERR_ok || ret.GetResumeTicket() == "" {
+		t.Fatalf("connect got: %v", ret)
+	}
+	ticket := ret.GetResumeTicket()
+
+	// the new conn replaces the previous one with the ticket
+	second := dialRoom(t, r, 1, ticket)
+	ret = readConnectReply(t, second)
+	if ret.GetErrorCode() != pb.ERRORCODE_ERR_ok || ret.GetResumeTicket() == "" || ret.GetResumeTicket() == ticket {
+		t.Fatalf("resume got: %v", ret)
+	}
+	_ = first.SetReadDeadline(time.Now().Add(time.Second))
+	if _, err := first.Read(make([]byte, 1)); err == nil {
+		t.Error("the replaced conn should be closed")
+	}
+
+	// the replaced ticket is not accepted
+	third := dialRoom(t, r, 1, ticket)
+	if ret = readConnectReply(t, third); ret.GetErrorCode() != pb.ERRORCODE_ERR_Ticket {
+		t.Errorf("resume with the replaced ticket got: %v", ret)
+	}
+}
+
+// connectReply returns the reply of MSG_Connect the player has received since the last call
+func (h *harness) connectReply(pid uint64) *pb.S2C_ConnectMsg {
+	for _, p := range h.received(pid) {
+		if pb.ID(p.GetMessageID()) != pb.ID_MSG_Connect {
+			continue
+		}
+		msg := &pb.S2C_ConnectMsg{}
+		if err := p.UnmarshalPB(msg); err != nil {
+			h.t.Fatal(err)
+		}
+		return msg
+	}
+	h.t.Fatalf("player[%d] MSG_Connect is not received", pid)
+	return nil
+}
+
+func Test_ResumeTTL(t *testing.T) {
+	ttl := time.Second
+	h := newHarness(t, []uint64{1, 2}, &Config{ResumeTTL: ttl})
+	h.connect(1)
+	h.connect(2)
+	h.steps(1)
+	ticket := h.connectReply(1).GetResumeTicket()
+
+	// the game runs longer than the ttl while the player is connected
+	for i := 0; i < 3; i++ {
+		h.send(1, pb.ID_MSG_Heartbeat, nil)
+		h.send(2, pb.ID_MSG_Heartbeat, nil)
+		h.steps(int(ttl / TickTimer))
+	}
+
+	// the player resumes within the ttl after the disconnect
+	h.disconnect(1)
+	h.steps(int(ttl/TickTimer) - 1)
+	h.resume(1, ticket, 0)
+	h.steps(1)
+	ret := h.connectReply(1)
+	if ret.GetErrorCode() != pb.ERRORCODE_ERR_ok {
+		t.Fatalf("resume after %v of play got: %v", 3*ttl, ret)
+	}
+	ticket = ret.GetResumeTicket()
+
+	// the ticket expires once the player has been offline for longer than the ttl
+	h.disconnect(1)
+	h.steps(int(ttl/TickTimer) + 1)
+	h.resume(1, ticket, 0)
+	h.steps(1)
+	if ret = h.connectReply(1); ret.GetErrorCode() != pb.ERRORCODE_ERR_Ticket {
+		t.Errorf("resume after the ttl got: %v", ret)
+	}
+}
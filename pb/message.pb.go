@@ -29,11 +29,14 @@ const (
 	ID_MSG_Heartbeat ID = 2 // heartbeat (send a heartbeat packet every 1 second after the server returns Connect successfully)
 	ID_MSG_JoinRoom  ID = 10
 	ID_MSG_Progress  ID = 20
+	ID_MSG_Loadout   ID = 25 // pre-game choices of a seat, accepted until the game starts
 	ID_MSG_Ready     ID = 30
 	ID_MSG_Start     ID = 40
 	ID_MSG_Frame     ID = 50 // frame data
 	ID_MSG_Input     ID = 60
+	ID_MSG_Checksum  ID = 65 // the state hash of a frame, checked against the simulation of the server
 	ID_MSG_Result    ID = 70
+	ID_MSG_Roster    ID = 80  // a seat is assigned to another player
 	ID_MSG_Close     ID = 100 // close romm
 	ID_MSG_END       ID = 255
 )
@@ -46,11 +49,14 @@ var (
 		2:   "MSG_Heartbeat",
 		10:  "MSG_JoinRoom",
 		20:  "MSG_Progress",
+		25:  "MSG_Loadout",
 		30:  "MSG_Ready",
 		40:  "MSG_Start",
 		50:  "MSG_Frame",
 		60:  "MSG_Input",
+		65:  "MSG_Checksum",
 		70:  "MSG_Result",
+		80:  "MSG_Roster",
 		100: "MSG_Close",
 		255: "MSG_END",
 	}
@@ -60,11 +66,14 @@ var (
 		"MSG_Heartbeat": 2,
 		"MSG_JoinRoom":  10,
 		"MSG_Progress":  20,
+		"MSG_Loadout":   25,
 		"MSG_Ready":     30,
 		"MSG_Start":     40,
 		"MSG_Frame":     50,
 		"MSG_Input":     60,
+		"MSG_Checksum":  65,
 		"MSG_Result":    70,
+		"MSG_Roster":    80,
 		"MSG_Close":     100,
 		"MSG_END":       255,
 	}
@@ -106,6 +115,11 @@ const (
 	ERRORCODE_ERR_NoRoom    ERRORCODE = 2 // room not exists
 	ERRORCODE_ERR_RoomState ERRORCODE = 3 // room state error
 	ERRORCODE_ERR_Token     ERRORCODE = 4 // token invalied
+	ERRORCODE_ERR_Redirect  ERRORCODE = 5 // room is owned by another node, reconnect to the address in S2C_ConnectMsg
+	ERRORCODE_ERR_Version   ERRORCODE = 6 // client is outdated, upgrade it to a protocol version supported by the server
+	ERRORCODE_ERR_Checksum  ERRORCODE = 7 // the packet checksum requested is weaker than the one required by the server
+	ERRORCODE_ERR_Ticket    ERRORCODE = 8 // the resume ticket is invalid, expired or replaced, connect with the token instead
+	ERRORCODE_ERR_Kicked    ERRORCODE = 9 // the player has been kicked out of the room for the invalid inputs
 )
 
 // Enum value maps for ERRORCODE.
@@ -116,6 +130,11 @@ var (
 		2: "ERR_NoRoom",
 		3: "ERR_RoomState",
 		4: "ERR_Token",
+		5: "ERR_Redirect",
+		6: "ERR_Version",
+		7: "ERR_Checksum",
+		8: "ERR_Ticket",
+		9: "ERR_Kicked",
 	}
 	ERRORCODE_value = map[string]int32{
 		"ERR_ok":        0,
@@ -123,6 +142,11 @@ var (
 		"ERR_NoRoom":    2,
 		"ERR_RoomState": 3,
 		"ERR_Token":     4,
+		"ERR_Redirect":  5,
+		"ERR_Version":   6,
+		"ERR_Checksum":  7,
+		"ERR_Ticket":    8,
+		"ERR_Kicked":    9,
 	}
 )
 
@@ -153,15 +177,71 @@ func (ERRORCODE) EnumDescriptor() ([]byte, []int) {
 	return file_message_proto_rawDescGZIP(), []int{1}
 }
 
+// packet checksum, it is applied to the packets after MSG_Connect,
+// it detects the corrupted, dropped or replayed packets, it is not an authentication of the packets
+type CHECKSUM int32
+
+const (
+	CHECKSUM_CHK_None  CHECKSUM = 0
+	CHECKSUM_CHK_CRC32 CHECKSUM = 1 // crc32 of the packet and its sequence number
+	CHECKSUM_CHK_HMAC  CHECKSUM = 2 // hmac-sha256 of the packet and its sequence number, keyed by the token sent in clear in MSG_Connect
+)
+
+// Enum value maps for CHECKSUM.
+var (
+	CHECKSUM_name = map[int32]string{
+		0: "CHK_None",
+		1: "CHK_CRC32",
+		2: "CHK_HMAC",
+	}
+	CHECKSUM_value = map[string]int32{
+		"CHK_None":  0,
+		"CHK_CRC32": 1,
+		"CHK_HMAC":  2,
+	}
+)
+
+func (x CHECKSUM) Enum() *CHECKSUM {
+	p := new(CHECKSUM)
+	*p = x
+	return p
+}
+
+func (x CHECKSUM) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (CHECKSUM) Descriptor() protoreflect.EnumDescriptor {
+	return file_message_proto_enumTypes[2].Descriptor()
+}
+
+func (CHECKSUM) Type() protoreflect.EnumType {
+	return &file_message_proto_enumTypes[2]
+}
+
+func (x CHECKSUM) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use CHECKSUM.Descriptor instead.
+func (CHECKSUM) EnumDescriptor() ([]byte, []int) {
+	return file_message_proto_rawDescGZIP(), []int{2}
+}
+
 // the first message sent by client
 type C2S_ConnectMsg struct {
 	state         protoimpl.MessageState
 	sizeCache     protoimpl.SizeCache
 	unknownFields protoimpl.UnknownFields
 
-	PlayerID *uint64 `protobuf:"varint,1,opt,name=playerID,proto3,oneof" json:"playerID,omitempty"`
-	BattleID *uint64 `protobuf:"varint,2,opt,name=battleID,proto3,oneof" json:"battleID,omitempty"`
-	Token    *string `protobuf:"bytes,3,opt,name=token,proto3,oneof" json:"token,omitempty"`
+	PlayerID        *uint64   `protobuf:"varint,1,opt,name=playerID,proto3,oneof" json:"playerID,omitempty"`
+	BattleID        *uint64   `protobuf:"varint,2,opt,name=battleID,proto3,oneof" json:"battleID,omitempty"`
+	Token           *string   `protobuf:"bytes,3,opt,name=token,proto3,oneof" json:"token,omitempty"`
+	ProtocolVersion *uint32   `protobuf:"varint,4,opt,name=protocolVersion,proto3,oneof" json:"protocolVersion,omitempty"`    // the highest protocol version supported by the client, 0 means 1
+	ClientVersion   *string   `protobuf:"bytes,5,opt,name=clientVersion,proto3,oneof" json:"clientVersion,omitempty"`         // the client version, like 1.2.3
+	Checksum        *CHECKSUM `protobuf:"varint,6,opt,name=checksum,proto3,enum=pb.CHECKSUM,oneof" json:"checksum,omitempty"` // the packet checksum requested by the client
+	ResumeTicket    *string   `protobuf:"bytes,7,opt,name=resumeTicket,proto3,oneof" json:"resumeTicket,omitempty"`           // resume the session with the ticket instead of playerID, battleID and token
+	FrameID         *uint32   `protobuf:"varint,8,opt,name=frameID,proto3,oneof" json:"frameID,omitempty"`                    // the first frame the client needs when resuming
 }
 
 func (x *C2S_ConnectMsg) Reset() {
@@ -217,13 +297,52 @@ func (x *C2S_ConnectMsg) GetToken() string {
 	return ""
 }
 
+func (x *C2S_ConnectMsg) GetProtocolVersion() uint32 {
+	if x != nil && x.ProtocolVersion != nil {
+		return *x.ProtocolVersion
+	}
+	return 0
+}
+
+func (x *C2S_ConnectMsg) GetClientVersion() string {
+	if x != nil && x.ClientVersion != nil {
+		return *x.ClientVersion
+	}
+	return ""
+}
+
+func (x *C2S_ConnectMsg) GetChecksum() CHECKSUM {
+	if x != nil && x.Checksum != nil {
+		return *x.Checksum
+	}
+	return CHECKSUM_CHK_None
+}
+
+func (x *C2S_ConnectMsg) GetResumeTicket() string {
+	if x != nil && x.ResumeTicket != nil {
+		return *x.ResumeTicket
+	}
+	return ""
+}
+
+func (x *C2S_ConnectMsg) GetFrameID() uint32 {
+	if x != nil && x.FrameID != nil {
+		return *x.FrameID
+	}
+	return 0
+}
+
 // the server returns the connection result
 type S2C_ConnectMsg struct {
 	state         protoimpl.MessageState
 	sizeCache     protoimpl.SizeCache
 	unknownFields protoimpl.UnknownFields
 
-	ErrorCode *ERRORCODE `protobuf:"varint,1,opt,name=errorCode,proto3,enum=pb.ERRORCODE,oneof" json:"errorCode,omitempty"`
+	ErrorCode       *ERRORCODE `protobuf:"varint,1,opt,name=errorCode,proto3,enum=pb.ERRORCODE,oneof" json:"errorCode,omitempty"`
+	Address         *string    `protobuf:"bytes,2,opt,name=address,proto3,oneof" json:"address,omitempty"`                     // the address of the node owning the room if errorCode is ERR_Redirect
+	ProtocolVersion *uint32    `protobuf:"varint,3,opt,name=protocolVersion,proto3,oneof" json:"protocolVersion,omitempty"`    // the negotiated protocol version, or the highest one supported by the server if errorCode is ERR_Version
+	Checksum        *CHECKSUM  `protobuf:"varint,4,opt,name=checksum,proto3,enum=pb.CHECKSUM,oneof" json:"checksum,omitempty"` // the negotiated packet checksum, or the one required by the server if errorCode is ERR_Checksum
+	ResumeTicket    *string    `protobuf:"bytes,5,opt,name=resumeTicket,proto3,oneof" json:"resumeTicket,omitempty"`           // the ticket resuming the session on a new connection, it replaces the previous one
 }
 
 func (x *S2C_ConnectMsg) Reset() {
@@ -265,16 +384,45 @@ func (x *S2C_ConnectMsg) GetErrorCode() ERRORCODE {
 	return ERRORCODE_ERR_ok
 }
 
+func (x *S2C_ConnectMsg) GetAddress() string {
+	if x != nil && x.Address != nil {
+		return *x.Address
+	}
+	return ""
+}
+
+func (x *S2C_ConnectMsg) GetProtocolVersion() uint32 {
+	if x != nil && x.ProtocolVersion != nil {
+		return *x.ProtocolVersion
+	}
+	return 0
+}
+
+func (x *S2C_ConnectMsg) GetChecksum() CHECKSUM {
+	if x != nil && x.Checksum != nil {
+		return *x.Checksum
+	}
+	return CHECKSUM_CHK_None
+}
+
+func (x *S2C_ConnectMsg) GetResumeTicket() string {
+	if x != nil && x.ResumeTicket != nil {
+		return *x.ResumeTicket
+	}
+	return ""
+}
+
 // the server returns the join room result
 type S2C_JoinRoomMsg struct {
 	state         protoimpl.MessageState
 	sizeCache     protoimpl.SizeCache
 	unknownFields protoimpl.UnknownFields
 
-	Roomseatid *int32   `protobuf:"varint,1,opt,name=roomseatid,proto3,oneof" json:"roomseatid,omitempty"` // own index id (1~N)
-	Others     []uint64 `protobuf:"varint,2,rep,packed,name=others,proto3" json:"others,omitempty"`        // others' id
-	Pros       []int32  `protobuf:"varint,3,rep,packed,name=pros,proto3" json:"pros,omitempty"`            // others' progress
-	RandomSeed *int32   `protobuf:"varint,4,opt,name=randomSeed,proto3,oneof" json:"randomSeed,omitempty"` // random seed
+	Roomseatid *int32     `protobuf:"varint,1,opt,name=roomseatid,proto3,oneof" json:"roomseatid,omitempty"` // own index id (1~N)
+	Others     []uint64   `protobuf:"varint,2,rep,packed,name=others,proto3" json:"others,omitempty"`        // others' id
+	Pros       []int32    `protobuf:"varint,3,rep,packed,name=pros,proto3" json:"pros,omitempty"`            // others' progress
+	RandomSeed *int32     `protobuf:"varint,4,opt,name=randomSeed,proto3,oneof" json:"randomSeed,omitempty"` // random seed
+	Loadouts   []*Loadout `protobuf:"bytes,5,rep,name=loadouts,proto3" json:"loadouts,omitempty"`            // the loadouts submitted by the others
 }
 
 func (x *S2C_JoinRoomMsg) Reset() {
@@ -337,13 +485,21 @@ func (x *S2C_JoinRoomMsg) GetRandomSeed() int32 {
 	return 0
 }
 
+func (x *S2C_JoinRoomMsg) GetLoadouts() []*Loadout {
+	if x != nil {
+		return x.Loadouts
+	}
+	return nil
+}
+
 // the server broadcasts a start game message
 type S2C_StartMsg struct {
 	state         protoimpl.MessageState
 	sizeCache     protoimpl.SizeCache
 	unknownFields protoimpl.UnknownFields
 
-	TimeStamp *int64 `protobuf:"varint,1,opt,name=timeStamp,proto3,oneof" json:"timeStamp,omitempty"` // synchronization time stamp
+	TimeStamp *int64     `protobuf:"varint,1,opt,name=timeStamp,proto3,oneof" json:"timeStamp,omitempty"` // synchronization time stamp
+	Loadouts  []*Loadout `protobuf:"bytes,2,rep,name=loadouts,proto3" json:"loadouts,omitempty"`          // the loadouts locked at start, ordered by seat
 }
 
 func (x *S2C_StartMsg) Reset() {
@@ -385,6 +541,173 @@ func (x *S2C_StartMsg) GetTimeStamp() int64 {
 	return 0
 }
 
+func (x *S2C_StartMsg) GetLoadouts() []*Loadout {
+	if x != nil {
+		return x.Loadouts
+	}
+	return nil
+}
+
+// the pre-game choices of a seat, like faction, color or map vote, opaque to the server
+type Loadout struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Roomseatid *int32  `protobuf:"varint,1,opt,name=roomseatid,proto3,oneof" json:"roomseatid,omitempty"` // the seat index(1~N)
+	Id         *uint64 `protobuf:"varint,2,opt,name=id,proto3,oneof" json:"id,omitempty"`                 // the player id
+	Data       []byte  `protobuf:"bytes,3,opt,name=data,proto3,oneof" json:"data,omitempty"`
+}
+
+func (x *Loadout) Reset() {
+	*x = Loadout{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_message_proto_msgTypes[4]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *Loadout) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Loadout) ProtoMessage() {}
+
+func (x *Loadout) ProtoReflect() protoreflect.Message {
+	mi := &file_message_proto_msgTypes[4]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Loadout.ProtoReflect.Descriptor instead.
+func (*Loadout) Descriptor() ([]byte, []int) {
+	return file_message_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *Loadout) GetRoomseatid() int32 {
+	if x != nil && x.Roomseatid != nil {
+		return *x.Roomseatid
+	}
+	return 0
+}
+
+func (x *Loadout) GetId() uint64 {
+	if x != nil && x.Id != nil {
+		return *x.Id
+	}
+	return 0
+}
+
+func (x *Loadout) GetData() []byte {
+	if x != nil {
+		return x.Data
+	}
+	return nil
+}
+
+// submit the loadout of the seat
+type C2S_LoadoutMsg struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Data []byte `protobuf:"bytes,1,opt,name=data,proto3,oneof" json:"data,omitempty"`
+}
+
+func (x *C2S_LoadoutMsg) Reset() {
+	*x = C2S_LoadoutMsg{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_message_proto_msgTypes[5]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *C2S_LoadoutMsg) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*C2S_LoadoutMsg) ProtoMessage() {}
+
+func (x *C2S_LoadoutMsg) ProtoReflect() protoreflect.Message {
+	mi := &file_message_proto_msgTypes[5]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use C2S_LoadoutMsg.ProtoReflect.Descriptor instead.
+func (*C2S_LoadoutMsg) Descriptor() ([]byte, []int) {
+	return file_message_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *C2S_LoadoutMsg) GetData() []byte {
+	if x != nil {
+		return x.Data
+	}
+	return nil
+}
+
+// the server broadcasts the loadout submitted
+type S2C_LoadoutMsg struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Loadout *Loadout `protobuf:"bytes,1,opt,name=loadout,proto3,oneof" json:"loadout,omitempty"`
+}
+
+func (x *S2C_LoadoutMsg) Reset() {
+	*x = S2C_LoadoutMsg{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_message_proto_msgTypes[6]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *S2C_LoadoutMsg) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*S2C_LoadoutMsg) ProtoMessage() {}
+
+func (x *S2C_LoadoutMsg) ProtoReflect() protoreflect.Message {
+	mi := &file_message_proto_msgTypes[6]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use S2C_LoadoutMsg.ProtoReflect.Descriptor instead.
+func (*S2C_LoadoutMsg) Descriptor() ([]byte, []int) {
+	return file_message_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *S2C_LoadoutMsg) GetLoadout() *Loadout {
+	if x != nil {
+		return x.Loadout
+	}
+	return nil
+}
+
 // bar reading Progress
 type C2S_ProgressMsg struct {
 	state         protoimpl.MessageState
@@ -397,7 +720,7 @@ type C2S_ProgressMsg struct {
 func (x *C2S_ProgressMsg) Reset() {
 	*x = C2S_ProgressMsg{}
 	if protoimpl.UnsafeEnabled {
-		mi := &file_message_proto_msgTypes[4]
+		mi := &file_message_proto_msgTypes[7]
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		ms.StoreMessageInfo(mi)
 	}
@@ -410,7 +733,7 @@ func (x *C2S_ProgressMsg) String() string {
 func (*C2S_ProgressMsg) ProtoMessage() {}
 
 func (x *C2S_ProgressMsg) ProtoReflect() protoreflect.Message {
-	mi := &file_message_proto_msgTypes[4]
+	mi := &file_message_proto_msgTypes[7]
 	if protoimpl.UnsafeEnabled && x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -423,7 +746,7 @@ func (x *C2S_ProgressMsg) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use C2S_ProgressMsg.ProtoReflect.Descriptor instead.
 func (*C2S_ProgressMsg) Descriptor() ([]byte, []int) {
-	return file_message_proto_rawDescGZIP(), []int{4}
+	return file_message_proto_rawDescGZIP(), []int{7}
 }
 
 func (x *C2S_ProgressMsg) GetPro() int32 {
@@ -446,7 +769,7 @@ type S2C_ProgressMsg struct {
 func (x *S2C_ProgressMsg) Reset() {
 	*x = S2C_ProgressMsg{}
 	if protoimpl.UnsafeEnabled {
-		mi := &file_message_proto_msgTypes[5]
+		mi := &file_message_proto_msgTypes[8]
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		ms.StoreMessageInfo(mi)
 	}
@@ -459,7 +782,7 @@ func (x *S2C_ProgressMsg) String() string {
 func (*S2C_ProgressMsg) ProtoMessage() {}
 
 func (x *S2C_ProgressMsg) ProtoReflect() protoreflect.Message {
-	mi := &file_message_proto_msgTypes[5]
+	mi := &file_message_proto_msgTypes[8]
 	if protoimpl.UnsafeEnabled && x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -472,7 +795,7 @@ func (x *S2C_ProgressMsg) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use S2C_ProgressMsg.ProtoReflect.Descriptor instead.
 func (*S2C_ProgressMsg) Descriptor() ([]byte, []int) {
-	return file_message_proto_rawDescGZIP(), []int{5}
+	return file_message_proto_rawDescGZIP(), []int{8}
 }
 
 func (x *S2C_ProgressMsg) GetId() uint64 {
@@ -504,7 +827,7 @@ type C2S_InputMsg struct {
 func (x *C2S_InputMsg) Reset() {
 	*x = C2S_InputMsg{}
 	if protoimpl.UnsafeEnabled {
-		mi := &file_message_proto_msgTypes[6]
+		mi := &file_message_proto_msgTypes[9]
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		ms.StoreMessageInfo(mi)
 	}
@@ -517,7 +840,7 @@ func (x *C2S_InputMsg) String() string {
 func (*C2S_InputMsg) ProtoMessage() {}
 
 func (x *C2S_InputMsg) ProtoReflect() protoreflect.Message {
-	mi := &file_message_proto_msgTypes[6]
+	mi := &file_message_proto_msgTypes[9]
 	if protoimpl.UnsafeEnabled && x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -530,7 +853,7 @@ func (x *C2S_InputMsg) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use C2S_InputMsg.ProtoReflect.Descriptor instead.
 func (*C2S_InputMsg) Descriptor() ([]byte, []int) {
-	return file_message_proto_rawDescGZIP(), []int{6}
+	return file_message_proto_rawDescGZIP(), []int{9}
 }
 
 func (x *C2S_InputMsg) GetSid() int32 {
@@ -577,7 +900,7 @@ type InputData struct {
 func (x *InputData) Reset() {
 	*x = InputData{}
 	if protoimpl.UnsafeEnabled {
-		mi := &file_message_proto_msgTypes[7]
+		mi := &file_message_proto_msgTypes[10]
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		ms.StoreMessageInfo(mi)
 	}
@@ -590,7 +913,7 @@ func (x *InputData) String() string {
 func (*InputData) ProtoMessage() {}
 
 func (x *InputData) ProtoReflect() protoreflect.Message {
-	mi := &file_message_proto_msgTypes[7]
+	mi := &file_message_proto_msgTypes[10]
 	if protoimpl.UnsafeEnabled && x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -603,7 +926,7 @@ func (x *InputData) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use InputData.ProtoReflect.Descriptor instead.
 func (*InputData) Descriptor() ([]byte, []int) {
-	return file_message_proto_rawDescGZIP(), []int{7}
+	return file_message_proto_rawDescGZIP(), []int{10}
 }
 
 func (x *InputData) GetId() uint64 {
@@ -654,7 +977,7 @@ type FrameData struct {
 func (x *FrameData) Reset() {
 	*x = FrameData{}
 	if protoimpl.UnsafeEnabled {
-		mi := &file_message_proto_msgTypes[8]
+		mi := &file_message_proto_msgTypes[11]
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		ms.StoreMessageInfo(mi)
 	}
@@ -667,7 +990,7 @@ func (x *FrameData) String() string {
 func (*FrameData) ProtoMessage() {}
 
 func (x *FrameData) ProtoReflect() protoreflect.Message {
-	mi := &file_message_proto_msgTypes[8]
+	mi := &file_message_proto_msgTypes[11]
 	if protoimpl.UnsafeEnabled && x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -680,7 +1003,7 @@ func (x *FrameData) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use FrameData.ProtoReflect.Descriptor instead.
 func (*FrameData) Descriptor() ([]byte, []int) {
-	return file_message_proto_rawDescGZIP(), []int{8}
+	return file_message_proto_rawDescGZIP(), []int{11}
 }
 
 func (x *FrameData) GetFrameID() uint32 {
@@ -709,7 +1032,7 @@ type S2C_FrameMsg struct {
 func (x *S2C_FrameMsg) Reset() {
 	*x = S2C_FrameMsg{}
 	if protoimpl.UnsafeEnabled {
-		mi := &file_message_proto_msgTypes[9]
+		mi := &file_message_proto_msgTypes[12]
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		ms.StoreMessageInfo(mi)
 	}
@@ -722,7 +1045,7 @@ func (x *S2C_FrameMsg) String() string {
 func (*S2C_FrameMsg) ProtoMessage() {}
 
 func (x *S2C_FrameMsg) ProtoReflect() protoreflect.Message {
-	mi := &file_message_proto_msgTypes[9]
+	mi := &file_message_proto_msgTypes[12]
 	if protoimpl.UnsafeEnabled && x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -735,7 +1058,7 @@ func (x *S2C_FrameMsg) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use S2C_FrameMsg.ProtoReflect.Descriptor instead.
 func (*S2C_FrameMsg) Descriptor() ([]byte, []int) {
-	return file_message_proto_rawDescGZIP(), []int{9}
+	return file_message_proto_rawDescGZIP(), []int{12}
 }
 
 func (x *S2C_FrameMsg) GetFrames() []*FrameData {
@@ -745,6 +1068,182 @@ func (x *S2C_FrameMsg) GetFrames() []*FrameData {
 	return nil
 }
 
+// the server broadcasts the roster change of a seat
+type S2C_RosterMsg struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Roomseatid *int32  `protobuf:"varint,1,opt,name=roomseatid,proto3,oneof" json:"roomseatid,omitempty"` // the seat index(1~N)
+	Id         *uint64 `protobuf:"varint,2,opt,name=id,proto3,oneof" json:"id,omitempty"`                 // the id of the player assigned to the seat
+	Replaced   *uint64 `protobuf:"varint,3,opt,name=replaced,proto3,oneof" json:"replaced,omitempty"`     // the id of the player replaced, 0 means the seat is added
+}
+
+func (x *S2C_RosterMsg) Reset() {
+	*x = S2C_RosterMsg{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_message_proto_msgTypes[13]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *S2C_RosterMsg) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*S2C_RosterMsg) ProtoMessage() {}
+
+func (x *S2C_RosterMsg) ProtoReflect() protoreflect.Message {
+	mi := &file_message_proto_msgTypes[13]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use S2C_RosterMsg.ProtoReflect.Descriptor instead.
+func (*S2C_RosterMsg) Descriptor() ([]byte, []int) {
+	return file_message_proto_rawDescGZIP(), []int{13}
+}
+
+func (x *S2C_RosterMsg) GetRoomseatid() int32 {
+	if x != nil && x.Roomseatid != nil {
+		return *x.Roomseatid
+	}
+	return 0
+}
+
+func (x *S2C_RosterMsg) GetId() uint64 {
+	if x != nil && x.Id != nil {
+		return *x.Id
+	}
+	return 0
+}
+
+func (x *S2C_RosterMsg) GetReplaced() uint64 {
+	if x != nil && x.Replaced != nil {
+		return *x.Replaced
+	}
+	return 0
+}
+
+// the state hash computed by the client after the frame
+type C2S_ChecksumMsg struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	FrameID *uint32 `protobuf:"varint,1,opt,name=frameID,proto3,oneof" json:"frameID,omitempty"`
+	Hash    *uint64 `protobuf:"varint,2,opt,name=hash,proto3,oneof" json:"hash,omitempty"`
+}
+
+func (x *C2S_ChecksumMsg) Reset() {
+	*x = C2S_ChecksumMsg{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_message_proto_msgTypes[14]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *C2S_ChecksumMsg) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*C2S_ChecksumMsg) ProtoMessage() {}
+
+func (x *C2S_ChecksumMsg) ProtoReflect() protoreflect.Message {
+	mi := &file_message_proto_msgTypes[14]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use C2S_ChecksumMsg.ProtoReflect.Descriptor instead.
+func (*C2S_ChecksumMsg) Descriptor() ([]byte, []int) {
+	return file_message_proto_rawDescGZIP(), []int{14}
+}
+
+func (x *C2S_ChecksumMsg) GetFrameID() uint32 {
+	if x != nil && x.FrameID != nil {
+		return *x.FrameID
+	}
+	return 0
+}
+
+func (x *C2S_ChecksumMsg) GetHash() uint64 {
+	if x != nil && x.Hash != nil {
+		return *x.Hash
+	}
+	return 0
+}
+
+// the server replies the hash of its simulation if the one of the client differs
+type S2C_ChecksumMsg struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	FrameID *uint32 `protobuf:"varint,1,opt,name=frameID,proto3,oneof" json:"frameID,omitempty"`
+	Hash    *uint64 `protobuf:"varint,2,opt,name=hash,proto3,oneof" json:"hash,omitempty"`
+}
+
+func (x *S2C_ChecksumMsg) Reset() {
+	*x = S2C_ChecksumMsg{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_message_proto_msgTypes[15]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *S2C_ChecksumMsg) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*S2C_ChecksumMsg) ProtoMessage() {}
+
+func (x *S2C_ChecksumMsg) ProtoReflect() protoreflect.Message {
+	mi := &file_message_proto_msgTypes[15]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use S2C_ChecksumMsg.ProtoReflect.Descriptor instead.
+func (*S2C_ChecksumMsg) Descriptor() ([]byte, []int) {
+	return file_message_proto_rawDescGZIP(), []int{15}
+}
+
+func (x *S2C_ChecksumMsg) GetFrameID() uint32 {
+	if x != nil && x.FrameID != nil {
+		return *x.FrameID
+	}
+	return 0
+}
+
+func (x *S2C_ChecksumMsg) GetHash() uint64 {
+	if x != nil && x.Hash != nil {
+		return *x.Hash
+	}
+	return 0
+}
+
 // result message
 type C2S_ResultMsg struct {
 	state         protoimpl.MessageState
@@ -757,7 +1256,7 @@ type C2S_ResultMsg struct {
 func (x *C2S_ResultMsg) Reset() {
 	*x = C2S_ResultMsg{}
 	if protoimpl.UnsafeEnabled {
-		mi := &file_message_proto_msgTypes[10]
+		mi := &file_message_proto_msgTypes[16]
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		ms.StoreMessageInfo(mi)
 	}
@@ -770,7 +1269,7 @@ func (x *C2S_ResultMsg) String() string {
 func (*C2S_ResultMsg) ProtoMessage() {}
 
 func (x *C2S_ResultMsg) ProtoReflect() protoreflect.Message {
-	mi := &file_message_proto_msgTypes[10]
+	mi := &file_message_proto_msgTypes[16]
 	if protoimpl.UnsafeEnabled && x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -783,7 +1282,7 @@ func (x *C2S_ResultMsg) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use C2S_ResultMsg.ProtoReflect.Descriptor instead.
 func (*C2S_ResultMsg) Descriptor() ([]byte, []int) {
-	return file_message_proto_rawDescGZIP(), []int{10}
+	return file_message_proto_rawDescGZIP(), []int{16}
 }
 
 func (x *C2S_ResultMsg) GetWinnerID() uint64 {
@@ -797,96 +1296,182 @@ var File_message_proto protoreflect.FileDescriptor
 
 var file_message_proto_rawDesc = []byte{
 	0x0a, 0x0d, 0x6d, 0x65, 0x73, 0x73, 0x61, 0x67, 0x65, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x12,
-	0x02, 0x70, 0x62, 0x22, 0x91, 0x01, 0x0a, 0x0e, 0x43, 0x32, 0x53, 0x5f, 0x43, 0x6f, 0x6e, 0x6e,
+	0x02, 0x70, 0x62, 0x22, 0xb2, 0x03, 0x0a, 0x0e, 0x43, 0x32, 0x53, 0x5f, 0x43, 0x6f, 0x6e, 0x6e,
 	0x65, 0x63, 0x74, 0x4d, 0x73, 0x67, 0x12, 0x1f, 0x0a, 0x08, 0x70, 0x6c, 0x61, 0x79, 0x65, 0x72,
 	0x49, 0x44, 0x18, 0x01, 0x20, 0x01, 0x28, 0x04, 0x48, 0x00, 0x52, 0x08, 0x70, 0x6c, 0x61, 0x79,
 	0x65, 0x72, 0x49, 0x44, 0x88, 0x01, 0x01, 0x12, 0x1f, 0x0a, 0x08, 0x62, 0x61, 0x74, 0x74, 0x6c,
 	0x65, 0x49, 0x44, 0x18, 0x02, 0x20, 0x01, 0x28, 0x04, 0x48, 0x01, 0x52, 0x08, 0x62, 0x61, 0x74,
 	0x74, 0x6c, 0x65, 0x49, 0x44, 0x88, 0x01, 0x01, 0x12, 0x19, 0x0a, 0x05, 0x74, 0x6f, 0x6b, 0x65,
 	0x6e, 0x18, 0x03, 0x20, 0x01, 0x28, 0x09, 0x48, 0x02, 0x52, 0x05, 0x74, 0x6f, 0x6b, 0x65, 0x6e,
-	0x88, 0x01, 0x01, 0x42, 0x0b, 0x0a, 0x09, 0x5f, 0x70, 0x6c, 0x61, 0x79, 0x65, 0x72, 0x49, 0x44,
-	0x42, 0x0b, 0x0a, 0x09, 0x5f, 0x62, 0x61, 0x74, 0x74, 0x6c, 0x65, 0x49, 0x44, 0x42, 0x08, 0x0a,
-	0x06, 0x5f, 0x74, 0x6f, 0x6b, 0x65, 0x6e, 0x22, 0x50, 0x0a, 0x0e, 0x53, 0x32, 0x43, 0x5f, 0x43,
-	0x6f, 0x6e, 0x6e, 0x65, 0x63, 0x74, 0x4d, 0x73, 0x67, 0x12, 0x30, 0x0a, 0x09, 0x65, 0x72, 0x72,
-	0x6f, 0x72, 0x43, 0x6f, 0x64, 0x65, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0e, 0x32, 0x0d, 0x2e, 0x70,
-	0x62, 0x2e, 0x45, 0x52, 0x52, 0x4f, 0x52, 0x43, 0x4f, 0x44, 0x45, 0x48, 0x00, 0x52, 0x09, 0x65,
-	0x72, 0x72, 0x6f, 0x72, 0x43, 0x6f, 0x64, 0x65, 0x88, 0x01, 0x01, 0x42, 0x0c, 0x0a, 0x0a, 0x5f,
-	0x65, 0x72, 0x72, 0x6f, 0x72, 0x43, 0x6f, 0x64, 0x65, 0x22, 0xa5, 0x01, 0x0a, 0x0f, 0x53, 0x32,
-	0x43, 0x5f, 0x4a, 0x6f, 0x69, 0x6e, 0x52, 0x6f, 0x6f, 0x6d, 0x4d, 0x73, 0x67, 0x12, 0x23, 0x0a,
-	0x0a, 0x72, 0x6f, 0x6f, 0x6d, 0x73, 0x65, 0x61, 0x74, 0x69, 0x64, 0x18, 0x01, 0x20, 0x01, 0x28,
-	0x05, 0x48, 0x00, 0x52, 0x0a, 0x72, 0x6f, 0x6f, 0x6d, 0x73, 0x65, 0x61, 0x74, 0x69, 0x64, 0x88,
-	0x01, 0x01, 0x12, 0x16, 0x0a, 0x06, 0x6f, 0x74, 0x68, 0x65, 0x72, 0x73, 0x18, 0x02, 0x20, 0x03,
-	0x28, 0x04, 0x52, 0x06, 0x6f, 0x74, 0x68, 0x65, 0x72, 0x73, 0x12, 0x12, 0x0a, 0x04, 0x70, 0x72,
-	0x6f, 0x73, 0x18, 0x03, 0x20, 0x03, 0x28, 0x05, 0x52, 0x04, 0x70, 0x72, 0x6f, 0x73, 0x12, 0x23,
-	0x0a, 0x0a, 0x72, 0x61, 0x6e, 0x64, 0x6f, 0x6d, 0x53, 0x65, 0x65, 0x64, 0x18, 0x04, 0x20, 0x01,
-	0x28, 0x05, 0x48, 0x01, 0x52, 0x0a, 0x72, 0x61, 0x6e, 0x64, 0x6f, 0x6d, 0x53, 0x65, 0x65, 0x64,
-	0x88, 0x01, 0x01, 0x42, 0x0d, 0x0a, 0x0b, 0x5f, 0x72, 0x6f, 0x6f, 0x6d, 0x73, 0x65, 0x61, 0x74,
-	0x69, 0x64, 0x42, 0x0d, 0x0a, 0x0b, 0x5f, 0x72, 0x61, 0x6e, 0x64, 0x6f, 0x6d, 0x53, 0x65, 0x65,
-	0x64, 0x22, 0x3f, 0x0a, 0x0c, 0x53, 0x32, 0x43, 0x5f, 0x53, 0x74, 0x61, 0x72, 0x74, 0x4d, 0x73,
-	0x67, 0x12, 0x21, 0x0a, 0x09, 0x74, 0x69, 0x6d, 0x65, 0x53, 0x74, 0x61, 0x6d, 0x70, 0x18, 0x01,
-	0x20, 0x01, 0x28, 0x03, 0x48, 0x00, 0x52, 0x09, 0x74, 0x69, 0x6d, 0x65, 0x53, 0x74, 0x61, 0x6d,
-	0x70, 0x88, 0x01, 0x01, 0x42, 0x0c, 0x0a, 0x0a, 0x5f, 0x74, 0x69, 0x6d, 0x65, 0x53, 0x74, 0x61,
-	0x6d, 0x70, 0x22, 0x30, 0x0a, 0x0f, 0x43, 0x32, 0x53, 0x5f, 0x50, 0x72, 0x6f, 0x67, 0x72, 0x65,
-	0x73, 0x73, 0x4d, 0x73, 0x67, 0x12, 0x15, 0x0a, 0x03, 0x70, 0x72, 0x6f, 0x18, 0x01, 0x20, 0x01,
-	0x28, 0x05, 0x48, 0x00, 0x52, 0x03, 0x70, 0x72, 0x6f, 0x88, 0x01, 0x01, 0x42, 0x06, 0x0a, 0x04,
-	0x5f, 0x70, 0x72, 0x6f, 0x22, 0x4c, 0x0a, 0x0f, 0x53, 0x32, 0x43, 0x5f, 0x50, 0x72, 0x6f, 0x67,
-	0x72, 0x65, 0x73, 0x73, 0x4d, 0x73, 0x67, 0x12, 0x13, 0x0a, 0x02, 0x69, 0x64, 0x18, 0x01, 0x20,
-	0x01, 0x28, 0x04, 0x48, 0x00, 0x52, 0x02, 0x69, 0x64, 0x88, 0x01, 0x01, 0x12, 0x15, 0x0a, 0x03,
-	0x70, 0x72, 0x6f, 0x18, 0x02, 0x20, 0x01, 0x28, 0x05, 0x48, 0x01, 0x52, 0x03, 0x70, 0x72, 0x6f,
-	0x88, 0x01, 0x01, 0x42, 0x05, 0x0a, 0x03, 0x5f, 0x69, 0x64, 0x42, 0x06, 0x0a, 0x04, 0x5f, 0x70,
-	0x72, 0x6f, 0x22, 0x8a, 0x01, 0x0a, 0x0c, 0x43, 0x32, 0x53, 0x5f, 0x49, 0x6e, 0x70, 0x75, 0x74,
-	0x4d, 0x73, 0x67, 0x12, 0x15, 0x0a, 0x03, 0x73, 0x69, 0x64, 0x18, 0x01, 0x20, 0x01, 0x28, 0x05,
-	0x48, 0x00, 0x52, 0x03, 0x73, 0x69, 0x64, 0x88, 0x01, 0x01, 0x12, 0x11, 0x0a, 0x01, 0x78, 0x18,
-	0x02, 0x20, 0x01, 0x28, 0x05, 0x48, 0x01, 0x52, 0x01, 0x78, 0x88, 0x01, 0x01, 0x12, 0x11, 0x0a,
-	0x01, 0x79, 0x18, 0x03, 0x20, 0x01, 0x28, 0x05, 0x48, 0x02, 0x52, 0x01, 0x79, 0x88, 0x01, 0x01,
-	0x12, 0x1d, 0x0a, 0x07, 0x66, 0x72, 0x61, 0x6d, 0x65, 0x49, 0x44, 0x18, 0x04, 0x20, 0x01, 0x28,
-	0x0d, 0x48, 0x03, 0x52, 0x07, 0x66, 0x72, 0x61, 0x6d, 0x65, 0x49, 0x44, 0x88, 0x01, 0x01, 0x42,
-	0x06, 0x0a, 0x04, 0x5f, 0x73, 0x69, 0x64, 0x42, 0x04, 0x0a, 0x02, 0x5f, 0x78, 0x42, 0x04, 0x0a,
-	0x02, 0x5f, 0x79, 0x42, 0x0a, 0x0a, 0x08, 0x5f, 0x66, 0x72, 0x61, 0x6d, 0x65, 0x49, 0x44, 0x22,
-	0xac, 0x01, 0x0a, 0x09, 0x49, 0x6e, 0x70, 0x75, 0x74, 0x44, 0x61, 0x74, 0x61, 0x12, 0x13, 0x0a,
-	0x02, 0x69, 0x64, 0x18, 0x01, 0x20, 0x01, 0x28, 0x04, 0x48, 0x00, 0x52, 0x02, 0x69, 0x64, 0x88,
-	0x01, 0x01, 0x12, 0x15, 0x0a, 0x03, 0x73, 0x69, 0x64, 0x18, 0x02, 0x20, 0x01, 0x28, 0x05, 0x48,
-	0x01, 0x52, 0x03, 0x73, 0x69, 0x64, 0x88, 0x01, 0x01, 0x12, 0x11, 0x0a, 0x01, 0x78, 0x18, 0x03,
-	0x20, 0x01, 0x28, 0x05, 0x48, 0x02, 0x52, 0x01, 0x78, 0x88, 0x01, 0x01, 0x12, 0x11, 0x0a, 0x01,
-	0x79, 0x18, 0x04, 0x20, 0x01, 0x28, 0x05, 0x48, 0x03, 0x52, 0x01, 0x79, 0x88, 0x01, 0x01, 0x12,
-	0x23, 0x0a, 0x0a, 0x72, 0x6f, 0x6f, 0x6d, 0x73, 0x65, 0x61, 0x74, 0x69, 0x64, 0x18, 0x05, 0x20,
-	0x01, 0x28, 0x05, 0x48, 0x04, 0x52, 0x0a, 0x72, 0x6f, 0x6f, 0x6d, 0x73, 0x65, 0x61, 0x74, 0x69,
-	0x64, 0x88, 0x01, 0x01, 0x42, 0x05, 0x0a, 0x03, 0x5f, 0x69, 0x64, 0x42, 0x06, 0x0a, 0x04, 0x5f,
-	0x73, 0x69, 0x64, 0x42, 0x04, 0x0a, 0x02, 0x5f, 0x78, 0x42, 0x04, 0x0a, 0x02, 0x5f, 0x79, 0x42,
-	0x0d, 0x0a, 0x0b, 0x5f, 0x72, 0x6f, 0x6f, 0x6d, 0x73, 0x65, 0x61, 0x74, 0x69, 0x64, 0x22, 0x5b,
-	0x0a, 0x09, 0x46, 0x72, 0x61, 0x6d, 0x65, 0x44, 0x61, 0x74, 0x61, 0x12, 0x1d, 0x0a, 0x07, 0x66,
-	0x72, 0x61, 0x6d, 0x65, 0x49, 0x44, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0d, 0x48, 0x00, 0x52, 0x07,
-	0x66, 0x72, 0x61, 0x6d, 0x65, 0x49, 0x44, 0x88, 0x01, 0x01, 0x12, 0x23, 0x0a, 0x05, 0x69, 0x6e,
-	0x70, 0x75, 0x74, 0x18, 0x02, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x0d, 0x2e, 0x70, 0x62, 0x2e, 0x49,
-	0x6e, 0x70, 0x75, 0x74, 0x44, 0x61, 0x74, 0x61, 0x52, 0x05, 0x69, 0x6e, 0x70, 0x75, 0x74, 0x42,
-	0x0a, 0x0a, 0x08, 0x5f, 0x66, 0x72, 0x61, 0x6d, 0x65, 0x49, 0x44, 0x22, 0x35, 0x0a, 0x0c, 0x53,
-	0x32, 0x43, 0x5f, 0x46, 0x72, 0x61, 0x6d, 0x65, 0x4d, 0x73, 0x67, 0x12, 0x25, 0x0a, 0x06, 0x66,
-	0x72, 0x61, 0x6d, 0x65, 0x73, 0x18, 0x01, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x0d, 0x2e, 0x70, 0x62,
-	0x2e, 0x46, 0x72, 0x61, 0x6d, 0x65, 0x44, 0x61, 0x74, 0x61, 0x52, 0x06, 0x66, 0x72, 0x61, 0x6d,
-	0x65, 0x73, 0x22, 0x3d, 0x0a, 0x0d, 0x43, 0x32, 0x53, 0x5f, 0x52, 0x65, 0x73, 0x75, 0x6c, 0x74,
-	0x4d, 0x73, 0x67, 0x12, 0x1f, 0x0a, 0x08, 0x77, 0x69, 0x6e, 0x6e, 0x65, 0x72, 0x49, 0x44, 0x18,
-	0x01, 0x20, 0x01, 0x28, 0x04, 0x48, 0x00, 0x52, 0x08, 0x77, 0x69, 0x6e, 0x6e, 0x65, 0x72, 0x49,
-	0x44, 0x88, 0x01, 0x01, 0x42, 0x0b, 0x0a, 0x09, 0x5f, 0x77, 0x69, 0x6e, 0x6e, 0x65, 0x72, 0x49,
-	0x44, 0x2a, 0xc4, 0x01, 0x0a, 0x02, 0x49, 0x44, 0x12, 0x0d, 0x0a, 0x09, 0x4d, 0x53, 0x47, 0x5f,
-	0x42, 0x45, 0x47, 0x49, 0x4e, 0x10, 0x00, 0x12, 0x0f, 0x0a, 0x0b, 0x4d, 0x53, 0x47, 0x5f, 0x43,
-	0x6f, 0x6e, 0x6e, 0x65, 0x63, 0x74, 0x10, 0x01, 0x12, 0x11, 0x0a, 0x0d, 0x4d, 0x53, 0x47, 0x5f,
-	0x48, 0x65, 0x61, 0x72, 0x74, 0x62, 0x65, 0x61, 0x74, 0x10, 0x02, 0x12, 0x10, 0x0a, 0x0c, 0x4d,
-	0x53, 0x47, 0x5f, 0x4a, 0x6f, 0x69, 0x6e, 0x52, 0x6f, 0x6f, 0x6d, 0x10, 0x0a, 0x12, 0x10, 0x0a,
-	0x0c, 0x4d, 0x53, 0x47, 0x5f, 0x50, 0x72, 0x6f, 0x67, 0x72, 0x65, 0x73, 0x73, 0x10, 0x14, 0x12,
-	0x0d, 0x0a, 0x09, 0x4d, 0x53, 0x47, 0x5f, 0x52, 0x65, 0x61, 0x64, 0x79, 0x10, 0x1e, 0x12, 0x0d,
-	0x0a, 0x09, 0x4d, 0x53, 0x47, 0x5f, 0x53, 0x74, 0x61, 0x72, 0x74, 0x10, 0x28, 0x12, 0x0d, 0x0a,
-	0x09, 0x4d, 0x53, 0x47, 0x5f, 0x46, 0x72, 0x61, 0x6d, 0x65, 0x10, 0x32, 0x12, 0x0d, 0x0a, 0x09,
-	0x4d, 0x53, 0x47, 0x5f, 0x49, 0x6e, 0x70, 0x75, 0x74, 0x10, 0x3c, 0x12, 0x0e, 0x0a, 0x0a, 0x4d,
-	0x53, 0x47, 0x5f, 0x52, 0x65, 0x73, 0x75, 0x6c, 0x74, 0x10, 0x46, 0x12, 0x0d, 0x0a, 0x09, 0x4d,
-	0x53, 0x47, 0x5f, 0x43, 0x6c, 0x6f, 0x73, 0x65, 0x10, 0x64, 0x12, 0x0c, 0x0a, 0x07, 0x4d, 0x53,
-	0x47, 0x5f, 0x45, 0x4e, 0x44, 0x10, 0xff, 0x01, 0x2a, 0x5b, 0x0a, 0x09, 0x45, 0x52, 0x52, 0x4f,
-	0x52, 0x43, 0x4f, 0x44, 0x45, 0x12, 0x0a, 0x0a, 0x06, 0x45, 0x52, 0x52, 0x5f, 0x6f, 0x6b, 0x10,
-	0x00, 0x12, 0x10, 0x0a, 0x0c, 0x45, 0x52, 0x52, 0x5f, 0x4e, 0x6f, 0x50, 0x6c, 0x61, 0x79, 0x65,
-	0x72, 0x10, 0x01, 0x12, 0x0e, 0x0a, 0x0a, 0x45, 0x52, 0x52, 0x5f, 0x4e, 0x6f, 0x52, 0x6f, 0x6f,
-	0x6d, 0x10, 0x02, 0x12, 0x11, 0x0a, 0x0d, 0x45, 0x52, 0x52, 0x5f, 0x52, 0x6f, 0x6f, 0x6d, 0x53,
-	0x74, 0x61, 0x74, 0x65, 0x10, 0x03, 0x12, 0x0d, 0x0a, 0x09, 0x45, 0x52, 0x52, 0x5f, 0x54, 0x6f,
-	0x6b, 0x65, 0x6e, 0x10, 0x04, 0x42, 0x06, 0x5a, 0x04, 0x2e, 0x2f, 0x70, 0x62, 0x62, 0x06, 0x70,
-	0x72, 0x6f, 0x74, 0x6f, 0x33,
+	0x88, 0x01, 0x01, 0x12, 0x2d, 0x0a, 0x0f, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x63, 0x6f, 0x6c, 0x56,
+	0x65, 0x72, 0x73, 0x69, 0x6f, 0x6e, 0x18, 0x04, 0x20, 0x01, 0x28, 0x0d, 0x48, 0x03, 0x52, 0x0f,
+	0x70, 0x72, 0x6f, 0x74, 0x6f, 0x63, 0x6f, 0x6c, 0x56, 0x65, 0x72, 0x73, 0x69, 0x6f, 0x6e, 0x88,
+	0x01, 0x01, 0x12, 0x29, 0x0a, 0x0d, 0x63, 0x6c, 0x69, 0x65, 0x6e, 0x74, 0x56, 0x65, 0x72, 0x73,
+	0x69, 0x6f, 0x6e, 0x18, 0x05, 0x20, 0x01, 0x28, 0x09, 0x48, 0x04, 0x52, 0x0d, 0x63, 0x6c, 0x69,
+	0x65, 0x6e, 0x74, 0x56, 0x65, 0x72, 0x73, 0x69, 0x6f, 0x6e, 0x88, 0x01, 0x01, 0x12, 0x2d, 0x0a,
+	0x08, 0x63, 0x68, 0x65, 0x63, 0x6b, 0x73, 0x75, 0x6d, 0x18, 0x06, 0x20, 0x01, 0x28, 0x0e, 0x32,
+	0x0c, 0x2e, 0x70, 0x62, 0x2e, 0x43, 0x48, 0x45, 0x43, 0x4b, 0x53, 0x55, 0x4d, 0x48, 0x05, 0x52,
+	0x08, 0x63, 0x68, 0x65, 0x63, 0x6b, 0x73, 0x75, 0x6d, 0x88, 0x01, 0x01, 0x12, 0x27, 0x0a, 0x0c,
+	0x72, 0x65, 0x73, 0x75, 0x6d, 0x65, 0x54, 0x69, 0x63, 0x6b, 0x65, 0x74, 0x18, 0x07, 0x20, 0x01,
+	0x28, 0x09, 0x48, 0x06, 0x52, 0x0c, 0x72, 0x65, 0x73, 0x75, 0x6d, 0x65, 0x54, 0x69, 0x63, 0x6b,
+	0x65, 0x74, 0x88, 0x01, 0x01, 0x12, 0x1d, 0x0a, 0x07, 0x66, 0x72, 0x61, 0x6d, 0x65, 0x49, 0x44,
+	0x18, 0x08, 0x20, 0x01, 0x28, 0x0d, 0x48, 0x07, 0x52, 0x07, 0x66, 0x72, 0x61, 0x6d, 0x65, 0x49,
+	0x44, 0x88, 0x01, 0x01, 0x42, 0x0b, 0x0a, 0x09, 0x5f, 0x70, 0x6c, 0x61, 0x79, 0x65, 0x72, 0x49,
+	0x44, 0x42, 0x0b, 0x0a, 0x09, 0x5f, 0x62, 0x61, 0x74, 0x74, 0x6c, 0x65, 0x49, 0x44, 0x42, 0x08,
+	0x0a, 0x06, 0x5f, 0x74, 0x6f, 0x6b, 0x65, 0x6e, 0x42, 0x12, 0x0a, 0x10, 0x5f, 0x70, 0x72, 0x6f,
+	0x74, 0x6f, 0x63, 0x6f, 0x6c, 0x56, 0x65, 0x72, 0x73, 0x69, 0x6f, 0x6e, 0x42, 0x10, 0x0a, 0x0e,
+	0x5f, 0x63, 0x6c, 0x69, 0x65, 0x6e, 0x74, 0x56, 0x65, 0x72, 0x73, 0x69, 0x6f, 0x6e, 0x42, 0x0b,
+	0x0a, 0x09, 0x5f, 0x63, 0x68, 0x65, 0x63, 0x6b, 0x73, 0x75, 0x6d, 0x42, 0x0f, 0x0a, 0x0d, 0x5f,
+	0x72, 0x65, 0x73, 0x75, 0x6d, 0x65, 0x54, 0x69, 0x63, 0x6b, 0x65, 0x74, 0x42, 0x0a, 0x0a, 0x08,
+	0x5f, 0x66, 0x72, 0x61, 0x6d, 0x65, 0x49, 0x44, 0x22, 0xb4, 0x02, 0x0a, 0x0e, 0x53, 0x32, 0x43,
+	0x5f, 0x43, 0x6f, 0x6e, 0x6e, 0x65, 0x63, 0x74, 0x4d, 0x73, 0x67, 0x12, 0x30, 0x0a, 0x09, 0x65,
+	0x72, 0x72, 0x6f, 0x72, 0x43, 0x6f, 0x64, 0x65, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0e, 0x32, 0x0d,
+	0x2e, 0x70, 0x62, 0x2e, 0x45, 0x52, 0x52, 0x4f, 0x52, 0x43, 0x4f, 0x44, 0x45, 0x48, 0x00, 0x52,
+	0x09, 0x65, 0x72, 0x72, 0x6f, 0x72, 0x43, 0x6f, 0x64, 0x65, 0x88, 0x01, 0x01, 0x12, 0x1d, 0x0a,
+	0x07, 0x61, 0x64, 0x64, 0x72, 0x65, 0x73, 0x73, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x48, 0x01,
+	0x52, 0x07, 0x61, 0x64, 0x64, 0x72, 0x65, 0x73, 0x73, 0x88, 0x01, 0x01, 0x12, 0x2d, 0x0a, 0x0f,
+	0x70, 0x72, 0x6f, 0x74, 0x6f, 0x63, 0x6f, 0x6c, 0x56, 0x65, 0x72, 0x73, 0x69, 0x6f, 0x6e, 0x18,
+	0x03, 0x20, 0x01, 0x28, 0x0d, 0x48, 0x02, 0x52, 0x0f, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x63, 0x6f,
+	0x6c, 0x56, 0x65, 0x72, 0x73, 0x69, 0x6f, 0x6e, 0x88, 0x01, 0x01, 0x12, 0x2d, 0x0a, 0x08, 0x63,
+	0x68, 0x65, 0x63, 0x6b, 0x73, 0x75, 0x6d, 0x18, 0x04, 0x20, 0x01, 0x28, 0x0e, 0x32, 0x0c, 0x2e,
+	0x70, 0x62, 0x2e, 0x43, 0x48, 0x45, 0x43, 0x4b, 0x53, 0x55, 0x4d, 0x48, 0x03, 0x52, 0x08, 0x63,
+	0x68, 0x65, 0x63, 0x6b, 0x73, 0x75, 0x6d, 0x88, 0x01, 0x01, 0x12, 0x27, 0x0a, 0x0c, 0x72, 0x65,
+	0x73, 0x75, 0x6d, 0x65, 0x54, 0x69, 0x63, 0x6b, 0x65, 0x74, 0x18, 0x05, 0x20, 0x01, 0x28, 0x09,
+	0x48, 0x04, 0x52, 0x0c, 0x72, 0x65, 0x73, 0x75, 0x6d, 0x65, 0x54, 0x69, 0x63, 0x6b, 0x65, 0x74,
+	0x88, 0x01, 0x01, 0x42, 0x0c, 0x0a, 0x0a, 0x5f, 0x65, 0x72, 0x72, 0x6f, 0x72, 0x43, 0x6f, 0x64,
+	0x65, 0x42, 0x0a, 0x0a, 0x08, 0x5f, 0x61, 0x64, 0x64, 0x72, 0x65, 0x73, 0x73, 0x42, 0x12, 0x0a,
+	0x10, 0x5f, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x63, 0x6f, 0x6c, 0x56, 0x65, 0x72, 0x73, 0x69, 0x6f,
+	0x6e, 0x42, 0x0b, 0x0a, 0x09, 0x5f, 0x63, 0x68, 0x65, 0x63, 0x6b, 0x73, 0x75, 0x6d, 0x42, 0x0f,
+	0x0a, 0x0d, 0x5f, 0x72, 0x65, 0x73, 0x75, 0x6d, 0x65, 0x54, 0x69, 0x63, 0x6b, 0x65, 0x74, 0x22,
+	0xce, 0x01, 0x0a, 0x0f, 0x53, 0x32, 0x43, 0x5f, 0x4a, 0x6f, 0x69, 0x6e, 0x52, 0x6f, 0x6f, 0x6d,
+	0x4d, 0x73, 0x67, 0x12, 0x23, 0x0a, 0x0a, 0x72, 0x6f, 0x6f, 0x6d, 0x73, 0x65, 0x61, 0x74, 0x69,
+	0x64, 0x18, 0x01, 0x20, 0x01, 0x28, 0x05, 0x48, 0x00, 0x52, 0x0a, 0x72, 0x6f, 0x6f, 0x6d, 0x73,
+	0x65, 0x61, 0x74, 0x69, 0x64, 0x88, 0x01, 0x01, 0x12, 0x16, 0x0a, 0x06, 0x6f, 0x74, 0x68, 0x65,
+	0x72, 0x73, 0x18, 0x02, 0x20, 0x03, 0x28, 0x04, 0x52, 0x06, 0x6f, 0x74, 0x68, 0x65, 0x72, 0x73,
+	0x12, 0x12, 0x0a, 0x04, 0x70, 0x72, 0x6f, 0x73, 0x18, 0x03, 0x20, 0x03, 0x28, 0x05, 0x52, 0x04,
+	0x70, 0x72, 0x6f, 0x73, 0x12, 0x23, 0x0a, 0x0a, 0x72, 0x61, 0x6e, 0x64, 0x6f, 0x6d, 0x53, 0x65,
+	0x65, 0x64, 0x18, 0x04, 0x20, 0x01, 0x28, 0x05, 0x48, 0x01, 0x52, 0x0a, 0x72, 0x61, 0x6e, 0x64,
+	0x6f, 0x6d, 0x53, 0x65, 0x65, 0x64, 0x88, 0x01, 0x01, 0x12, 0x27, 0x0a, 0x08, 0x6c, 0x6f, 0x61,
+	0x64, 0x6f, 0x75, 0x74, 0x73, 0x18, 0x05, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x0b, 0x2e, 0x70, 0x62,
+	0x2e, 0x4c, 0x6f, 0x61, 0x64, 0x6f, 0x75, 0x74, 0x52, 0x08, 0x6c, 0x6f, 0x61, 0x64, 0x6f, 0x75,
+	0x74, 0x73, 0x42, 0x0d, 0x0a, 0x0b, 0x5f, 0x72, 0x6f, 0x6f, 0x6d, 0x73, 0x65, 0x61, 0x74, 0x69,
+	0x64, 0x42, 0x0d, 0x0a, 0x0b, 0x5f, 0x72, 0x61, 0x6e, 0x64, 0x6f, 0x6d, 0x53, 0x65, 0x65, 0x64,
+	0x22, 0x68, 0x0a, 0x0c, 0x53, 0x32, 0x43, 0x5f, 0x53, 0x74, 0x61, 0x72, 0x74, 0x4d, 0x73, 0x67,
+	0x12, 0x21, 0x0a, 0x09, 0x74, 0x69, 0x6d, 0x65, 0x53, 0x74, 0x61, 0x6d, 0x70, 0x18, 0x01, 0x20,
+	0x01, 0x28, 0x03, 0x48, 0x00, 0x52, 0x09, 0x74, 0x69, 0x6d, 0x65, 0x53, 0x74, 0x61, 0x6d, 0x70,
+	0x88, 0x01, 0x01, 0x12, 0x27, 0x0a, 0x08, 0x6c, 0x6f, 0x61, 0x64, 0x6f, 0x75, 0x74, 0x73, 0x18,
+	0x02, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x0b, 0x2e, 0x70, 0x62, 0x2e, 0x4c, 0x6f, 0x61, 0x64, 0x6f,
+	0x75, 0x74, 0x52, 0x08, 0x6c, 0x6f, 0x61, 0x64, 0x6f, 0x75, 0x74, 0x73, 0x42, 0x0c, 0x0a, 0x0a,
+	0x5f, 0x74, 0x69, 0x6d, 0x65, 0x53, 0x74, 0x61, 0x6d, 0x70, 0x22, 0x7b, 0x0a, 0x07, 0x4c, 0x6f,
+	0x61, 0x64, 0x6f, 0x75, 0x74, 0x12, 0x23, 0x0a, 0x0a, 0x72, 0x6f, 0x6f, 0x6d, 0x73, 0x65, 0x61,
+	0x74, 0x69, 0x64, 0x18, 0x01, 0x20, 0x01, 0x28, 0x05, 0x48, 0x00, 0x52, 0x0a, 0x72, 0x6f, 0x6f,
+	0x6d, 0x73, 0x65, 0x61, 0x74, 0x69, 0x64, 0x88, 0x01, 0x01, 0x12, 0x13, 0x0a, 0x02, 0x69, 0x64,
+	0x18, 0x02, 0x20, 0x01, 0x28, 0x04, 0x48, 0x01, 0x52, 0x02, 0x69, 0x64, 0x88, 0x01, 0x01, 0x12,
+	0x17, 0x0a, 0x04, 0x64, 0x61, 0x74, 0x61, 0x18, 0x03, 0x20, 0x01, 0x28, 0x0c, 0x48, 0x02, 0x52,
+	0x04, 0x64, 0x61, 0x74, 0x61, 0x88, 0x01, 0x01, 0x42, 0x0d, 0x0a, 0x0b, 0x5f, 0x72, 0x6f, 0x6f,
+	0x6d, 0x73, 0x65, 0x61, 0x74, 0x69, 0x64, 0x42, 0x05, 0x0a, 0x03, 0x5f, 0x69, 0x64, 0x42, 0x07,
+	0x0a, 0x05, 0x5f, 0x64, 0x61, 0x74, 0x61, 0x22, 0x32, 0x0a, 0x0e, 0x43, 0x32, 0x53, 0x5f, 0x4c,
+	0x6f, 0x61, 0x64, 0x6f, 0x75, 0x74, 0x4d, 0x73, 0x67, 0x12, 0x17, 0x0a, 0x04, 0x64, 0x61, 0x74,
+	0x61, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0c, 0x48, 0x00, 0x52, 0x04, 0x64, 0x61, 0x74, 0x61, 0x88,
+	0x01, 0x01, 0x42, 0x07, 0x0a, 0x05, 0x5f, 0x64, 0x61, 0x74, 0x61, 0x22, 0x48, 0x0a, 0x0e, 0x53,
+	0x32, 0x43, 0x5f, 0x4c, 0x6f, 0x61, 0x64, 0x6f, 0x75, 0x74, 0x4d, 0x73, 0x67, 0x12, 0x2a, 0x0a,
+	0x07, 0x6c, 0x6f, 0x61, 0x64, 0x6f, 0x75, 0x74, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x0b,
+	0x2e, 0x70, 0x62, 0x2e, 0x4c, 0x6f, 0x61, 0x64, 0x6f, 0x75, 0x74, 0x48, 0x00, 0x52, 0x07, 0x6c,
+	0x6f, 0x61, 0x64, 0x6f, 0x75, 0x74, 0x88, 0x01, 0x01, 0x42, 0x0a, 0x0a, 0x08, 0x5f, 0x6c, 0x6f,
+	0x61, 0x64, 0x6f, 0x75, 0x74, 0x22, 0x30, 0x0a, 0x0f, 0x43, 0x32, 0x53, 0x5f, 0x50, 0x72, 0x6f,
+	0x67, 0x72, 0x65, 0x73, 0x73, 0x4d, 0x73, 0x67, 0x12, 0x15, 0x0a, 0x03, 0x70, 0x72, 0x6f, 0x18,
+	0x01, 0x20, 0x01, 0x28, 0x05, 0x48, 0x00, 0x52, 0x03, 0x70, 0x72, 0x6f, 0x88, 0x01, 0x01, 0x42,
+	0x06, 0x0a, 0x04, 0x5f, 0x70, 0x72, 0x6f, 0x22, 0x4c, 0x0a, 0x0f, 0x53, 0x32, 0x43, 0x5f, 0x50,
+	0x72, 0x6f, 0x67, 0x72, 0x65, 0x73, 0x73, 0x4d, 0x73, 0x67, 0x12, 0x13, 0x0a, 0x02, 0x69, 0x64,
+	0x18, 0x01, 0x20, 0x01, 0x28, 0x04, 0x48, 0x00, 0x52, 0x02, 0x69, 0x64, 0x88, 0x01, 0x01, 0x12,
+	0x15, 0x0a, 0x03, 0x70, 0x72, 0x6f, 0x18, 0x02, 0x20, 0x01, 0x28, 0x05, 0x48, 0x01, 0x52, 0x03,
+	0x70, 0x72, 0x6f, 0x88, 0x01, 0x01, 0x42, 0x05, 0x0a, 0x03, 0x5f, 0x69, 0x64, 0x42, 0x06, 0x0a,
+	0x04, 0x5f, 0x70, 0x72, 0x6f, 0x22, 0x8a, 0x01, 0x0a, 0x0c, 0x43, 0x32, 0x53, 0x5f, 0x49, 0x6e,
+	0x70, 0x75, 0x74, 0x4d, 0x73, 0x67, 0x12, 0x15, 0x0a, 0x03, 0x73, 0x69, 0x64, 0x18, 0x01, 0x20,
+	0x01, 0x28, 0x05, 0x48, 0x00, 0x52, 0x03, 0x73, 0x69, 0x64, 0x88, 0x01, 0x01, 0x12, 0x11, 0x0a,
+	0x01, 0x78, 0x18, 0x02, 0x20, 0x01, 0x28, 0x05, 0x48, 0x01, 0x52, 0x01, 0x78, 0x88, 0x01, 0x01,
+	0x12, 0x11, 0x0a, 0x01, 0x79, 0x18, 0x03, 0x20, 0x01, 0x28, 0x05, 0x48, 0x02, 0x52, 0x01, 0x79,
+	0x88, 0x01, 0x01, 0x12, 0x1d, 0x0a, 0x07, 0x66, 0x72, 0x61, 0x6d, 0x65, 0x49, 0x44, 0x18, 0x04,
+	0x20, 0x01, 0x28, 0x0d, 0x48, 0x03, 0x52, 0x07, 0x66, 0x72, 0x61, 0x6d, 0x65, 0x49, 0x44, 0x88,
+	0x01, 0x01, 0x42, 0x06, 0x0a, 0x04, 0x5f, 0x73, 0x69, 0x64, 0x42, 0x04, 0x0a, 0x02, 0x5f, 0x78,
+	0x42, 0x04, 0x0a, 0x02, 0x5f, 0x79, 0x42, 0x0a, 0x0a, 0x08, 0x5f, 0x66, 0x72, 0x61, 0x6d, 0x65,
+	0x49, 0x44, 0x22, 0xac, 0x01, 0x0a, 0x09, 0x49, 0x6e, 0x70, 0x75, 0x74, 0x44, 0x61, 0x74, 0x61,
+	0x12, 0x13, 0x0a, 0x02, 0x69, 0x64, 0x18, 0x01, 0x20, 0x01, 0x28, 0x04, 0x48, 0x00, 0x52, 0x02,
+	0x69, 0x64, 0x88, 0x01, 0x01, 0x12, 0x15, 0x0a, 0x03, 0x73, 0x69, 0x64, 0x18, 0x02, 0x20, 0x01,
+	0x28, 0x05, 0x48, 0x01, 0x52, 0x03, 0x73, 0x69, 0x64, 0x88, 0x01, 0x01, 0x12, 0x11, 0x0a, 0x01,
+	0x78, 0x18, 0x03, 0x20, 0x01, 0x28, 0x05, 0x48, 0x02, 0x52, 0x01, 0x78, 0x88, 0x01, 0x01, 0x12,
+	0x11, 0x0a, 0x01, 0x79, 0x18, 0x04, 0x20, 0x01, 0x28, 0x05, 0x48, 0x03, 0x52, 0x01, 0x79, 0x88,
+	0x01, 0x01, 0x12, 0x23, 0x0a, 0x0a, 0x72, 0x6f, 0x6f, 0x6d, 0x73, 0x65, 0x61, 0x74, 0x69, 0x64,
+	0x18, 0x05, 0x20, 0x01, 0x28, 0x05, 0x48, 0x04, 0x52, 0x0a, 0x72, 0x6f, 0x6f, 0x6d, 0x73, 0x65,
+	0x61, 0x74, 0x69, 0x64, 0x88, 0x01, 0x01, 0x42, 0x05, 0x0a, 0x03, 0x5f, 0x69, 0x64, 0x42, 0x06,
+	0x0a, 0x04, 0x5f, 0x73, 0x69, 0x64, 0x42, 0x04, 0x0a, 0x02, 0x5f, 0x78, 0x42, 0x04, 0x0a, 0x02,
+	0x5f, 0x79, 0x42, 0x0d, 0x0a, 0x0b, 0x5f, 0x72, 0x6f, 0x6f, 0x6d, 0x73, 0x65, 0x61, 0x74, 0x69,
+	0x64, 0x22, 0x5b, 0x0a, 0x09, 0x46, 0x72, 0x61, 0x6d, 0x65, 0x44, 0x61, 0x74, 0x61, 0x12, 0x1d,
+	0x0a, 0x07, 0x66, 0x72, 0x61, 0x6d, 0x65, 0x49, 0x44, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0d, 0x48,
+	0x00, 0x52, 0x07, 0x66, 0x72, 0x61, 0x6d, 0x65, 0x49, 0x44, 0x88, 0x01, 0x01, 0x12, 0x23, 0x0a,
+	0x05, 0x69, 0x6e, 0x70, 0x75, 0x74, 0x18, 0x02, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x0d, 0x2e, 0x70,
+	0x62, 0x2e, 0x49, 0x6e, 0x70, 0x75, 0x74, 0x44, 0x61, 0x74, 0x61, 0x52, 0x05, 0x69, 0x6e, 0x70,
+	0x75, 0x74, 0x42, 0x0a, 0x0a, 0x08, 0x5f, 0x66, 0x72, 0x61, 0x6d, 0x65, 0x49, 0x44, 0x22, 0x35,
+	0x0a, 0x0c, 0x53, 0x32, 0x43, 0x5f, 0x46, 0x72, 0x61, 0x6d, 0x65, 0x4d, 0x73, 0x67, 0x12, 0x25,
+	0x0a, 0x06, 0x66, 0x72, 0x61, 0x6d, 0x65, 0x73, 0x18, 0x01, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x0d,
+	0x2e, 0x70, 0x62, 0x2e, 0x46, 0x72, 0x61, 0x6d, 0x65, 0x44, 0x61, 0x74, 0x61, 0x52, 0x06, 0x66,
+	0x72, 0x61, 0x6d, 0x65, 0x73, 0x22, 0x8d, 0x01, 0x0a, 0x0d, 0x53, 0x32, 0x43, 0x5f, 0x52, 0x6f,
+	0x73, 0x74, 0x65, 0x72, 0x4d, 0x73, 0x67, 0x12, 0x23, 0x0a, 0x0a, 0x72, 0x6f, 0x6f, 0x6d, 0x73,
+	0x65, 0x61, 0x74, 0x69, 0x64, 0x18, 0x01, 0x20, 0x01, 0x28, 0x05, 0x48, 0x00, 0x52, 0x0a, 0x72,
+	0x6f, 0x6f, 0x6d, 0x73, 0x65, 0x61, 0x74, 0x69, 0x64, 0x88, 0x01, 0x01, 0x12, 0x13, 0x0a, 0x02,
+	0x69, 0x64, 0x18, 0x02, 0x20, 0x01, 0x28, 0x04, 0x48, 0x01, 0x52, 0x02, 0x69, 0x64, 0x88, 0x01,
+	0x01, 0x12, 0x1f, 0x0a, 0x08, 0x72, 0x65, 0x70, 0x6c, 0x61, 0x63, 0x65, 0x64, 0x18, 0x03, 0x20,
+	0x01, 0x28, 0x04, 0x48, 0x02, 0x52, 0x08, 0x72, 0x65, 0x70, 0x6c, 0x61, 0x63, 0x65, 0x64, 0x88,
+	0x01, 0x01, 0x42, 0x0d, 0x0a, 0x0b, 0x5f, 0x72, 0x6f, 0x6f, 0x6d, 0x73, 0x65, 0x61, 0x74, 0x69,
+	0x64, 0x42, 0x05, 0x0a, 0x03, 0x5f, 0x69, 0x64, 0x42, 0x0b, 0x0a, 0x09, 0x5f, 0x72, 0x65, 0x70,
+	0x6c, 0x61, 0x63, 0x65, 0x64, 0x22, 0x5e, 0x0a, 0x0f, 0x43, 0x32, 0x53, 0x5f, 0x43, 0x68, 0x65,
+	0x63, 0x6b, 0x73, 0x75, 0x6d, 0x4d, 0x73, 0x67, 0x12, 0x1d, 0x0a, 0x07, 0x66, 0x72, 0x61, 0x6d,
+	0x65, 0x49, 0x44, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0d, 0x48, 0x00, 0x52, 0x07, 0x66, 0x72, 0x61,
+	0x6d, 0x65, 0x49, 0x44, 0x88, 0x01, 0x01, 0x12, 0x17, 0x0a, 0x04, 0x68, 0x61, 0x73, 0x68, 0x18,
+	0x02, 0x20, 0x01, 0x28, 0x04, 0x48, 0x01, 0x52, 0x04, 0x68, 0x61, 0x73, 0x68, 0x88, 0x01, 0x01,
+	0x42, 0x0a, 0x0a, 0x08, 0x5f, 0x66, 0x72, 0x61, 0x6d, 0x65, 0x49, 0x44, 0x42, 0x07, 0x0a, 0x05,
+	0x5f, 0x68, 0x61, 0x73, 0x68, 0x22, 0x5e, 0x0a, 0x0f, 0x53, 0x32, 0x43, 0x5f, 0x43, 0x68, 0x65,
+	0x63, 0x6b, 0x73, 0x75, 0x6d, 0x4d, 0x73, 0x67, 0x12, 0x1d, 0x0a, 0x07, 0x66, 0x72, 0x61, 0x6d,
+	0x65, 0x49, 0x44, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0d, 0x48, 0x00, 0x52, 0x07, 0x66, 0x72, 0x61,
+	0x6d, 0x65, 0x49, 0x44, 0x88, 0x01, 0x01, 0x12, 0x17, 0x0a, 0x04, 0x68, 0x61, 0x73, 0x68, 0x18,
+	0x02, 0x20, 0x01, 0x28, 0x04, 0x48, 0x01, 0x52, 0x04, 0x68, 0x61, 0x73, 0x68, 0x88, 0x01, 0x01,
+	0x42, 0x0a, 0x0a, 0x08, 0x5f, 0x66, 0x72, 0x61, 0x6d, 0x65, 0x49, 0x44, 0x42, 0x07, 0x0a, 0x05,
+	0x5f, 0x68, 0x61, 0x73, 0x68, 0x22, 0x3d, 0x0a, 0x0d, 0x43, 0x32, 0x53, 0x5f, 0x52, 0x65, 0x73,
+	0x75, 0x6c, 0x74, 0x4d, 0x73, 0x67, 0x12, 0x1f, 0x0a, 0x08, 0x77, 0x69, 0x6e, 0x6e, 0x65, 0x72,
+	0x49, 0x44, 0x18, 0x01, 0x20, 0x01, 0x28, 0x04, 0x48, 0x00, 0x52, 0x08, 0x77, 0x69, 0x6e, 0x6e,
+	0x65, 0x72, 0x49, 0x44, 0x88, 0x01, 0x01, 0x42, 0x0b, 0x0a, 0x09, 0x5f, 0x77, 0x69, 0x6e, 0x6e,
+	0x65, 0x72, 0x49, 0x44, 0x2a, 0xf7, 0x01, 0x0a, 0x02, 0x49, 0x44, 0x12, 0x0d, 0x0a, 0x09, 0x4d,
+	0x53, 0x47, 0x5f, 0x42, 0x45, 0x47, 0x49, 0x4e, 0x10, 0x00, 0x12, 0x0f, 0x0a, 0x0b, 0x4d, 0x53,
+	0x47, 0x5f, 0x43, 0x6f, 0x6e, 0x6e, 0x65, 0x63, 0x74, 0x10, 0x01, 0x12, 0x11, 0x0a, 0x0d, 0x4d,
+	0x53, 0x47, 0x5f, 0x48, 0x65, 0x61, 0x72, 0x74, 0x62, 0x65, 0x61, 0x74, 0x10, 0x02, 0x12, 0x10,
+	0x0a, 0x0c, 0x4d, 0x53, 0x47, 0x5f, 0x4a, 0x6f, 0x69, 0x6e, 0x52, 0x6f, 0x6f, 0x6d, 0x10, 0x0a,
+	0x12, 0x10, 0x0a, 0x0c, 0x4d, 0x53, 0x47, 0x5f, 0x50, 0x72, 0x6f, 0x67, 0x72, 0x65, 0x73, 0x73,
+	0x10, 0x14, 0x12, 0x0f, 0x0a, 0x0b, 0x4d, 0x53, 0x47, 0x5f, 0x4c, 0x6f, 0x61, 0x64, 0x6f, 0x75,
+	0x74, 0x10, 0x19, 0x12, 0x0d, 0x0a, 0x09, 0x4d, 0x53, 0x47, 0x5f, 0x52, 0x65, 0x61, 0x64, 0x79,
+	0x10, 0x1e, 0x12, 0x0d, 0x0a, 0x09, 0x4d, 0x53, 0x47, 0x5f, 0x53, 0x74, 0x61, 0x72, 0x74, 0x10,
+	0x28, 0x12, 0x0d, 0x0a, 0x09, 0x4d, 0x53, 0x47, 0x5f, 0x46, 0x72, 0x61, 0x6d, 0x65, 0x10, 0x32,
+	0x12, 0x0d, 0x0a, 0x09, 0x4d, 0x53, 0x47, 0x5f, 0x49, 0x6e, 0x70, 0x75, 0x74, 0x10, 0x3c, 0x12,
+	0x10, 0x0a, 0x0c, 0x4d, 0x53, 0x47, 0x5f, 0x43, 0x68, 0x65, 0x63, 0x6b, 0x73, 0x75, 0x6d, 0x10,
+	0x41, 0x12, 0x0e, 0x0a, 0x0a, 0x4d, 0x53, 0x47, 0x5f, 0x52, 0x65, 0x73, 0x75, 0x6c, 0x74, 0x10,
+	0x46, 0x12, 0x0e, 0x0a, 0x0a, 0x4d, 0x53, 0x47, 0x5f, 0x52, 0x6f, 0x73, 0x74, 0x65, 0x72, 0x10,
+	0x50, 0x12, 0x0d, 0x0a, 0x09, 0x4d, 0x53, 0x47, 0x5f, 0x43, 0x6c, 0x6f, 0x73, 0x65, 0x10, 0x64,
+	0x12, 0x0c, 0x0a, 0x07, 0x4d, 0x53, 0x47, 0x5f, 0x45, 0x4e, 0x44, 0x10, 0xff, 0x01, 0x2a, 0xb0,
+	0x01, 0x0a, 0x09, 0x45, 0x52, 0x52, 0x4f, 0x52, 0x43, 0x4f, 0x44, 0x45, 0x12, 0x0a, 0x0a, 0x06,
+	0x45, 0x52, 0x52, 0x5f, 0x6f, 0x6b, 0x10, 0x00, 0x12, 0x10, 0x0a, 0x0c, 0x45, 0x52, 0x52, 0x5f,
+	0x4e, 0x6f, 0x50, 0x6c, 0x61, 0x79, 0x65, 0x72, 0x10, 0x01, 0x12, 0x0e, 0x0a, 0x0a, 0x45, 0x52,
+	0x52, 0x5f, 0x4e, 0x6f, 0x52, 0x6f, 0x6f, 0x6d, 0x10, 0x02, 0x12, 0x11, 0x0a, 0x0d, 0x45, 0x52,
+	0x52, 0x5f, 0x52, 0x6f, 0x6f, 0x6d, 0x53, 0x74, 0x61, 0x74, 0x65, 0x10, 0x03, 0x12, 0x0d, 0x0a,
+	0x09, 0x45, 0x52, 0x52, 0x5f, 0x54, 0x6f, 0x6b, 0x65, 0x6e, 0x10, 0x04, 0x12, 0x10, 0x0a, 0x0c,
+	0x45, 0x52, 0x52, 0x5f, 0x52, 0x65, 0x64, 0x69, 0x72, 0x65, 0x63, 0x74, 0x10, 0x05, 0x12, 0x0f,
+	0x0a, 0x0b, 0x45, 0x52, 0x52, 0x5f, 0x56, 0x65, 0x72, 0x73, 0x69, 0x6f, 0x6e, 0x10, 0x06, 0x12,
+	0x10, 0x0a, 0x0c, 0x45, 0x52, 0x52, 0x5f, 0x43, 0x68, 0x65, 0x63, 0x6b, 0x73, 0x75, 0x6d, 0x10,
+	0x07, 0x12, 0x0e, 0x0a, 0x0a, 0x45, 0x52, 0x52, 0x5f, 0x54, 0x69, 0x63, 0x6b, 0x65, 0x74, 0x10,
+	0x08, 0x12, 0x0e, 0x0a, 0x0a, 0x45, 0x52, 0x52, 0x5f, 0x4b, 0x69, 0x63, 0x6b, 0x65, 0x64, 0x10,
+	0x09, 0x2a, 0x35, 0x0a, 0x08, 0x43, 0x48, 0x45, 0x43, 0x4b, 0x53, 0x55, 0x4d, 0x12, 0x0c, 0x0a,
+	0x08, 0x43, 0x48, 0x4b, 0x5f, 0x4e, 0x6f, 0x6e, 0x65, 0x10, 0x00, 0x12, 0x0d, 0x0a, 0x09, 0x43,
+	0x48, 0x4b, 0x5f, 0x43, 0x52, 0x43, 0x33, 0x32, 0x10, 0x01, 0x12, 0x0c, 0x0a, 0x08, 0x43, 0x48,
+	0x4b, 0x5f, 0x48, 0x4d, 0x41, 0x43, 0x10, 0x02, 0x42, 0x06, 0x5a, 0x04, 0x2e, 0x2f, 0x70, 0x62,
+	0x62, 0x06, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x33,
 }
 
 var (
@@ -901,32 +1486,44 @@ func file_message_proto_rawDescGZIP() []byte {
 	return file_message_proto_rawDescData
 }
 
-var file_message_proto_enumTypes = make([]protoimpl.EnumInfo, 2)
-var file_message_proto_msgTypes = make([]protoimpl.MessageInfo, 11)
+var file_message_proto_enumTypes = make([]protoimpl.EnumInfo, 3)
+var file_message_proto_msgTypes = make([]protoimpl.MessageInfo, 17)
 var file_message_proto_goTypes = []interface{}{
 	(ID)(0),                 // 0: pb.ID
 	(ERRORCODE)(0),          // 1: pb.ERRORCODE
-	(*C2S_ConnectMsg)(nil),  // 2: pb.C2S_ConnectMsg
-	(*S2C_ConnectMsg)(nil),  // 3: pb.S2C_ConnectMsg
-	(*S2C_JoinRoomMsg)(nil), // 4: pb.S2C_JoinRoomMsg
-	(*S2C_StartMsg)(nil),    // 5: pb.S2C_StartMsg
-	(*C2S_ProgressMsg)(nil), // 6: pb.C2S_ProgressMsg
-	(*S2C_ProgressMsg)(nil), // 7: pb.S2C_ProgressMsg
-	(*C2S_InputMsg)(nil),    // 8: pb.C2S_InputMsg
-	(*InputData)(nil),       // 9: pb.InputData
-	(*FrameData)(nil),       // 10: pb.FrameData
-	(*S2C_FrameMsg)(nil),    // 11: pb.S2C_FrameMsg
-	(*C2S_ResultMsg)(nil),   // 12: pb.C2S_ResultMsg
+	(CHECKSUM)(0),           // 2: pb.CHECKSUM
+	(*C2S_ConnectMsg)(nil),  // 3: pb.C2S_ConnectMsg
+	(*S2C_ConnectMsg)(nil),  // 4: pb.S2C_ConnectMsg
+	(*S2C_JoinRoomMsg)(nil), // 5: pb.S2C_JoinRoomMsg
+	(*S2C_StartMsg)(nil),    // 6: pb.S2C_StartMsg
+	(*Loadout)(nil),         // 7: pb.Loadout
+	(*C2S_LoadoutMsg)(nil),  // 8: pb.C2S_LoadoutMsg
+	(*S2C_LoadoutMsg)(nil),  // 9: pb.S2C_LoadoutMsg
+	(*C2S_ProgressMsg)(nil), // 10: pb.C2S_ProgressMsg
+	(*S2C_ProgressMsg)(nil), // 11: pb.S2C_ProgressMsg
+	(*C2S_InputMsg)(nil),    // 12: pb.C2S_InputMsg
+	(*InputData)(nil),       // 13: pb.InputData
+	(*FrameData)(nil),       // 14: pb.FrameData
+	(*S2C_FrameMsg)(nil),    // 15: pb.S2C_FrameMsg
+	(*S2C_RosterMsg)(nil),   // 16: pb.S2C_RosterMsg
+	(*C2S_ChecksumMsg)(nil), // 17: pb.C2S_ChecksumMsg
+	(*S2C_ChecksumMsg)(nil), // 18: pb.S2C_ChecksumMsg
+	(*C2S_ResultMsg)(nil),   // 19: pb.C2S_ResultMsg
 }
 var file_message_proto_depIdxs = []int32{
-	1,  // 0: pb.S2C_ConnectMsg.errorCode:type_name -> pb.ERRORCODE
-	9,  // 1: pb.FrameData.input:type_name -> pb.InputData
-	10, // 2: pb.S2C_FrameMsg.frames:type_name -> pb.FrameData
-	3,  // [3:3] is the sub-list for method output_type
-	3,  // [3:3] is the sub-list for method input_type
-	3,  // [3:3] is the sub-list for extension type_name
-	3,  // [3:3] is the sub-list for extension extendee
-	0,  // [0:3] is the sub-list for field type_name
+	2,  // 0: pb.C2S_ConnectMsg.checksum:type_name -> pb.CHECKSUM
+	1,  // 1: pb.S2C_ConnectMsg.errorCode:type_name -> pb.ERRORCODE
+	2,  // 2: pb.S2C_ConnectMsg.checksum:type_name -> pb.CHECKSUM
+	7,  // 3: pb.S2C_JoinRoomMsg.loadouts:type_name -> pb.Loadout
+	7,  // 4: pb.S2C_StartMsg.loadouts:type_name -> pb.Loadout
+	7,  // 5: pb.S2C_LoadoutMsg.loadout:type_name -> pb.Loadout
+	13, // 6: pb.FrameData.input:type_name -> pb.InputData
+	14, // 7: pb.S2C_FrameMsg.frames:type_name -> pb.FrameData
+	8,  // [8:8] is the sub-list for method output_type
+	8,  // [8:8] is the sub-list for method input_type
+	8,  // [8:8] is the sub-list for extension type_name
+	8,  // [8:8] is the sub-list for extension extendee
+	0,  // [0:8] is the sub-list for field type_name
 }
 
 func init() { file_message_proto_init() }
@@ -984,7 +1581,7 @@ func file_message_proto_init() {
 			}
 		}
 		file_message_proto_msgTypes[4].Exporter = func(v interface{}, i int) interface{} {
-			switch v := v.(*C2S_ProgressMsg); i {
+			switch v := v.(*Loadout); i {
 			case 0:
 				return &v.state
 			case 1:
@@ -996,7 +1593,7 @@ func file_message_proto_init() {
 			}
 		}
 		file_message_proto_msgTypes[5].Exporter = func(v interface{}, i int) interface{} {
-			switch v := v.(*S2C_ProgressMsg); i {
+			switch v := v.(*C2S_LoadoutMsg); i {
 			case 0:
 				return &v.state
 			case 1:
@@ -1008,7 +1605,7 @@ func file_message_proto_init() {
 			}
 		}
 		file_message_proto_msgTypes[6].Exporter = func(v interface{}, i int) interface{} {
-			switch v := v.(*C2S_InputMsg); i {
+			switch v := v.(*S2C_LoadoutMsg); i {
 			case 0:
 				return &v.state
 			case 1:
@@ -1020,7 +1617,7 @@ func file_message_proto_init() {
 			}
 		}
 		file_message_proto_msgTypes[7].Exporter = func(v interface{}, i int) interface{} {
-			switch v := v.(*InputData); i {
+			switch v := v.(*C2S_ProgressMsg); i {
 			case 0:
 				return &v.state
 			case 1:
@@ -1032,7 +1629,7 @@ func file_message_proto_init() {
 			}
 		}
 		file_message_proto_msgTypes[8].Exporter = func(v interface{}, i int) interface{} {
-			switch v := v.(*FrameData); i {
+			switch v := v.(*S2C_ProgressMsg); i {
 			case 0:
 				return &v.state
 			case 1:
@@ -1044,7 +1641,7 @@ func file_message_proto_init() {
 			}
 		}
 		file_message_proto_msgTypes[9].Exporter = func(v interface{}, i int) interface{} {
-			switch v := v.(*S2C_FrameMsg); i {
+			switch v := v.(*C2S_InputMsg); i {
 			case 0:
 				return &v.state
 			case 1:
@@ -1056,6 +1653,78 @@ func file_message_proto_init() {
 			}
 		}
 		file_message_proto_msgTypes[10].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*InputData); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_message_proto_msgTypes[11].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*FrameData); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_message_proto_msgTypes[12].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*S2C_FrameMsg); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_message_proto_msgTypes[13].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*S2C_RosterMsg); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_message_proto_msgTypes[14].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*C2S_ChecksumMsg); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_message_proto_msgTypes[15].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*S2C_ChecksumMsg); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_message_proto_msgTypes[16].Exporter = func(v interface{}, i int) interface{} {
 			switch v := v.(*C2S_ResultMsg); i {
 			case 0:
 				return &v.state
@@ -1077,14 +1746,20 @@ func file_message_proto_init() {
 	file_message_proto_msgTypes[6].OneofWrappers = []interface{}{}
 	file_message_proto_msgTypes[7].OneofWrappers = []interface{}{}
 	file_message_proto_msgTypes[8].OneofWrappers = []interface{}{}
+	file_message_proto_msgTypes[9].OneofWrappers = []interface{}{}
 	file_message_proto_msgTypes[10].OneofWrappers = []interface{}{}
+	file_message_proto_msgTypes[11].OneofWrappers = []interface{}{}
+	file_message_proto_msgTypes[13].OneofWrappers = []interface{}{}
+	file_message_proto_msgTypes[14].OneofWrappers = []interface{}{}
+	file_message_proto_msgTypes[15].OneofWrappers = []interface{}{}
+	file_message_proto_msgTypes[16].OneofWrappers = []interface{}{}
 	type x struct{}
 	out := protoimpl.TypeBuilder{
 		File: protoimpl.DescBuilder{
 			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
 			RawDescriptor: file_message_proto_rawDesc,
-			NumEnums:      2,
-			NumMessages:   11,
+			NumEnums:      3,
+			NumMessages:   17,
 			NumExtensions: 0,
 			NumServices:   0,
 		},
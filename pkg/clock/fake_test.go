@@ -0,0 +1,55 @@
+package clock
+
+import (
+	"testing"
+	"time"
+)
+
+func Test_Fake(t *testing.T) {
+	start := time.Unix(1000, 0)
+	f := NewFake(start)
+
+	ticker := f.NewTicker(time.Second)
+	timer := f.NewTimer(time.Second * 3)
+	after := f.After(time.Second * 2)
+
+	f.Advance(time.Millisecond * 999)
+	select {
+	case <-ticker.C():
+		t.Fatal("the ticker fires too early")
+	default:
+	}
+
+	f.Advance(time.Millisecond)
+	if now := <-ticker.C(); !now.Equal(start.Add(time.Second)) {
+		t.Errorf("tick at %v", now)
+	}
+
+	// the ticks not received are dropped
+	f.Advance(time.Second * 2)
+	if now := <-ticker.C(); !now.Equal(start.Add(time.Second * 2)) {
+		t.Errorf("tick at %v", now)
+	}
+	select {
+	case <-ticker.C():
+		t.Fatal("the tick should be dropped")
+	default:
+	}
+	if now := <-after; !now.Equal(start.Add(time.Second * 2)) {
+		t.Errorf("after at %v", now)
+	}
+	if now := <-timer.C(); !now.Equal(start.Add(time.Second * 3)) {
+		t.Errorf("timer at %v", now)
+	}
+	if timer.Stop() {
+		t.Error("the timer fired should not be stopped")
+	}
+	if !f.Now().Equal(start.Add(time.Second * 3)) {
+		t.Errorf("now is %v", f.Now())
+	}
+
+	ticker.Stop()
+	if f.Waiters() != 0 {
+		t.Errorf("waiters[%d] should be 0", f.Waiters())
+	}
+}
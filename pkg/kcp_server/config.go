@@ -0,0 +1,151 @@
+package kcp_server
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"time"
+
+	"github.com/hedon954/go-lock-step-server/pkg/network"
+	"github.com/xtaci/kcp-go"
+)
+
+// Crypt is the block crypt of the kcp packets
+type Crypt string
+
+const (
+	CryptNone    Crypt = ""
+	CryptAES     Crypt = "aes"
+	CryptSalsa20 Crypt = "salsa20"
+)
+
+// KCPConfig is the configuration of the kcp sessions,
+// the client must use the same FEC and crypt options as the server
+type KCPConfig struct {
+	// see kcp.UDPSession.SetNoDelay
+	NoDelay      int // 1 enables nodelay mode
+	Interval     int // the internal update interval in milliseconds
+	Resend       int // fast resend after this number of duplicated acks, 0 means disabled
+	NoCongestion int // 1 disables the congestion control
+
+	SndWnd      int  // the send window size in packets
+	RcvWnd      int  // the receive window size in packets
+	ReadBuffer  int  // the socket read buffer in bytes
+	WriteBuffer int  // the socket write buffer in bytes
+	StreamMode  bool // merges the packets like tcp
+	ACKNoDelay  bool // sends acks immediately
+
+	DataShards   int // the reed-solomon data shards, 0 means FEC is disabled
+	ParityShards int // the reed-solomon parity shards, 0 means FEC is disabled
+
+	Crypt Crypt  // the block crypt
+	Key   string // the shared key of the block crypt, it is stretched by sha256
+
+	ConnReadTimeout        time.Duration
+	ConnWriteTimeout       time.Duration
+	PacketSendChanLimit    uint32
+	PacketReceiveChanLimit uint32
+}
+
+// DefaultKCPConfig returns the default configuration, a low latency one with large windows
+func DefaultKCPConfig() *KCPConfig {
+	return &KCPConfig{
+		NoDelay:                1,
+		Interval:               10,
+		Resend:                 2,
+		NoCongestion:           1,
+		SndWnd:                 4096,
+		RcvWnd:                 4096,
+		ReadBuffer:             4 * 1024 * 1024,
+		WriteBuffer:            4 * 1024 * 1024,
+		StreamMode:             true,
+		ACKNoDelay:             true,
+		ConnReadTimeout:        time.Second * 5,
+		ConnWriteTimeout:       time.Second * 5,
+		PacketSendChanLimit:    1024,
+		PacketReceiveChanLimit: 1024,
+	}
+}
+
+// LANKCPConfig returns the configuration for local networks with little loss
+func LANKCPConfig() *KCPConfig {
+	c := DefaultKCPConfig()
+	c.SndWnd, c.RcvWnd = 1024, 1024
+	c.ReadBuffer, c.WriteBuffer = 1024*1024, 1024*1024
+	c.ConnReadTimeout, c.ConnWriteTimeout = time.Second*3, time.Second*3
+	return c
+}
+
+// MobileKCPConfig returns the configuration for mobile networks with jitter and short outages
+func MobileKCPConfig() *KCPConfig {
+	c := DefaultKCPConfig()
+	c.Interval = 20
+	c.SndWnd, c.RcvWnd = 512, 512
+	c.ReadBuffer, c.WriteBuffer = 1024*1024, 1024*1024
+	c.ConnReadTimeout, c.ConnWriteTimeout = time.Second*10, time.Second*10
+	return c
+}
+
+// LossyKCPConfig returns the configuration for networks with heavy packet loss,
+// FEC recovers the lost packets without waiting for the retransmission
+func LossyKCPConfig() *KCPConfig {
+	c := DefaultKCPConfig()
+	c.DataShards, c.ParityShards = 10, 3
+	c.ConnReadTimeout, c.ConnWriteTimeout = time.Second*10, time.Second*10
+	return c
+}
+
+// PresetKCPConfig returns the configuration of the preset name: default, lan, mobile or lossy
+func PresetKCPConfig(name string) (*KCPConfig, error) {
+	switch name {
+	case "", "default":
+		return DefaultKCPConfig(), nil
+	case "lan":
+		return LANKCPConfig(), nil
+	case "mobile":
+		return MobileKCPConfig(), nil
+	case "lossy":
+		return LossyKCPConfig(), nil
+	default:
+		return nil, fmt.Errorf("unknown kcp preset[%s]", name)
+	}
+}
+
+// BlockCrypt creates the block crypt of the configuration, it returns nil if the crypt is disabled
+func (c *KCPConfig) BlockCrypt() (kcp.BlockCrypt, error) {
+	if c.Crypt == CryptNone {
+		return nil, nil
+	}
+	if c.Key == "" {
+		return nil, fmt.Errorf("kcp crypt[%s] requires a key", c.Crypt)
+	}
+
+	key := sha256.Sum256([]byte(c.Key))
+	switch c.Crypt {
+	case CryptAES:
+		return kcp.NewAESBlockCrypt(key[:])
+	case CryptSalsa20:
+		return kcp.NewSalsa20BlockCrypt(key[:])
+	default:
+		return nil, fmt.Errorf("unknown kcp crypt[%s]", c.Crypt)
+	}
+}
+
+// Apply applies the options of the configuration to the session
+func (c *KCPConfig) Apply(sess *kcp.UDPSession) {
+	sess.SetNoDelay(c.NoDelay, c.Interval, c.Resend, c.NoCongestion)
+	sess.SetStreamMode(c.StreamMode)
+	sess.SetWindowSize(c.SndWnd, c.RcvWnd)
+	_ = sess.SetReadBuffer(c.ReadBuffer)
+	_ = sess.SetWriteBuffer(c.WriteBuffer)
+	sess.SetACKNoDelay(c.ACKNoDelay)
+}
+
+// networkConfig returns the configuration of the network server
+func (c *KCPConfig) networkConfig() *network.Config {
+	return &network.Config{
+		PacketReceiveChanLimit: c.PacketReceiveChanLimit,
+		PacketSendChanLimit:    c.PacketSendChanLimit,
+		ConnReadTimeout:        c.ConnReadTimeout,
+		ConnWriteTimeout:       c.ConnWriteTimeout,
+	}
+}
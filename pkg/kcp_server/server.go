@@ -2,38 +2,58 @@ package kcp_server
 
 import (
 	"net"
-	"time"
 
 	"github.com/hedon954/go-lock-step-server/pkg/network"
 	"github.com/xtaci/kcp-go"
 )
 
-func ListenAndServe(addr string, callback network.ConnCallback, protocol network.Protocol) (*network.Server, error) {
-	dupConfig := &network.Config{
-		PacketReceiveChanLimit: 1024,
-		PacketSendChanLimit:    1024,
-		ConnReadTimeout:        time.Second * 5,
-		ConnWriteTimeout:       time.Second * 5,
+// ListenAndServe listens on the udp address and serves the kcp sessions,
+// a nil config means DefaultKCPConfig
+func ListenAndServe(addr string, config *KCPConfig, callback network.ConnCallback, protocol network.Protocol) (
+	*network.Server, error,
+) {
+	if config == nil {
+		config = DefaultKCPConfig()
 	}
 
-	l, err := kcp.Listen(addr)
+	block, err := config.BlockCrypt()
 	if err != nil {
 		return nil, err
 	}
 
-	server := network.NewServer(dupConfig, callback, protocol)
-	go server.Start(l, func(conn net.Conn, i *network.Server) *network.Conn {
+	l, err := kcp.ListenWithOptions(addr, block, config.DataShards, config.ParityShards)
+	if err != nil {
+		return nil, err
+	}
+
+	server := network.NewServer(config.networkConfig(), callback, protocol)
+	server.Serve(l, func(conn net.Conn, i *network.Server) *network.Conn {
 
 		kcpConn := conn.(*kcp.UDPSession)
-		kcpConn.SetNoDelay(1, 10, 2, 1)
-		kcpConn.SetStreamMode(true)
-		kcpConn.SetWindowSize(4096, 4096)
-		kcpConn.SetReadBuffer(4 * 1024 * 1024)
-		kcpConn.SetWriteBuffer(4 * 1024 * 1024)
-		kcpConn.SetACKNoDelay(true)
+		config.Apply(kcpConn)
 
 		return network.NewConn(conn, i)
 	})
 
 	return server, nil
 }
+
+// Dial connects to the kcp server with the same configuration as the server,
+// a nil config means DefaultKCPConfig
+func Dial(addr string, config *KCPConfig) (*kcp.UDPSession, error) {
+	if config == nil {
+		config = DefaultKCPConfig()
+	}
+
+	block, err := config.BlockCrypt()
+	if err != nil {
+		return nil, err
+	}
+
+	sess, err := kcp.DialWithOptions(addr, block, config.DataShards, config.ParityShards)
+	if err != nil {
+		return nil, err
+	}
+	config.Apply(sess)
+	return sess, nil
+}
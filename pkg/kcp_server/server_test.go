@@ -0,0 +1,107 @@
+package kcp_server
+
+import (
+	"testing"
+	"time"
+
+	"github.com/hedon954/go-lock-step-server/pkg/network"
+)
+
+func Test_PresetKCPConfig(t *testing.T) {
+	for _, name := range []string{"", "default", "lan", "mobile", "lossy"} {
+		if _, err := PresetKCPConfig(name); err != nil {
+			t.Errorf("preset[%s] error: %v", name, err)
+		}
+	}
+	if _, err := PresetKCPConfig("satellite"); err == nil {
+		t.Error("unknown preset should fail")
+	}
+}
+
+func Test_BlockCrypt(t *testing.T) {
+	c := DefaultKCPConfig()
+	if block, err := c.BlockCrypt(); err != nil || block != nil {
+		t.Errorf("no crypt: block[%v] err[%v]", block, err)
+	}
+
+	c.Crypt = CryptAES
+	if _, err := c.BlockCrypt(); err == nil {
+		t.Error("crypt without key should fail")
+	}
+
+	c.Key = "secret"
+	for _, crypt := range []Crypt{CryptAES, CryptSalsa20} {
+		c.Crypt = crypt
+		if block, err := c.BlockCrypt(); err != nil || block == nil {
+			t.Errorf("crypt[%s]: block[%v] err[%v]", crypt, block, err)
+		}
+	}
+
+	c.Crypt = "rot13"
+	if _, err := c.BlockCrypt(); err == nil {
+		t.Error("unknown crypt should fail")
+	}
+}
+
+// Test_KCPServerOptions tests the kcp server with FEC and block crypt enabled
+func Test_KCPServerOptions(t *testing.T) {
+	tests := []struct {
+		addr  string
+		crypt Crypt
+	}{
+		{"127.0.0.1:10087", CryptAES},
+		{"127.0.0.1:10088", CryptSalsa20},
+	}
+
+	for _, tt := range tests {
+		config := LossyKCPConfig()
+		config.Crypt = tt.crypt
+		config.Key = "secret"
+
+		server, err := ListenAndServe(tt.addr, config, &echoCallback{}, &network.DefaultProtocol{})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if !ping(t, tt.addr, config) {
+			t.Errorf("crypt[%s] ping with the same config failed", tt.crypt)
+		}
+
+		// a client with a wrong key can not talk to the server
+		wrong := *config
+		wrong.Key = "wrong"
+		if ping(t, tt.addr, &wrong) {
+			t.Errorf("crypt[%s] ping with a wrong key should fail", tt.crypt)
+		}
+
+		server.Stop()
+	}
+}
+
+func ping(t *testing.T, addr string, config *KCPConfig) bool {
+	c, err := Dial(addr, config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	if _, err = c.Write(network.NewDefaultPacket([]byte("ping")).Serialize()); err != nil {
+		return false
+	}
+	_ = c.SetReadDeadline(time.Now().Add(time.Second))
+	p, err := (&network.DefaultProtocol{}).ReadPacket(c)
+	return err == nil && string(p.Serialize()[4:]) == "pong"
+}
+
+type echoCallback struct{}
+
+func (e *echoCallback) OnConnect(conn *network.Conn) bool {
+	return true
+}
+
+func (e *echoCallback) OnMessage(conn *network.Conn, packet network.Packet) bool {
+	return conn.AsyncWritePacket(network.NewDefaultPacket([]byte("pong")), time.Second) == nil
+}
+
+func (e *echoCallback) OnClose(conn *network.Conn) {
+}
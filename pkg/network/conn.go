@@ -20,6 +20,7 @@ var (
 type Conn struct {
 	srv               *Server
 	conn              net.Conn
+	protocol          Protocol
 	extraData         interface{}
 	closeOnce         sync.Once
 	closeFlag         int32
@@ -45,14 +46,19 @@ type ConnCallback interface {
 
 // NewConn creates a new connection
 func NewConn(conn net.Conn, srv *Server) *Conn {
-	return &Conn{
+	c := &Conn{
 		srv:               srv,
 		callback:          srv.callback,
 		conn:              conn,
+		protocol:          srv.protocol,
 		closeChan:         make(chan struct{}),
 		packetSendChan:    make(chan Packet, srv.config.PacketSendChanLimit),
 		packetReceiveChan: make(chan Packet, srv.config.PacketReceiveChanLimit),
 	}
+	if f, ok := srv.protocol.(ProtocolFactory); ok {
+		c.protocol = f.NewConnProtocol()
+	}
+	return c
 }
 
 // GetExtraData returns the extra data from the Conn
@@ -65,6 +71,11 @@ func (c *Conn) PutExtraData(data interface{}) {
 	c.extraData = data
 }
 
+// GetProtocol returns the protocol reading and writing the packets of the Conn
+func (c *Conn) GetProtocol() Protocol {
+	return c.protocol
+}
+
 // SetCallback sets the connection callback
 func (c *Conn) SetCallback(callback ConnCallback) {
 	c.callback = callback
@@ -98,6 +109,15 @@ func (c *Conn) AsyncWritePacket(p Packet, timeout time.Duration) (err error) {
 		return ErrConnClosing
 	}
 
+	if sp, ok := p.(SharedPacket); ok {
+		// it is released by the write loop once encoded
+		sp.Retain()
+		defer func() {
+			if err != nil {
+				sp.Release()
+			}
+		}()
+	}
 	defer func() {
 		if e := recover(); e != nil {
 			err = ErrConnClosing
@@ -159,7 +179,7 @@ func (c *Conn) readLoop() {
 		}
 
 		_ = c.conn.SetReadDeadline(time.Now().Add(c.srv.config.ConnReadTimeout))
-		p, err := c.srv.protocol.ReadPacket(c.conn)
+		p, err := c.protocol.ReadPacket(c.conn)
 		if err != nil {
 			return
 		}
@@ -184,8 +204,16 @@ func (c *Conn) writeLoop() {
 			if c.IsClosed() {
 				return
 			}
+			buf, err := c.encode(p)
+			if sp, ok := p.(SharedPacket); ok {
+				sp.Release()
+			}
+			if err != nil {
+				log4go.Error("encode packet error: %v\n", err)
+				return
+			}
 			_ = c.conn.SetWriteDeadline(time.Now().Add(c.srv.config.ConnWriteTimeout))
-			if _, err := c.conn.Write(p.Serialize()); err != nil {
+			if _, err := c.conn.Write(buf); err != nil {
 				log4go.Error("write packet error: %v\n", err)
 				return
 			}
@@ -193,6 +221,14 @@ func (c *Conn) writeLoop() {
 	}
 }
 
+// encode encodes the packet with the protocol of the connection
+func (c *Conn) encode(p Packet) ([]byte, error) {
+	if e, ok := c.protocol.(PacketEncoder); ok {
+		return e.EncodePacket(p)
+	}
+	return p.Serialize(), nil
+}
+
 // handleLoop is a loop to handle OnMessage event
 func (c *Conn) handleLoop() {
 	defer func() {
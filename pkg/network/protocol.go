@@ -18,6 +18,26 @@ type Protocol interface {
 	ReadPacket(conn io.Reader) (Packet, error)
 }
 
+// ProtocolFactory is implemented by the protocols keeping per-connection state,
+// every connection reads and writes with its own protocol created by NewConnProtocol
+type ProtocolFactory interface {
+	NewConnProtocol() Protocol
+}
+
+// PacketEncoder is implemented by the protocols encoding the packets written to the connection,
+// the packets are written as Serialize returns otherwise
+type PacketEncoder interface {
+	EncodePacket(p Packet) ([]byte, error)
+}
+
+// SharedPacket is a packet written to many connections, every connection retains it when it is queued,
+// and releases it once it is encoded. A packet dropped with a closed connection is left to the gc
+type SharedPacket interface {
+	Packet
+	Retain()
+	Release()
+}
+
 type DefaultPacket struct {
 	buff []byte
 }
@@ -37,10 +37,29 @@ func NewServer(config *Config, callback ConnCallback, protocol Protocol) *Server
 // ConnectionCreator is a creator to create connection
 type ConnectionCreator func(net.Conn, *Server) *Conn
 
-// Start starts service
+// Start starts service, it blocks until the server stops
 func (s *Server) Start(listener net.Listener, creator ConnectionCreator) {
 	s.listener = listener
 	s.waitGroup.Add(1)
+	s.accept(creator)
+}
+
+// Serve starts service in background, Addr is available once it returns
+func (s *Server) Serve(listener net.Listener, creator ConnectionCreator) {
+	s.listener = listener
+	s.waitGroup.Add(1)
+	go s.accept(creator)
+}
+
+// Addr returns the address the server listens on, it is nil before the service starts
+func (s *Server) Addr() net.Addr {
+	if s.listener == nil {
+		return nil
+	}
+	return s.listener.Addr()
+}
+
+func (s *Server) accept(creator ConnectionCreator) {
 	defer s.waitGroup.Done()
 
 	for {
@@ -0,0 +1,91 @@
+package pb_packet
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"hash"
+	"hash/crc32"
+
+	"github.com/hedon954/go-lock-step-server/pb"
+)
+
+const (
+	SeqLen = 4 // the length of the sequence number following the data
+
+	CRC32Len = crc32.Size
+	HMACLen  = sha256.Size
+)
+
+var (
+	ErrChecksumMismatch = errors.New("the checksum of packet mismatches")
+	ErrPacketDuplicated = errors.New("the packet is duplicated")
+	ErrPacketOutOfOrder = errors.New("the packet is out of order")
+)
+
+// checksum is the packet checksum negotiated by the connection
+type checksum struct {
+	mode pb.CHECKSUM
+	key  []byte
+}
+
+// newChecksum returns nil if the mode is CHK_None or unknown
+func newChecksum(mode pb.CHECKSUM, key []byte) *checksum {
+	switch mode {
+	case pb.CHECKSUM_CHK_CRC32, pb.CHECKSUM_CHK_HMAC:
+		return &checksum{mode: mode, key: append([]byte(nil), key...)}
+	}
+	return nil
+}
+
+// size returns the length of the trailer following the data
+func (c *checksum) size() int {
+	if c.mode == pb.CHECKSUM_CHK_HMAC {
+		return SeqLen + HMACLen
+	}
+	return SeqLen + CRC32Len
+}
+
+// sum returns the checksum of the message id, the data and the sequence number
+func (c *checksum) sum(id uint8, data []byte, seq []byte) []byte {
+	var h hash.Hash
+	if c.mode == pb.CHECKSUM_CHK_HMAC {
+		h = hmac.New(sha256.New, c.key)
+	} else {
+		h = crc32.NewIEEE()
+	}
+	h.Write([]byte{id})
+	h.Write(data)
+	h.Write(seq)
+	return h.Sum(nil)
+}
+
+// seal appends the trailer of the packet to the serialized buff
+func (c *checksum) seal(buff []byte, p *Packet, seq uint32) []byte {
+	var s [SeqLen]byte
+	binary.BigEndian.PutUint32(s[:], seq)
+	buff = append(buff, s[:]...)
+	return append(buff, c.sum(p.id, p.data, s[:])...)
+}
+
+// open verifies the trailer of the packet and returns its sequence number
+func (c *checksum) open(p *Packet, trailer []byte) (uint32, error) {
+	seq := trailer[:SeqLen]
+	if !hmac.Equal(trailer[SeqLen:], c.sum(p.id, p.data, seq)) {
+		return 0, ErrChecksumMismatch
+	}
+	return binary.BigEndian.Uint32(seq), nil
+}
+
+// checkSeq checks the sequence number received follows the last one
+func checkSeq(last, seq uint32) error {
+	expect := last + 1
+	if seq == expect {
+		return nil
+	}
+	if int32(seq-expect) < 0 {
+		return ErrPacketDuplicated
+	}
+	return ErrPacketOutOfOrder
+}
@@ -0,0 +1,164 @@
+package pb_packet
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/hedon954/go-lock-step-server/pb"
+	"google.golang.org/protobuf/proto"
+)
+
+// newChecksumPair returns the protocols of the server sending and the client receiving
+func newChecksumPair(mode pb.CHECKSUM) (sender, receiver *MsgProtocol) {
+	sender, receiver = &MsgProtocol{}, &MsgProtocol{client: true}
+	sender.SetChecksum(mode, []byte("token"))
+	receiver.SetChecksum(mode, []byte("token"))
+	return sender, receiver
+}
+
+func encodeAll(t *testing.T, mp *MsgProtocol, packets ...*Packet) [][]byte {
+	var ret [][]byte
+	for _, p := range packets {
+		buff, err := mp.EncodePacket(p)
+		if err != nil {
+			t.Fatal(err)
+		}
+		ret = append(ret, buff)
+	}
+	return ret
+}
+
+func Test_ChecksumRoundTrip(t *testing.T) {
+	for _, mode := range []pb.CHECKSUM{pb.CHECKSUM_CHK_CRC32, pb.CHECKSUM_CHK_HMAC} {
+		sender, receiver := newChecksumPair(mode)
+		sender.SetVersion(ProtocolVersion2)
+		receiver.SetVersion(ProtocolVersion2)
+
+		packets := []*Packet{
+			NewPacket(uint8(pb.ID_MSG_Input), &pb.C2S_InputMsg{Sid: proto.Int32(1)}),
+			NewPacket(uint8(pb.ID_MSG_Heartbeat), nil),
+			NewPacket(uint8(pb.ID_MSG_Frame), bytes.Repeat([]byte{7}, 100*1024)),
+		}
+		r := bytes.NewReader(bytes.Join(encodeAll(t, sender, packets...), nil))
+		for i, want := range packets {
+			ret, err := receiver.ReadPacket(r)
+			if err != nil {
+				t.Fatalf("mode[%s] packet[%d] read error: %v", mode, i, err)
+			}
+			if got := ret.(*Packet); got.id != want.id || !bytes.Equal(got.data, want.data) {
+				t.Errorf("mode[%s] packet[%d] read a different packet", mode, i)
+			}
+		}
+		if r.Len() != 0 {
+			t.Errorf("mode[%s] [%d] bytes are left", mode, r.Len())
+		}
+	}
+}
+
+func Test_ChecksumTampered(t *testing.T) {
+	for _, mode := range []pb.CHECKSUM{pb.CHECKSUM_CHK_CRC32, pb.CHECKSUM_CHK_HMAC} {
+		sender, _ := newChecksumPair(mode)
+		buff := encodeAll(t, sender, NewPacket(uint8(pb.ID_MSG_Input), []byte{1, 2, 3, 4}))[0]
+
+		// flip every bit after the length, the id, the data, the sequence number and the checksum are covered
+		for i := DataLen; i < len(buff); i++ {
+			for bit := 0; bit < 8; bit++ {
+				tampered := append([]byte(nil), buff...)
+				tampered[i] ^= 1 << bit
+
+				_, receiver := newChecksumPair(mode)
+				if _, err := receiver.ReadPacket(bytes.NewReader(tampered)); err == nil {
+					t.Fatalf("mode[%s] byte[%d] bit[%d] tampered packet is accepted", mode, i, bit)
+				}
+			}
+		}
+	}
+
+	// the HMAC key is the token
+	sender, _ := newChecksumPair(pb.CHECKSUM_CHK_HMAC)
+	buff := encodeAll(t, sender, NewPacket(uint8(pb.ID_MSG_Input), []byte{1}))[0]
+	receiver := &MsgProtocol{}
+	receiver.SetChecksum(pb.CHECKSUM_CHK_HMAC, []byte("another token"))
+	if _, err := receiver.ReadPacket(bytes.NewReader(buff)); err != ErrChecksumMismatch {
+		t.Errorf("want: %v, got: %v", ErrChecksumMismatch, err)
+	}
+}
+
+func Test_ChecksumSequence(t *testing.T) {
+	sender, _ := newChecksumPair(pb.CHECKSUM_CHK_CRC32)
+	p := NewPacket(uint8(pb.ID_MSG_Input), []byte{1})
+	buffs := encodeAll(t, sender, p, p, p)
+
+	tests := []struct {
+		order []int
+		err   error
+	}{
+		{[]int{0, 1, 2}, nil},
+		{[]int{0, 0}, ErrPacketDuplicated},
+		{[]int{0, 1, 0}, ErrPacketDuplicated},
+		{[]int{1}, ErrPacketOutOfOrder},
+		{[]int{0, 2}, ErrPacketOutOfOrder},
+	}
+	for _, tt := range tests {
+		_, receiver := newChecksumPair(pb.CHECKSUM_CHK_CRC32)
+		var err error
+		for _, i := range tt.order {
+			if _, err = receiver.ReadPacket(bytes.NewReader(buffs[i])); err != nil {
+				break
+			}
+		}
+		if err != tt.err {
+			t.Errorf("order%v want: %v, got: %v", tt.order, tt.err, err)
+		}
+	}
+
+	// the sequence number wraps around
+	if err := checkSeq(^uint32(0), 0); err != nil {
+		t.Errorf("wrapped sequence number should be accepted: %v", err)
+	}
+}
+
+func Test_ClientProtocolAccept(t *testing.T) {
+	server := &MsgProtocol{}
+	client := NewClientProtocol([]byte("token"))
+
+	// the packets before MSG_Connect have no checksum
+	reply := NewPacket(uint8(pb.ID_MSG_Connect), &pb.S2C_ConnectMsg{
+		ErrorCode:       pb.ERRORCODE_ERR_ok.Enum(),
+		ProtocolVersion: proto.Uint32(ProtocolVersion2),
+		Checksum:        pb.CHECKSUM_CHK_HMAC.Enum(),
+	})
+	server.SetVersion(ProtocolVersion2)
+	server.SetChecksum(pb.CHECKSUM_CHK_HMAC, []byte("token"))
+	buffs := encodeAll(t, server, reply, NewPacket(uint8(pb.ID_MSG_JoinRoom), []byte{1}))
+
+	// the client applies the negotiation before it reads the next packet
+	r := bytes.NewReader(bytes.Join(buffs, nil))
+	for i := range buffs {
+		if _, err := client.ReadPacket(r); err != nil {
+			t.Fatalf("packet[%d] read error: %v", i, err)
+		}
+	}
+	if client.Version() != ProtocolVersion2 || client.Checksum() != pb.CHECKSUM_CHK_HMAC {
+		t.Errorf("client version[%d] checksum[%s]", client.Version(), client.Checksum())
+	}
+
+	// the client sends with the negotiated checksum
+	buff := encodeAll(t, client, NewPacket(uint8(pb.ID_MSG_Ready), nil))[0]
+	if _, err := server.ReadPacket(bytes.NewReader(buff)); err != nil {
+		t.Errorf("server read error: %v", err)
+	}
+
+	// a failed negotiation changes nothing
+	client = NewClientProtocol(nil)
+	buff = encodeAll(t, server, NewPacket(uint8(pb.ID_MSG_Connect), &pb.S2C_ConnectMsg{
+		ErrorCode: pb.ERRORCODE_ERR_Checksum.Enum(),
+		Checksum:  pb.CHECKSUM_CHK_HMAC.Enum(),
+	}))[0]
+	if _, err := client.ReadPacket(bytes.NewReader(buff)); err != nil {
+		t.Fatal(err)
+	}
+	if client.Checksum() != pb.CHECKSUM_CHK_None {
+		t.Errorf("client checksum[%s] should be CHK_None", client.Checksum())
+	}
+}
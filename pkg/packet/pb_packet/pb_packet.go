@@ -3,8 +3,12 @@ package pb_packet
 import (
 	"encoding/binary"
 	"io"
+	"math"
+	"sync"
+	"sync/atomic"
 
 	"github.com/alecthomas/log4go"
+	"github.com/hedon954/go-lock-step-server/pb"
 	"github.com/hedon954/go-lock-step-server/pkg/network"
 	"google.golang.org/protobuf/proto"
 )
@@ -12,18 +16,53 @@ import (
 const (
 	DataLen      = 2
 	MessageIDLen = 1
+	ExtDataLen   = 4
 
 	MinPacketLen = DataLen + MessageIDLen
-	MaxPacketLen = (2 << 8) * DataLen
-	MaxMessageID = (2 << 8) * MessageIDLen
+	MaxPacketLen = math.MaxUint16 // the maximum data length of the base header
+	MaxMessageID = math.MaxUint8
+
+	// ExtDataLenFlag in the field `totalDataLen` means the extended field `extDataLen` follows the message id
+	ExtDataLenFlag = math.MaxUint16
+
+	// MaxExtPacketLen is the maximum data length of the extended header
+	MaxExtPacketLen = 4 * 1024 * 1024
+
+	// MaxInboundDataLen is the maximum data length of the packets the server reads from the clients,
+	// the large packets are the frame batches sent by the server only
+	MaxInboundDataLen = 16 * 1024
+
+	// kMaxPooledLen is the capacity of the largest buffer kept in the pool of the shared packets
+	kMaxPooledLen = 64 * 1024
+)
+
+const (
+	// ProtocolVersion1 uses the base header only
+	ProtocolVersion1 uint32 = 1
+
+	// ProtocolVersion2 uses the extended header for the data longer than MaxPacketLen-1
+	ProtocolVersion2 uint32 = 2
+
+	MinProtocolVersion = ProtocolVersion1 // the lowest protocol version supported
+	MaxProtocolVersion = ProtocolVersion2 // the highest protocol version supported
 )
 
 /*
 
-s -> c
+base header
 
 |--totalDataLen(uint16)--|--msgIDLen(uint8)--|--------------data--------------|
-|-------------2----------|---------1---------|---------(totalDataLen-2-1)-----|
+|-------------2----------|---------1---------|---------(totalDataLen)---------|
+
+extended header (ProtocolVersion2), totalDataLen is ExtDataLenFlag
+
+|--totalDataLen(uint16)--|--msgIDLen(uint8)--|--extDataLen(uint32)--|--------------data--------------|
+|-------------2----------|---------1---------|----------4-----------|----------(extDataLen)----------|
+
+checksum (negotiated in MSG_Connect), the trailer follows the data of every packet but MSG_Connect,
+the sequence numbers start from 1 in both directions, and the data length does not count the trailer
+
+|--header--|--data--|--seq(uint32)--|--checksum(CRC32: 4, HMAC: 32)--|
 
 */
 
@@ -31,6 +70,19 @@ s -> c
 type Packet struct {
 	id   uint8
 	data []byte
+
+	// the pooled buffer of a shared packet, it is nil for the others
+	buff *[]byte
+	refs int32
+}
+
+var _ network.SharedPacket = (*Packet)(nil)
+
+var bufferPool = sync.Pool{
+	New: func() interface{} {
+		buff := make([]byte, 0, 1024)
+		return &buff
+	},
 }
 
 func (p *Packet) GetMessageID() uint8 {
@@ -41,18 +93,44 @@ func (p *Packet) GetData() []byte {
 	return p.data
 }
 
+// Serialize serializes the packet with the base header,
+// or the extended header if the data does not fit in the base one
 func (p *Packet) Serialize() []byte {
-	buff := make([]byte, MinPacketLen, MinPacketLen)
+	buff, err := p.SerializeVersion(MaxProtocolVersion)
+	if err != nil {
+		log4go.Error("[Packet] serialize msg: %d data length: %d error: %v", p.id, len(p.data), err)
+	}
+	return buff
+}
 
-	// set field `totalDataLen`
+// SerializeVersion serializes the packet with the protocol version,
+// it returns network.ErrDataLengthOutOfLimit if the data does not fit in the header of the version
+func (p *Packet) SerializeVersion(version uint32) ([]byte, error) {
 	dataLen := len(p.data)
-	binary.BigEndian.PutUint16(buff, uint16(dataLen))
 
-	// set field `msgIDLen`
-	buff[DataLen] = p.id
+	// MSG_Connect is exchanged before the negotiation, and a full uint16 length is valid before ProtocolVersion2
+	if dataLen < ExtDataLenFlag || (dataLen == ExtDataLenFlag && version < ProtocolVersion2) {
+		buff := make([]byte, MinPacketLen, MinPacketLen+dataLen)
+
+		// set field `totalDataLen`
+		binary.BigEndian.PutUint16(buff, uint16(dataLen))
+
+		// set field `msgIDLen`
+		buff[DataLen] = p.id
+
+		// set field `data`
+		return append(buff, p.data...), nil
+	}
+
+	if version < ProtocolVersion2 || p.id == uint8(pb.ID_MSG_Connect) || dataLen > MaxExtPacketLen {
+		return nil, network.ErrDataLengthOutOfLimit
+	}
 
-	// set field `data`
-	return append(buff, p.data...)
+	buff := make([]byte, MinPacketLen+ExtDataLen, MinPacketLen+ExtDataLen+dataLen)
+	binary.BigEndian.PutUint16(buff, ExtDataLenFlag)
+	buff[DataLen] = p.id
+	binary.BigEndian.PutUint32(buff[MinPacketLen:], uint32(dataLen))
+	return append(buff, p.data...), nil
 }
 
 func (p *Packet) UnmarshalPB(msg proto.Message) error {
@@ -82,11 +160,149 @@ func NewPacket(id uint8, msg interface{}) *Packet {
 		return nil
 	}
 
+	if len(p.data) > MaxExtPacketLen {
+		log4go.Error("[NewPacket] msg: %d data length: %d is out of limit", id, len(p.data))
+		return nil
+	}
+
 	return p
 }
 
-// MsgProtocol is used to read message according to protocol
+// NewSharedPacket creates a packet of the message marshaled into a pooled buffer, the creator holds a reference.
+// The buffer is reused once every reference is released, so the packet must not be read after its Release
+func NewSharedPacket(id uint8, msg proto.Message) *Packet {
+	buff := bufferPool.Get().(*[]byte)
+	data, err := proto.MarshalOptions{}.MarshalAppend((*buff)[:0], msg)
+	if err != nil {
+		bufferPool.Put(buff)
+		log4go.Error("[NewSharedPacket] proto marshal msg: %d error: %v", id, err)
+		return nil
+	}
+	if len(data) > MaxExtPacketLen {
+		bufferPool.Put(buff)
+		log4go.Error("[NewSharedPacket] msg: %d data length: %d is out of limit", id, len(data))
+		return nil
+	}
+	return &Packet{
+		id:   id,
+		data: data,
+		buff: buff,
+		refs: 1,
+	}
+}
+
+// Retain adds a reference of the shared packet, it does nothing to the others
+func (p *Packet) Retain() {
+	if p.buff != nil {
+		atomic.AddInt32(&p.refs, 1)
+	}
+}
+
+// Release drops a reference of the shared packet, and returns its buffer to the pool once nobody refers to it,
+// it does nothing to the others
+func (p *Packet) Release() {
+	if p.buff == nil || atomic.AddInt32(&p.refs, -1) != 0 {
+		return
+	}
+	if cap(p.data) <= kMaxPooledLen {
+		*p.buff = p.data[:0]
+		bufferPool.Put(p.buff)
+	}
+	p.data = nil
+}
+
+// MaxDataLen returns the maximum data length of a packet sent with the protocol version
+func MaxDataLen(version uint32) int {
+	if version >= ProtocolVersion2 {
+		return MaxExtPacketLen
+	}
+	return MaxPacketLen
+}
+
+// MsgProtocol is used to read message according to protocol,
+// it keeps the protocol version and the packet checksum negotiated by the connection.
+// MSG_Connect is always framed with ProtocolVersion1 and without checksum,
+// as it is exchanged before the negotiation completes
 type MsgProtocol struct {
+	version  uint32
+	checksum atomic.Value // *checksum
+	sendSeq  uint32
+	recvSeq  uint32
+
+	client bool
+	key    []byte
+}
+
+// NewClientProtocol creates the protocol of a client connection,
+// it applies the version and the checksum negotiated once it reads the reply of MSG_Connect,
+// key is the HMAC key which is the token sent in MSG_Connect
+func NewClientProtocol(key []byte) *MsgProtocol {
+	return &MsgProtocol{
+		client: true,
+		key:    key,
+	}
+}
+
+// NewConnProtocol creates the protocol of a new connection, it implements network.ProtocolFactory
+func (p *MsgProtocol) NewConnProtocol() network.Protocol {
+	return &MsgProtocol{
+		client: p.client,
+		key:    p.key,
+	}
+}
+
+// Version returns the negotiated protocol version
+func (p *MsgProtocol) Version() uint32 {
+	if v := atomic.LoadUint32(&p.version); v != 0 {
+		return v
+	}
+	return ProtocolVersion1
+}
+
+// SetVersion sets the negotiated protocol version,
+// the client must not use it before it receives the reply of MSG_Connect
+func (p *MsgProtocol) SetVersion(v uint32) {
+	atomic.StoreUint32(&p.version, v)
+}
+
+// Checksum returns the negotiated packet checksum
+func (p *MsgProtocol) Checksum() pb.CHECKSUM {
+	if c := p.loadChecksum(); c != nil {
+		return c.mode
+	}
+	return pb.CHECKSUM_CHK_None
+}
+
+// SetChecksum sets the negotiated packet checksum, key is used by CHK_HMAC only,
+// it is sent in clear in MSG_Connect, so CHK_HMAC detects the corrupted packets as CHK_CRC32 does.
+// The packets after MSG_Connect are checked since then, so the server sets it before the reply of MSG_Connect,
+// and the client must not send anything else before it receives the reply
+func (p *MsgProtocol) SetChecksum(mode pb.CHECKSUM, key []byte) {
+	p.checksum.Store(newChecksum(mode, key))
+}
+
+func (p *MsgProtocol) loadChecksum() *checksum {
+	c, _ := p.checksum.Load().(*checksum)
+	return c
+}
+
+// EncodePacket encodes the packet with the negotiated protocol version, it implements network.PacketEncoder
+func (p *MsgProtocol) EncodePacket(packet network.Packet) ([]byte, error) {
+	msg, ok := packet.(*Packet)
+	if !ok {
+		return packet.Serialize(), nil
+	}
+	if msg.id == uint8(pb.ID_MSG_Connect) {
+		return msg.SerializeVersion(ProtocolVersion1)
+	}
+	buff, err := msg.SerializeVersion(p.Version())
+	if err != nil {
+		return nil, err
+	}
+	if c := p.loadChecksum(); c != nil {
+		buff = c.seal(buff, msg, atomic.AddUint32(&p.sendSeq, 1))
+	}
+	return buff, nil
 }
 
 func (p *MsgProtocol) ReadPacket(r io.Reader) (network.Packet, error) {
@@ -96,16 +312,31 @@ func (p *MsgProtocol) ReadPacket(r io.Reader) (network.Packet, error) {
 	if _, err := io.ReadFull(r, buff); err != nil {
 		return nil, err
 	}
-	dataLen := binary.BigEndian.Uint16(buff)
-	if dataLen > MaxPacketLen {
-		return nil, network.ErrDataLengthOutOfLimit
-	}
+	dataLen := int(binary.BigEndian.Uint16(buff))
 
 	// set id
 	msg := &Packet{
 		id: buff[DataLen],
 	}
 
+	// read extended data length, the version is checked after the header arrives,
+	// as the client switches the version once it receives the reply of MSG_Connect
+	if dataLen == ExtDataLenFlag && msg.id != uint8(pb.ID_MSG_Connect) && p.Version() >= ProtocolVersion2 {
+		ext := make([]byte, ExtDataLen)
+		if _, err := io.ReadFull(r, ext); err != nil {
+			return nil, err
+		}
+		extLen := binary.BigEndian.Uint32(ext)
+		// lengths fitting in the base header must not use the extended one
+		if extLen < ExtDataLenFlag || extLen > MaxExtPacketLen {
+			return nil, network.ErrDataLengthOutOfLimit
+		}
+		dataLen = int(extLen)
+	}
+	if !p.client && dataLen > MaxInboundDataLen {
+		return nil, network.ErrDataLengthOutOfLimit
+	}
+
 	// read data
 	if dataLen > 0 {
 		msg.data = make([]byte, dataLen, dataLen)
@@ -114,5 +345,63 @@ func (p *MsgProtocol) ReadPacket(r io.Reader) (network.Packet, error) {
 		}
 	}
 
+	if msg.id == uint8(pb.ID_MSG_Connect) {
+		if p.client {
+			p.accept(msg)
+		}
+		return msg, nil
+	}
+
+	// read and verify checksum
+	if c := p.loadChecksum(); c != nil {
+		trailer := make([]byte, c.size())
+		if _, err := io.ReadFull(r, trailer); err != nil {
+			return nil, err
+		}
+		seq, err := c.open(msg, trailer)
+		if err != nil {
+			return nil, err
+		}
+		if err = checkSeq(p.recvSeq, seq); err != nil {
+			return nil, err
+		}
+		p.recvSeq = seq
+	}
+
 	return msg, nil
 }
+
+// accept applies the version and the checksum negotiated in the reply of MSG_Connect,
+// before the client handles the reply and reads the next packet
+func (p *MsgProtocol) accept(msg *Packet) {
+	ret := &pb.S2C_ConnectMsg{}
+	if err := msg.UnmarshalPB(ret); err != nil || ret.GetErrorCode() != pb.ERRORCODE_ERR_ok {
+		return
+	}
+	if v := ret.GetProtocolVersion(); v != 0 {
+		p.SetVersion(v)
+	}
+	p.SetChecksum(ret.GetChecksum(), p.key)
+}
+
+// VersionOf returns the protocol version negotiated by the connection,
+// it is ProtocolVersion1 if the connection does not use MsgProtocol
+func VersionOf(conn interface{}) uint32 {
+	if c, ok := conn.(interface{ GetProtocol() network.Protocol }); ok {
+		if mp, ok := c.GetProtocol().(*MsgProtocol); ok {
+			return mp.Version()
+		}
+	}
+	return ProtocolVersion1
+}
+
+// ChecksumOf returns the packet checksum negotiated by the connection,
+// it is CHK_None if the connection does not use MsgProtocol
+func ChecksumOf(conn interface{}) pb.CHECKSUM {
+	if c, ok := conn.(interface{ GetProtocol() network.Protocol }); ok {
+		if mp, ok := c.GetProtocol().(*MsgProtocol); ok {
+			return mp.Checksum()
+		}
+	}
+	return pb.CHECKSUM_CHK_None
+}
@@ -8,6 +8,7 @@ import (
 	"testing"
 
 	"github.com/hedon954/go-lock-step-server/pb"
+	"github.com/hedon954/go-lock-step-server/pkg/network"
 	"google.golang.org/protobuf/proto"
 )
 
@@ -139,3 +140,193 @@ func Benchmark_Packet(b *testing.B) {
 		}
 	}
 }
+
+func Test_PacketLength(t *testing.T) {
+	tests := []struct {
+		dataLen int
+		version uint32
+		ok      bool
+		ext     bool
+	}{
+		{0, ProtocolVersion1, true, false},
+		{1024, ProtocolVersion1, true, false},
+		{MaxInboundDataLen, ProtocolVersion1, true, false},
+		{MaxInboundDataLen + 1, ProtocolVersion1, true, false},
+		{MaxPacketLen, ProtocolVersion1, true, false},
+		{MaxPacketLen + 1, ProtocolVersion1, false, false},
+		{MaxPacketLen - 1, ProtocolVersion2, true, false},
+		{MaxPacketLen, ProtocolVersion2, true, true},
+		{100 * 1024, ProtocolVersion2, true, true},
+		{MaxExtPacketLen + 1, ProtocolVersion2, false, false},
+	}
+
+	for _, tt := range tests {
+		p := &Packet{
+			id:   uint8(pb.ID_MSG_Frame),
+			data: bytes.Repeat([]byte{0xAB}, tt.dataLen),
+		}
+
+		buff, err := p.SerializeVersion(tt.version)
+		if (err == nil) != tt.ok {
+			t.Errorf("len[%d] version[%d] want: ok[%v], got: err[%v]", tt.dataLen, tt.version, tt.ok, err)
+			continue
+		}
+		if !tt.ok {
+			continue
+		}
+
+		headerLen := MinPacketLen
+		if tt.ext {
+			headerLen += ExtDataLen
+		}
+		if len(buff) != headerLen+tt.dataLen {
+			t.Errorf("len[%d] version[%d] serialized length[%d] should be [%d]", tt.dataLen, tt.version, len(buff),
+				headerLen+tt.dataLen)
+		}
+
+		mp := NewClientProtocol(nil)
+		mp.SetVersion(tt.version)
+		ret, err := mp.ReadPacket(bytes.NewReader(buff))
+		if err != nil {
+			t.Errorf("len[%d] version[%d] read error: %v", tt.dataLen, tt.version, err)
+			continue
+		}
+		if got := ret.(*Packet); got.id != p.id || !bytes.Equal(got.data, p.data) {
+			t.Errorf("len[%d] version[%d] read a different packet", tt.dataLen, tt.version)
+		}
+
+		// the server reads the small packets of the clients only
+		mp = &MsgProtocol{}
+		mp.SetVersion(tt.version)
+		_, err = mp.ReadPacket(bytes.NewReader(buff))
+		if tt.dataLen > MaxInboundDataLen && err != network.ErrDataLengthOutOfLimit {
+			t.Errorf("len[%d] version[%d] server read got: %v", tt.dataLen, tt.version, err)
+		} else if tt.dataLen <= MaxInboundDataLen && err != nil {
+			t.Errorf("len[%d] version[%d] server read error: %v", tt.dataLen, tt.version, err)
+		}
+	}
+}
+
+func Test_ConnectPacketVersion(t *testing.T) {
+	mp := &MsgProtocol{}
+	mp.SetVersion(ProtocolVersion2)
+
+	// MSG_Connect is always framed with the base header
+	connect := &Packet{id: uint8(pb.ID_MSG_Connect), data: bytes.Repeat([]byte{1}, MaxPacketLen)}
+	buff, err := mp.EncodePacket(connect)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(buff) != MinPacketLen+MaxPacketLen {
+		t.Errorf("MSG_Connect should use the base header, length[%d]", len(buff))
+	}
+	ret, err := NewClientProtocol(nil).ReadPacket(bytes.NewReader(buff))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ret.(*Packet).data) != MaxPacketLen {
+		t.Errorf("MSG_Connect data length[%d] should be [%d]", len(ret.(*Packet).data), MaxPacketLen)
+	}
+
+	connect.data = append(connect.data, 1)
+	if _, err = mp.EncodePacket(connect); err == nil {
+		t.Error("MSG_Connect longer than MaxPacketLen should fail")
+	}
+}
+
+func Test_VersionOf(t *testing.T) {
+	if v := VersionOf(nil); v != ProtocolVersion1 {
+		t.Errorf("version[%d] should be [%d]", v, ProtocolVersion1)
+	}
+
+	mp := (&MsgProtocol{}).NewConnProtocol().(*MsgProtocol)
+	mp.SetVersion(ProtocolVersion2)
+	if v := VersionOf(&protocolHolder{mp}); v != ProtocolVersion2 {
+		t.Errorf("version[%d] should be [%d]", v, ProtocolVersion2)
+	}
+}
+
+type protocolHolder struct {
+	p network.Protocol
+}
+
+func (h *protocolHolder) GetProtocol() network.Protocol {
+	return h.p
+}
+
+func FuzzReadPacket(f *testing.F) {
+	small, _ := (&Packet{id: uint8(pb.ID_MSG_Input), data: []byte{8, 1, 16, 2}}).SerializeVersion(ProtocolVersion1)
+	large, _ := (&Packet{id: uint8(pb.ID_MSG_Frame), data: make([]byte, MaxPacketLen)}).SerializeVersion(
+		ProtocolVersion2)
+	f.Add(small, uint32(1))
+	f.Add(small, uint32(2))
+	f.Add(large[:MinPacketLen+ExtDataLen+16], uint32(2))
+	f.Add([]byte{0xFF, 0xFF, 0x01, 0xFF, 0xFF, 0xFF, 0xFF}, uint32(2))
+	f.Add([]byte{0x00}, uint32(1))
+
+	f.Fuzz(func(t *testing.T, data []byte, version uint32) {
+		mp := &MsgProtocol{}
+		mp.SetVersion(version)
+
+		r := bytes.NewReader(data)
+		ret, err := mp.ReadPacket(r)
+		if err != nil {
+			return
+		}
+		p := ret.(*Packet)
+		if len(p.data) > MaxExtPacketLen {
+			t.Fatalf("data length[%d] is out of limit", len(p.data))
+		}
+
+		// the packet read is encoded back to the bytes consumed
+		consumed := data[:len(data)-r.Len()]
+		buff, err := mp.EncodePacket(p)
+		if err != nil {
+			t.Fatalf("encode the packet read error: %v", err)
+		}
+		if !bytes.Equal(buff, consumed) {
+			t.Fatalf("want: %x, got: %x", consumed, buff)
+		}
+	})
+}
+
+func Test_SharedPacket(t *testing.T) {
+	msg := &pb.S2C_FrameMsg{Frames: []*pb.FrameData{{FrameID: proto.Uint32(7)}}}
+	want, _ := proto.Marshal(msg)
+
+	p := NewSharedPacket(uint8(pb.ID_MSG_Frame), msg)
+	p.Retain()
+	p.Retain()
+	if !bytes.Equal(p.GetData(), want) {
+		t.Fatalf("data got: %v, want: %v", p.GetData(), want)
+	}
+	p.Release()
+	p.Release()
+	if !bytes.Equal(p.GetData(), want) {
+		t.Fatal("the packet is released while referred by its creator")
+	}
+	p.Release()
+	if p.GetData() != nil {
+		t.Error("the packet should be released once nobody refers to it")
+	}
+
+	// the message out of the limit is not created
+	huge := &pb.S2C_FrameMsg{Frames: []*pb.FrameData{{Input: make([]*pb.InputData, MaxExtPacketLen/2)}}}
+	for _, f := range huge.Frames {
+		for i := range f.Input {
+			f.Input[i] = &pb.InputData{}
+		}
+	}
+	if p := NewSharedPacket(uint8(pb.ID_MSG_Frame), huge); p != nil {
+		t.Error("the packet out of the limit should not be created")
+	}
+
+	// the packets not shared are never released
+	p = NewPacket(uint8(pb.ID_MSG_Frame), msg)
+	p.Retain()
+	p.Release()
+	p.Release()
+	if !bytes.Equal(p.GetData(), want) {
+		t.Error("the packet not shared is released")
+	}
+}
@@ -0,0 +1,46 @@
+package ratelimit
+
+import (
+	"time"
+)
+
+// TokenBucket is a token bucket rate limiter,
+// it is not safe for concurrent use
+type TokenBucket struct {
+	rate   float64 // tokens added per second
+	burst  float64 // the capacity of the bucket
+	tokens float64
+	last   time.Time
+}
+
+// NewTokenBucket creates a full token bucket which allows rate events per second with bursts of at most burst events
+func NewTokenBucket(rate float64, burst int) *TokenBucket {
+	if burst < 1 {
+		burst = 1
+	}
+	return &TokenBucket{
+		rate:   rate,
+		burst:  float64(burst),
+		tokens: float64(burst),
+	}
+}
+
+// Allow reports whether an event may happen at now, it consumes a token if so
+func (b *TokenBucket) Allow(now time.Time) bool {
+	if !b.last.IsZero() {
+		elapsed := now.Sub(b.last).Seconds()
+		if elapsed > 0 {
+			b.tokens += elapsed * b.rate
+			if b.tokens > b.burst {
+				b.tokens = b.burst
+			}
+		}
+	}
+	b.last = now
+
+	if b.tokens < 1 {
+		return false
+	}
+	b.tokens--
+	return true
+}
@@ -0,0 +1,40 @@
+package ratelimit
+
+import (
+	"testing"
+	"time"
+)
+
+func Test_TokenBucket(t *testing.T) {
+	b := NewTokenBucket(10, 5)
+	now := time.Now()
+
+	// the bucket starts full
+	for i := 0; i < 5; i++ {
+		if !b.Allow(now) {
+			t.Fatalf("event[%d] should be allowed by the burst", i)
+		}
+	}
+	if b.Allow(now) {
+		t.Fatal("event should be limited after the burst")
+	}
+
+	// 10 tokens per second, one token every 100ms
+	now = now.Add(time.Millisecond * 100)
+	if !b.Allow(now) {
+		t.Error("event should be allowed after refilling a token")
+	}
+	if b.Allow(now) {
+		t.Error("event should be limited after consuming the refilled token")
+	}
+
+	// the bucket never holds more than the burst
+	now = now.Add(time.Hour)
+	n := 0
+	for b.Allow(now) {
+		n++
+	}
+	if n != 5 {
+		t.Errorf("allowed[%d] should be [5]", n)
+	}
+}
@@ -0,0 +1,35 @@
+package tcp_server
+
+import (
+	"net"
+	"time"
+
+	"github.com/hedon954/go-lock-step-server/pkg/network"
+)
+
+func ListenAndServe(addr string, callback network.ConnCallback, protocol network.Protocol) (*network.Server, error) {
+	dupConfig := &network.Config{
+		PacketReceiveChanLimit: 1024,
+		PacketSendChanLimit:    1024,
+		ConnReadTimeout:        time.Second * 5,
+		ConnWriteTimeout:       time.Second * 5,
+	}
+
+	l, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+
+	server := network.NewServer(dupConfig, callback, protocol)
+	server.Serve(l, func(conn net.Conn, i *network.Server) *network.Conn {
+
+		if tcpConn, ok := conn.(*net.TCPConn); ok {
+			_ = tcpConn.SetNoDelay(true)
+			_ = tcpConn.SetKeepAlive(true)
+		}
+
+		return network.NewConn(conn, i)
+	})
+
+	return server, nil
+}
@@ -0,0 +1,139 @@
+package ws_server
+
+import (
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/hedon954/go-lock-step-server/pkg/network"
+	"golang.org/x/net/websocket"
+)
+
+func ListenAndServe(addr string, path string, callback network.ConnCallback, protocol network.Protocol) (
+	*network.Server, error,
+) {
+	dupConfig := &network.Config{
+		PacketReceiveChanLimit: 1024,
+		PacketSendChanLimit:    1024,
+		ConnReadTimeout:        time.Second * 5,
+		ConnWriteTimeout:       time.Second * 5,
+	}
+
+	l, err := Listen(addr, path)
+	if err != nil {
+		return nil, err
+	}
+
+	server := network.NewServer(dupConfig, callback, protocol)
+	server.Serve(l, func(conn net.Conn, i *network.Server) *network.Conn {
+		return network.NewConn(conn, i)
+	})
+
+	return server, nil
+}
+
+// Listen announces on the tcp address and accepts websocket connections on the http path,
+// every packet is sent in a binary frame while the frames received are read as a stream
+func Listen(addr string, path string) (net.Listener, error) {
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+
+	l := &listener{
+		ln:        ln,
+		conns:     make(chan net.Conn),
+		closeChan: make(chan struct{}),
+	}
+
+	mux := http.NewServeMux()
+	// websocket.Server accepts any origin, unlike websocket.Handler
+	mux.Handle(path, websocket.Server{Handler: l.handle})
+	l.srv = &http.Server{Handler: mux}
+	go func() {
+		_ = l.srv.Serve(ln)
+	}()
+
+	return l, nil
+}
+
+// listener is a net.Listener of websocket connections
+type listener struct {
+	ln        net.Listener
+	srv       *http.Server
+	conns     chan net.Conn
+	closeOnce sync.Once
+	closeChan chan struct{}
+}
+
+// handle hands the websocket connection to Accept and holds the http handler until the connection is closed
+func (l *listener) handle(ws *websocket.Conn) {
+	ws.PayloadType = websocket.BinaryFrame
+	c := &conn{
+		Conn:   ws,
+		remote: remoteAddr(ws.Request().RemoteAddr),
+		done:   make(chan struct{}),
+	}
+
+	select {
+	case l.conns <- c:
+	case <-l.closeChan:
+		return
+	}
+	<-c.done
+}
+
+func (l *listener) Accept() (net.Conn, error) {
+	select {
+	case c := <-l.conns:
+		return c, nil
+	case <-l.closeChan:
+		return nil, net.ErrClosed
+	}
+}
+
+func (l *listener) Close() error {
+	var err error
+	l.closeOnce.Do(func() {
+		close(l.closeChan)
+		err = l.srv.Close()
+	})
+	return err
+}
+
+func (l *listener) Addr() net.Addr {
+	return l.ln.Addr()
+}
+
+// conn is a websocket connection accepted by the listener
+type conn struct {
+	*websocket.Conn
+	remote    net.Addr
+	closeOnce sync.Once
+	done      chan struct{}
+}
+
+func (c *conn) Close() error {
+	err := c.Conn.Close()
+	c.closeOnce.Do(func() {
+		close(c.done)
+	})
+	return err
+}
+
+// RemoteAddr returns the address of the peer instead of the origin returned by websocket.Conn
+func (c *conn) RemoteAddr() net.Addr {
+	return c.remote
+}
+
+// remoteAddr is the address of the peer of a websocket connection
+type remoteAddr string
+
+func (a remoteAddr) Network() string {
+	return "websocket"
+}
+
+func (a remoteAddr) String() string {
+	return string(a)
+}
@@ -0,0 +1,110 @@
+package ws_server
+
+import (
+	"net"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/hedon954/go-lock-step-server/pkg/network"
+	"golang.org/x/net/websocket"
+)
+
+// Test_WebSocketServer tests websocket server
+func Test_WebSocketServer(t *testing.T) {
+	l, err := Listen("127.0.0.1:0", "/ws")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	config := &network.Config{
+		PacketReceiveChanLimit: 64,
+		PacketSendChanLimit:    64,
+		ConnReadTimeout:        time.Second,
+		ConnWriteTimeout:       time.Second,
+	}
+
+	callback := &testCallback{}
+	server := network.NewServer(config, callback, &network.DefaultProtocol{})
+	go server.Start(l, func(conn net.Conn, i *network.Server) *network.Conn {
+		return network.NewConn(conn, i)
+	})
+	defer server.Stop()
+
+	url := "ws://" + l.Addr().String() + "/ws"
+
+	wg := sync.WaitGroup{}
+	const maxConn = 20
+	for i := 0; i < maxConn; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			c, err := websocket.Dial(url, "", "http://localhost/")
+			if err != nil {
+				t.Errorf("connect websocket server failed: %v", err)
+				return
+			}
+			defer c.Close()
+			c.PayloadType = websocket.BinaryFrame
+
+			// a packet split into several frames is read as a stream
+			buf := network.NewDefaultPacket([]byte("ping")).Serialize()
+			if _, err = c.Write(buf[:3]); err != nil {
+				t.Errorf("ping websocket server failed: %v", err)
+				return
+			}
+			if _, err = c.Write(buf[3:]); err != nil {
+				t.Errorf("ping websocket server failed: %v", err)
+				return
+			}
+
+			_ = c.SetReadDeadline(time.Now().Add(time.Second))
+			p, err := (&network.DefaultProtocol{}).ReadPacket(c)
+			if err != nil {
+				t.Errorf("read from websocket server failed: %v", err)
+				return
+			}
+			if string(p.Serialize()[4:]) != "pong" {
+				t.Errorf("want: pong, got: %s", p.Serialize()[4:])
+			}
+		}()
+	}
+	wg.Wait()
+
+	deadline := time.Now().Add(2 * time.Second)
+	for atomic.LoadUint32(&callback.numDiscon) != maxConn && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond * 10)
+	}
+
+	if n := atomic.LoadUint32(&callback.numConn); n != maxConn {
+		t.Errorf("numConn[%d] should be [%d]", n, maxConn)
+	}
+	if n := atomic.LoadUint32(&callback.numMsg); n != maxConn {
+		t.Errorf("numMsg[%d] should be [%d]", n, maxConn)
+	}
+	if n := atomic.LoadUint32(&callback.numDiscon); n != maxConn {
+		t.Errorf("numDiscon[%d] should be [%d]", n, maxConn)
+	}
+}
+
+type testCallback struct {
+	numConn   uint32
+	numMsg    uint32
+	numDiscon uint32
+}
+
+func (t *testCallback) OnConnect(conn *network.Conn) bool {
+	atomic.AddUint32(&t.numConn, 1)
+	return true
+}
+
+func (t *testCallback) OnMessage(conn *network.Conn, packet network.Packet) bool {
+	atomic.AddUint32(&t.numMsg, 1)
+	return conn.AsyncWritePacket(network.NewDefaultPacket([]byte("pong")), time.Second) == nil
+}
+
+func (t *testCallback) OnClose(conn *network.Conn) {
+	atomic.AddUint32(&t.numDiscon, 1)
+}
@@ -0,0 +1,25 @@
+package server
+
+import (
+	"github.com/hedon954/go-lock-step-server/pb"
+)
+
+// ChecksumPolicy is the packet checksum accepted by the server,
+// the clients requesting none while one is required are rejected with ERR_Checksum.
+// The checksums detect the corrupted, dropped or replayed packets only, CHK_HMAC is keyed by the token
+// sent in clear in MSG_Connect, so it is no stronger than CHK_CRC32 against an attacker on the path
+type ChecksumPolicy struct {
+	Required pb.CHECKSUM // any value but CHK_None requires a checksum, CHK_None accepts the clients without checksum
+}
+
+// negotiate returns the checksum used with the client, it is the requested one,
+// ok is false if the requested one is unknown, or it is CHK_None while a checksum is required
+func (c *ChecksumPolicy) negotiate(requested pb.CHECKSUM) (checksum pb.CHECKSUM, ok bool) {
+	if _, known := pb.CHECKSUM_name[int32(requested)]; !known {
+		return pb.CHECKSUM_CHK_None, false
+	}
+	if requested == pb.CHECKSUM_CHK_None && c.Required != pb.CHECKSUM_CHK_None {
+		return pb.CHECKSUM_CHK_None, false
+	}
+	return requested, true
+}
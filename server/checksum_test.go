@@ -0,0 +1,31 @@
+package server
+
+import (
+	"testing"
+
+	"github.com/hedon954/go-lock-step-server/pb"
+)
+
+func Test_ChecksumPolicyNegotiate(t *testing.T) {
+	tests := []struct {
+		policy    ChecksumPolicy
+		requested pb.CHECKSUM
+		checksum  pb.CHECKSUM
+		ok        bool
+	}{
+		{ChecksumPolicy{}, pb.CHECKSUM_CHK_None, pb.CHECKSUM_CHK_None, true},
+		{ChecksumPolicy{}, pb.CHECKSUM_CHK_HMAC, pb.CHECKSUM_CHK_HMAC, true},
+		{ChecksumPolicy{Required: pb.CHECKSUM_CHK_CRC32}, pb.CHECKSUM_CHK_None, pb.CHECKSUM_CHK_None, false},
+		{ChecksumPolicy{Required: pb.CHECKSUM_CHK_CRC32}, pb.CHECKSUM_CHK_CRC32, pb.CHECKSUM_CHK_CRC32, true},
+		{ChecksumPolicy{Required: pb.CHECKSUM_CHK_CRC32}, pb.CHECKSUM_CHK_HMAC, pb.CHECKSUM_CHK_HMAC, true},
+		{ChecksumPolicy{Required: pb.CHECKSUM_CHK_HMAC}, pb.CHECKSUM_CHK_CRC32, pb.CHECKSUM_CHK_CRC32, true},
+		{ChecksumPolicy{Required: pb.CHECKSUM_CHK_HMAC}, pb.CHECKSUM_CHK_None, pb.CHECKSUM_CHK_None, false},
+		{ChecksumPolicy{}, pb.CHECKSUM(100), pb.CHECKSUM_CHK_None, false},
+	}
+	for i, tt := range tests {
+		checksum, ok := tt.policy.negotiate(tt.requested)
+		if checksum != tt.checksum || ok != tt.ok {
+			t.Errorf("case[%d] want: (%v, %v), got: (%v, %v)", i, tt.checksum, tt.ok, checksum, ok)
+		}
+	}
+}
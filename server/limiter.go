@@ -0,0 +1,227 @@
+package server
+
+import (
+	"container/list"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/alecthomas/log4go"
+	"github.com/hedon954/go-lock-step-server/pb"
+	"github.com/hedon954/go-lock-step-server/pkg/network"
+	"github.com/hedon954/go-lock-step-server/pkg/packet/pb_packet"
+	"github.com/hedon954/go-lock-step-server/pkg/ratelimit"
+)
+
+// MaxAbusivePeers is the number of the peer hosts whose limited packets are counted,
+// the host limited least recently is forgotten once a new one exceeds it
+const MaxAbusivePeers = 4096
+
+// LimitAction is the action taken on a packet exceeding the rate limits
+type LimitAction int
+
+const (
+	LimitActionDrop       LimitAction = iota // drop the packet
+	LimitActionWarn                          // log a warning and handle the packet anyway
+	LimitActionDisconnect                    // close the connection
+)
+
+// Limit is a token bucket limit
+type Limit struct {
+	Rate  float64 // packets per second, 0 means unlimited
+	Burst int     // the maximum burst of packets
+}
+
+// RateLimitConfig is the configuration of the per-connection rate limits,
+// the zero value disables rate limiting
+type RateLimitConfig struct {
+	Conn     Limit           // the limit of all packets of a connection
+	Messages map[pb.ID]Limit // the limits of the packets of a message id
+	Action   LimitAction     // the action taken on the packets exceeding the limits
+}
+
+// DefaultRateLimitConfig returns a rate limit configuration suitable for 30 fps games
+func DefaultRateLimitConfig() RateLimitConfig {
+	return RateLimitConfig{
+		Conn: Limit{Rate: 200, Burst: 400},
+		Messages: map[pb.ID]Limit{
+			pb.ID_MSG_Connect:   {Rate: 1, Burst: 3},
+			pb.ID_MSG_Heartbeat: {Rate: 5, Burst: 10},
+			pb.ID_MSG_JoinRoom:  {Rate: 1, Burst: 5},
+			pb.ID_MSG_Progress:  {Rate: 10, Burst: 20},
+			pb.ID_MSG_Ready:     {Rate: 1, Burst: 5},
+			pb.ID_MSG_Input:     {Rate: 60, Burst: 90},
+			pb.ID_MSG_Result:    {Rate: 1, Burst: 5},
+			pb.ID_MSG_END:       {Rate: 5, Burst: 10},
+		},
+		Action: LimitActionDisconnect,
+	}
+}
+
+func (c *RateLimitConfig) enabled() bool {
+	if c.Conn.Rate > 0 {
+		return true
+	}
+	for _, l := range c.Messages {
+		if l.Rate > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// connLimiter holds the token buckets of a connection,
+// it is only used by the goroutine handling the messages of the connection
+type connLimiter struct {
+	all      *ratelimit.TokenBucket
+	messages map[pb.ID]*ratelimit.TokenBucket
+}
+
+// rateLimiter limits the packets of every connection
+type rateLimiter struct {
+	config RateLimitConfig
+	mu     sync.Mutex
+	conns  map[*network.Conn]*connLimiter
+	abuses map[string]*list.Element // the limited packets of each peer host, in lru
+	lru    *list.List               // the abuses from the most recently limited
+}
+
+// abuse is the number of limited packets of a peer host
+type abuse struct {
+	peer  string
+	count uint64
+}
+
+func newRateLimiter(config RateLimitConfig) *rateLimiter {
+	return &rateLimiter{
+		config: config,
+		conns:  make(map[*network.Conn]*connLimiter),
+		abuses: make(map[string]*list.Element),
+		lru:    list.New(),
+	}
+}
+
+func (l *rateLimiter) getConnLimiter(conn *network.Conn) *connLimiter {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	cl, ok := l.conns[conn]
+	if !ok {
+		cl = &connLimiter{
+			messages: make(map[pb.ID]*ratelimit.TokenBucket),
+		}
+		if l.config.Conn.Rate > 0 {
+			cl.all = ratelimit.NewTokenBucket(l.config.Conn.Rate, l.config.Conn.Burst)
+		}
+		for id, limit := range l.config.Messages {
+			if limit.Rate > 0 {
+				cl.messages[id] = ratelimit.NewTokenBucket(limit.Rate, limit.Burst)
+			}
+		}
+		l.conns[conn] = cl
+	}
+	return cl
+}
+
+// allow checks the packet against the limits of the connection,
+// handle indicates whether the packet should be handled and keep whether the connection should be kept
+func (l *rateLimiter) allow(conn *network.Conn, p network.Packet) (handle bool, keep bool) {
+	msg, ok := p.(*pb_packet.Packet)
+	if !ok {
+		return true, true
+	}
+
+	cl := l.getConnLimiter(conn)
+	now := time.Now()
+	allowed := cl.all == nil || cl.all.Allow(now)
+	if b, ok := cl.messages[pb.ID(msg.GetMessageID())]; ok && allowed {
+		allowed = b.Allow(now)
+	}
+	if allowed {
+		return true, true
+	}
+
+	peer := peerHost(conn)
+	count := l.addAbuse(peer)
+
+	switch l.config.Action {
+	case LimitActionWarn:
+		log4go.Warn("[router] rate limited peer=[%s] msg=[%d] count=[%d]", peer, msg.GetMessageID(), count)
+		return true, true
+	case LimitActionDisconnect:
+		log4go.Error("[router] rate limited peer=[%s] msg=[%d] count=[%d], disconnect", peer,
+			msg.GetMessageID(), count)
+		return false, false
+	default:
+		log4go.Debug("[router] rate limited peer=[%s] msg=[%d] count=[%d], drop", peer, msg.GetMessageID(), count)
+		return false, true
+	}
+}
+
+// addAbuse counts a limited packet of the peer host and returns the count
+func (l *rateLimiter) addAbuse(peer string) uint64 {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	e, ok := l.abuses[peer]
+	if ok {
+		l.lru.MoveToFront(e)
+	} else {
+		if l.lru.Len() >= MaxAbusivePeers {
+			oldest := l.lru.Back()
+			l.lru.Remove(oldest)
+			delete(l.abuses, oldest.Value.(*abuse).peer)
+		}
+		e = l.lru.PushFront(&abuse{peer: peer})
+		l.abuses[peer] = e
+	}
+	a := e.Value.(*abuse)
+	a.count++
+	return a.count
+}
+
+// remove forgets the connection
+func (l *rateLimiter) remove(conn *network.Conn) {
+	l.mu.Lock()
+	delete(l.conns, conn)
+	l.mu.Unlock()
+}
+
+// abusivePeers returns the number of limited packets of each peer host
+func (l *rateLimiter) abusivePeers() map[string]uint64 {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	ret := make(map[string]uint64, len(l.abuses))
+	for peer, e := range l.abuses {
+		ret[peer] = e.Value.(*abuse).count
+	}
+	return ret
+}
+
+func peerHost(conn *network.Conn) string {
+	addr := conn.GetRawConn().RemoteAddr().String()
+	if host, _, err := net.SplitHostPort(addr); err == nil {
+		return host
+	}
+	return addr
+}
+
+// limitedCallback applies the rate limits of the server before handing packets to the wrapped callback
+type limitedCallback struct {
+	network.ConnCallback
+	limiter *rateLimiter
+}
+
+func (c *limitedCallback) OnMessage(conn *network.Conn, p network.Packet) bool {
+	handle, keep := c.limiter.allow(conn, p)
+	if !handle {
+		return keep
+	}
+	return c.ConnCallback.OnMessage(conn, p)
+}
+
+func (c *limitedCallback) OnClose(conn *network.Conn) {
+	c.limiter.remove(conn)
+	c.ConnCallback.OnClose(conn)
+}
@@ -0,0 +1,112 @@
+package server
+
+import (
+	"fmt"
+	"net"
+	"testing"
+
+	"github.com/hedon954/go-lock-step-server/pb"
+	"github.com/hedon954/go-lock-step-server/pkg/network"
+	"github.com/hedon954/go-lock-step-server/pkg/packet/pb_packet"
+)
+
+func newTestConn(t *testing.T) *network.Conn {
+	c1, c2 := net.Pipe()
+	t.Cleanup(func() {
+		c1.Close()
+		c2.Close()
+	})
+	srv := network.NewServer(&network.Config{}, nil, &pb_packet.MsgProtocol{})
+	return network.NewConn(c1, srv)
+}
+
+func Test_RateLimiter(t *testing.T) {
+	tests := []struct {
+		action LimitAction
+		handle bool
+		keep   bool
+	}{
+		{LimitActionDrop, false, true},
+		{LimitActionWarn, true, true},
+		{LimitActionDisconnect, false, false},
+	}
+
+	for _, tt := range tests {
+		l := newRateLimiter(RateLimitConfig{
+			Messages: map[pb.ID]Limit{
+				pb.ID_MSG_Input: {Rate: 0.001, Burst: 2},
+			},
+			Action: tt.action,
+		})
+		conn := newTestConn(t)
+		input := pb_packet.NewPacket(uint8(pb.ID_MSG_Input), nil)
+
+		for i := 0; i < 2; i++ {
+			if handle, keep := l.allow(conn, input); !handle || !keep {
+				t.Fatalf("action[%d] packet[%d] should be allowed by the burst", tt.action, i)
+			}
+		}
+		handle, keep := l.allow(conn, input)
+		if handle != tt.handle || keep != tt.keep {
+			t.Errorf("action[%d] want: handle[%v] keep[%v], got: handle[%v] keep[%v]", tt.action, tt.handle,
+				tt.keep, handle, keep)
+		}
+
+		// other messages are not limited
+		if handle, keep = l.allow(conn, pb_packet.NewPacket(uint8(pb.ID_MSG_Heartbeat), nil)); !handle || !keep {
+			t.Errorf("action[%d] heartbeat should be allowed", tt.action)
+		}
+
+		if n := l.abusivePeers()[peerHost(conn)]; n != 1 {
+			t.Errorf("action[%d] abuses[%d] should be [1]", tt.action, n)
+		}
+
+		l.remove(conn)
+		if len(l.conns) != 0 {
+			t.Errorf("action[%d] the connection should be forgotten", tt.action)
+		}
+	}
+}
+
+func Test_RateLimiterConn(t *testing.T) {
+	l := newRateLimiter(RateLimitConfig{
+		Conn:   Limit{Rate: 0.001, Burst: 3},
+		Action: LimitActionDrop,
+	})
+	a, b := newTestConn(t), newTestConn(t)
+	p := pb_packet.NewPacket(uint8(pb.ID_MSG_Progress), nil)
+
+	for i := 0; i < 3; i++ {
+		if handle, _ := l.allow(a, p); !handle {
+			t.Fatalf("packet[%d] should be allowed by the burst", i)
+		}
+	}
+	if handle, _ := l.allow(a, p); handle {
+		t.Error("packet should be dropped after the burst")
+	}
+
+	// the limits are per connection
+	if handle, _ := l.allow(b, p); !handle {
+		t.Error("packet of another connection should be allowed")
+	}
+}
+
+func Test_RateLimiterAbusesBounded(t *testing.T) {
+	l := newRateLimiter(RateLimitConfig{})
+	// the first host is limited again with every new one, so that it is never the least recent
+	l.addAbuse("10.0.0.0")
+	for i := 1; i < MaxAbusivePeers+10; i++ {
+		l.addAbuse(fmt.Sprintf("10.0.%d.%d", i/256, i%256))
+		l.addAbuse("10.0.0.0")
+	}
+	peers := l.abusivePeers()
+	if len(peers) != MaxAbusivePeers || l.lru.Len() != MaxAbusivePeers {
+		t.Fatalf("%d peers are kept", len(peers))
+	}
+	if peers["10.0.0.0"] != MaxAbusivePeers+10 {
+		t.Errorf("the host limited recently got: %d", peers["10.0.0.0"])
+	}
+	if _, ok := peers["10.0.0.1"]; ok {
+		t.Error("the host limited least recently should be forgotten")
+	}
+}
@@ -5,16 +5,13 @@ import (
 	"time"
 
 	"github.com/alecthomas/log4go"
+	"github.com/hedon954/go-lock-step-server/logic/room"
 	"github.com/hedon954/go-lock-step-server/pb"
 	"github.com/hedon954/go-lock-step-server/pkg/network"
 	"github.com/hedon954/go-lock-step-server/pkg/packet/pb_packet"
+	"google.golang.org/protobuf/proto"
 )
 
-// TODO
-func verifyToken(token string) string {
-	return token
-}
-
 func (r *LockStepServer) OnConnect(conn *network.Conn) bool {
 	count := atomic.AddInt64(&r.totalConn, 1)
 	log4go.Debug("[router] OnConnect [%s] totalConn=%d", conn.GetRawConn().RemoteAddr().String(), count)
@@ -24,6 +21,12 @@ func (r *LockStepServer) OnConnect(conn *network.Conn) bool {
 }
 
 func (r *LockStepServer) OnMessage(conn *network.Conn, p network.Packet) bool {
+	if r.limiter != nil {
+		if handle, keep := r.limiter.allow(conn, p); !handle {
+			return keep
+		}
+	}
+
 	msg := p.(*pb_packet.Packet)
 	log4go.Info("[router] OnMessage [%s] msg=[%d] len=[%d]", conn.GetRawConn().RemoteAddr().String(),
 		msg.GetMessageID(), len(msg.GetData()))
@@ -35,30 +38,67 @@ func (r *LockStepServer) OnMessage(conn *network.Conn, p network.Packet) bool {
 			log4go.Error("[router] msg.Unmarshal error=[%s]", err.Error())
 			return false
 		}
+		ret := &pb.S2C_ConnectMsg{
+			ErrorCode: pb.ERRORCODE_ERR_ok.Enum(),
+		}
+
 		playerID := rec.GetPlayerID()
 		battleID := rec.GetBattleID()
 		token := rec.GetToken()
+		ticket := rec.GetResumeTicket()
+		if ticket != "" {
+			var err error
+			if battleID, playerID, err = room.ParseTicket(ticket); err != nil {
+				ret.ErrorCode = pb.ERRORCODE_ERR_Ticket.Enum()
+				conn.AsyncWritePacket(pb_packet.NewPacket(uint8(pb.ID_MSG_Connect), ret), time.Millisecond)
+				log4go.Error("[router] bad ticket [%s] error=[%s]", ticket, err.Error())
+				return true
+			}
+		}
 
-		ret := &pb.S2C_ConnectMsg{
-			ErrorCode: pb.ERRORCODE_ERR_ok.Enum(),
+		version, ok := r.version.negotiate(rec.GetProtocolVersion(), rec.GetClientVersion())
+		if !ok {
+			ret.ErrorCode = pb.ERRORCODE_ERR_Version.Enum()
+			ret.ProtocolVersion = proto.Uint32(r.version.maxProtocol())
+			conn.AsyncWritePacket(pb_packet.NewPacket(uint8(pb.ID_MSG_Connect), ret), time.Millisecond)
+			log4go.Error("[router] outdated player=[%d] room=[%d] protocol=[%d] client=[%s]", playerID, battleID,
+				rec.GetProtocolVersion(), rec.GetClientVersion())
+			return true
+		}
+
+		checksum, ok := r.checksum.negotiate(rec.GetChecksum())
+		if !ok {
+			ret.ErrorCode = pb.ERRORCODE_ERR_Checksum.Enum()
+			ret.Checksum = r.checksum.Required.Enum()
+			conn.AsyncWritePacket(pb_packet.NewPacket(uint8(pb.ID_MSG_Connect), ret), time.Millisecond)
+			log4go.Error("[router] checksum required player=[%d] room=[%d] checksum=[%s]", playerID, battleID,
+				rec.GetChecksum())
+			return true
 		}
 
-		room := r.roomMgr.GetRoom(battleID)
-		if room == nil {
+		rm := r.roomMgr.GetRoom(battleID)
+		if rm == nil {
+			if node, ok := r.roomMgr.RoomOwner(battleID); ok {
+				ret.ErrorCode = pb.ERRORCODE_ERR_Redirect.Enum()
+				ret.Address = proto.String(node)
+				conn.AsyncWritePacket(pb_packet.NewPacket(uint8(pb.ID_MSG_Connect), ret), time.Millisecond)
+				log4go.Warn("[router] redirect player=[%d] room=[%d] node=[%s]", playerID, battleID, node)
+				return true
+			}
 			ret.ErrorCode = pb.ERRORCODE_ERR_NoRoom.Enum()
 			conn.AsyncWritePacket(pb_packet.NewPacket(uint8(pb.ID_MSG_Connect), ret), time.Millisecond)
 			log4go.Error("[router] no room player=[%d] room=[%d] token=[%s]", playerID, battleID, token)
 			return true
 		}
 
-		if room.IsOver() {
+		if rm.IsOver() {
 			ret.ErrorCode = pb.ERRORCODE_ERR_RoomState.Enum()
 			conn.AsyncWritePacket(pb_packet.NewPacket(uint8(pb.ID_MSG_Connect), ret), time.Millisecond)
 			log4go.Error("[router] room is over player=[%d] room==[%d] token=[%s]", playerID, battleID, token)
 			return true
 		}
 
-		if !room.HasPlayer(playerID) {
+		if !rm.HasPlayer(playerID) {
 			ret.ErrorCode = pb.ERRORCODE_ERR_NoPlayer.Enum()
 			conn.AsyncWritePacket(pb_packet.NewPacket(uint8(pb.ID_MSG_Connect), ret), time.Millisecond)
 			log4go.Error("[router] !room.HasPlayer(playerID) player=[%d] room==[%d] token=[%s]", playerID, battleID,
@@ -66,15 +106,34 @@ func (r *LockStepServer) OnMessage(conn *network.Conn, p network.Packet) bool {
 			return true
 		}
 
-		if token != verifyToken(token) {
+		// the resume ticket replaces the token, and it is the key of CHK_HMAC as well
+		key := token
+		if ticket != "" {
+			if _, err := rm.VerifyTicket(ticket); err != nil {
+				ret.ErrorCode = pb.ERRORCODE_ERR_Ticket.Enum()
+				conn.AsyncWritePacket(pb_packet.NewPacket(uint8(pb.ID_MSG_Connect), ret), time.Millisecond)
+				log4go.Error("[router] verifyTicket failed player=[%d] room==[%d] error=[%s]", playerID, battleID,
+					err.Error())
+				return true
+			}
+			key = ticket
+		} else if !rm.VerifyToken(playerID, token) {
 			ret.ErrorCode = pb.ERRORCODE_ERR_Token.Enum()
 			conn.AsyncWritePacket(pb_packet.NewPacket(uint8(pb.ID_MSG_Connect), ret), time.Millisecond)
 			log4go.Error("[router] verifyToken failed player=[%d] room==[%d] token=[%s]", playerID, battleID, token)
 			return true
 		}
 
+		if mp, ok := conn.GetProtocol().(*pb_packet.MsgProtocol); ok {
+			mp.SetVersion(version)
+			mp.SetChecksum(checksum, []byte(key))
+		}
 		conn.PutExtraData(playerID)
-		return room.OnConnect(conn)
+		conn.SetCallback(r.roomCallback(rm))
+		if ticket != "" {
+			return rm.OnResume(conn, ticket, rec.GetFrameID())
+		}
+		return rm.OnConnect(conn)
 
 	case pb.ID_MSG_Heartbeat:
 		conn.AsyncWritePacket(pb_packet.NewPacket(uint8(pb.ID_MSG_Heartbeat), nil), time.Millisecond)
@@ -89,6 +148,20 @@ func (r *LockStepServer) OnMessage(conn *network.Conn, p network.Packet) bool {
 }
 
 func (r *LockStepServer) OnClose(conn *network.Conn) {
+	if r.limiter != nil {
+		r.limiter.remove(conn)
+	}
 	count := atomic.AddInt64(&r.totalConn, -1)
 	log4go.Info("[router] OnClose: total=%d", count)
 }
+
+// roomCallback returns the callback handling the connection after it has connected to the room
+func (r *LockStepServer) roomCallback(room network.ConnCallback) network.ConnCallback {
+	if r.limiter == nil {
+		return room
+	}
+	return &limitedCallback{
+		ConnCallback: room,
+		limiter:      r.limiter,
+	}
+}
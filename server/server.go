@@ -1,39 +1,129 @@
 package server
 
 import (
+	"fmt"
+	"net"
+
 	"github.com/hedon954/go-lock-step-server/logic"
 	"github.com/hedon954/go-lock-step-server/pkg/kcp_server"
 	"github.com/hedon954/go-lock-step-server/pkg/network"
 	"github.com/hedon954/go-lock-step-server/pkg/packet/pb_packet"
+	"github.com/hedon954/go-lock-step-server/pkg/tcp_server"
+	"github.com/hedon954/go-lock-step-server/pkg/ws_server"
+)
+
+// Transport is the transport protocol of a listener
+type Transport string
+
+const (
+	TransportKCP       Transport = "kcp"
+	TransportTCP       Transport = "tcp"
+	TransportWebSocket Transport = "ws"
 )
 
+// ListenerConfig is the configuration of a listener
+type ListenerConfig struct {
+	Transport Transport // the transport protocol
+	Address   string    // the listen address
+	Path      string    // the http path of the websocket listener, "/" by default
+
+	// KCP is the configuration of the kcp listener, nil means kcp_server.DefaultKCPConfig()
+	KCP *kcp_server.KCPConfig
+}
+
+// Config is the configuration of the lock step server
+type Config struct {
+	Listeners   []ListenerConfig // the listeners feeding the server, all of them join the same rooms
+	RoomManager logic.Config     // room manager configuration
+	RateLimit   RateLimitConfig  // per-connection rate limits, the zero value disables them
+	Version     VersionPolicy    // the versions accepted, the zero value accepts every supported protocol
+	Checksum    ChecksumPolicy   // the packet checksum accepted, the zero value accepts the clients without checksum
+}
+
 // LockStepServer is a lock step server
 type LockStepServer struct {
 	roomMgr   *logic.RoomManager
-	udpServer *network.Server
+	servers   []*network.Server
+	limiter   *rateLimiter
+	version   VersionPolicy
+	checksum  ChecksumPolicy
 	totalConn int64
 }
 
 // New creates a new lock step server
-func New(address string) (*LockStepServer, error) {
+func New(config *Config) (*LockStepServer, error) {
+	if len(config.Listeners) == 0 {
+		return nil, fmt.Errorf("no listener is configured")
+	}
+
 	s := &LockStepServer{
-		roomMgr: logic.NewRoomManager(),
+		roomMgr:  logic.NewRoomManager(&config.RoomManager),
+		version:  config.Version,
+		checksum: config.Checksum,
+	}
+	if config.RateLimit.enabled() {
+		s.limiter = newRateLimiter(config.RateLimit)
 	}
-	networkServer, err := kcp_server.ListenAndServe(address, s, &pb_packet.MsgProtocol{})
-	if err != nil {
-		return nil, err
+
+	for _, l := range config.Listeners {
+		networkServer, err := s.listen(l)
+		if err != nil {
+			s.stopServers()
+			return nil, fmt.Errorf("listen %s on [%s] error: %w", l.Transport, l.Address, err)
+		}
+		s.servers = append(s.servers, networkServer)
 	}
-	s.udpServer = networkServer
 	return s, nil
 }
 
+// listen starts a network server feeding the lock step server
+func (r *LockStepServer) listen(config ListenerConfig) (*network.Server, error) {
+	switch config.Transport {
+	case TransportKCP:
+		return kcp_server.ListenAndServe(config.Address, config.KCP, r, &pb_packet.MsgProtocol{})
+	case TransportTCP:
+		return tcp_server.ListenAndServe(config.Address, r, &pb_packet.MsgProtocol{})
+	case TransportWebSocket:
+		path := config.Path
+		if path == "" {
+			path = "/"
+		}
+		return ws_server.ListenAndServe(config.Address, path, r, &pb_packet.MsgProtocol{})
+	default:
+		return nil, fmt.Errorf("unknown transport")
+	}
+}
+
 //  RoomManager gets room manager
 func (r *LockStepServer) RoomManager() *logic.RoomManager {
 	return r.roomMgr
 }
 
+// Addrs returns the addresses of the listeners in the order they are configured
+func (r *LockStepServer) Addrs() []net.Addr {
+	addrs := make([]net.Addr, 0, len(r.servers))
+	for _, s := range r.servers {
+		addrs = append(addrs, s.Addr())
+	}
+	return addrs
+}
+
+// AbusivePeers returns the number of packets exceeding the rate limits of each peer host
+func (r *LockStepServer) AbusivePeers() map[string]uint64 {
+	if r.limiter == nil {
+		return map[string]uint64{}
+	}
+	return r.limiter.abusivePeers()
+}
+
 // Stop stops the server
 func (r *LockStepServer) Stop() {
 	r.roomMgr.Stop()
-	r.udpServer.Stop()
+	r.stopServers()
+}
+
+func (r *LockStepServer) stopServers() {
+	for _, s := range r.servers {
+		s.Stop()
+	}
 }
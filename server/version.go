@@ -0,0 +1,75 @@
+package server
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/hedon954/go-lock-step-server/pkg/packet/pb_packet"
+)
+
+// VersionPolicy is the range of the versions accepted by the server,
+// the clients out of the range are told to upgrade with ERR_Version
+type VersionPolicy struct {
+	MinProtocol      uint32 // the lowest protocol version accepted, 0 means pb_packet.MinProtocolVersion
+	MaxProtocol      uint32 // the highest protocol version negotiated, 0 or above pb_packet.MaxProtocolVersion means it
+	MinClientVersion string // the lowest client version accepted like 1.2.3, empty means any
+}
+
+func (v *VersionPolicy) minProtocol() uint32 {
+	if v.MinProtocol == 0 {
+		return pb_packet.MinProtocolVersion
+	}
+	return v.MinProtocol
+}
+
+func (v *VersionPolicy) maxProtocol() uint32 {
+	// the server cannot encode the versions above pb_packet.MaxProtocolVersion
+	if v.MaxProtocol == 0 || v.MaxProtocol > pb_packet.MaxProtocolVersion {
+		return pb_packet.MaxProtocolVersion
+	}
+	return v.MaxProtocol
+}
+
+// negotiate returns the protocol version used with the client,
+// ok is false if the client is outdated
+func (v *VersionPolicy) negotiate(protocolVersion uint32, clientVersion string) (version uint32, ok bool) {
+	// the clients before the negotiation do not send their version
+	if protocolVersion == 0 {
+		protocolVersion = pb_packet.ProtocolVersion1
+	}
+
+	version = protocolVersion
+	if max := v.maxProtocol(); version > max {
+		version = max
+	}
+	if version < v.minProtocol() {
+		return 0, false
+	}
+
+	if v.MinClientVersion != "" && compareVersion(clientVersion, v.MinClientVersion) < 0 {
+		return 0, false
+	}
+	return version, true
+}
+
+// compareVersion compares the dotted versions like 1.2.3 by their numeric parts,
+// the missing or malformed parts are treated as 0
+func compareVersion(a, b string) int {
+	as, bs := strings.Split(a, "."), strings.Split(b, ".")
+	for i := 0; i < len(as) || i < len(bs); i++ {
+		var x, y int
+		if i < len(as) {
+			x, _ = strconv.Atoi(strings.TrimSpace(as[i]))
+		}
+		if i < len(bs) {
+			y, _ = strconv.Atoi(strings.TrimSpace(bs[i]))
+		}
+		if x != y {
+			if x < y {
+				return -1
+			}
+			return 1
+		}
+	}
+	return 0
+}
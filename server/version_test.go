@@ -0,0 +1,53 @@
+package server
+
+import (
+	"testing"
+)
+
+func Test_CompareVersion(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{"1.2.3", "1.2.3", 0},
+		{"1.2", "1.2.0", 0},
+		{"1.10.0", "1.9.9", 1},
+		{"0.9", "1.0", -1},
+		{"", "0.0.1", -1},
+		{"2", "1.99", 1},
+	}
+	for _, tt := range tests {
+		if got := compareVersion(tt.a, tt.b); got != tt.want {
+			t.Errorf("compareVersion(%q, %q) want: %d, got: %d", tt.a, tt.b, tt.want, got)
+		}
+	}
+}
+
+func Test_VersionPolicyNegotiate(t *testing.T) {
+	tests := []struct {
+		policy   VersionPolicy
+		protocol uint32
+		client   string
+		version  uint32
+		ok       bool
+	}{
+		// the clients before the negotiation are accepted by default
+		{VersionPolicy{}, 0, "", 1, true},
+		{VersionPolicy{}, 1, "1.0.0", 1, true},
+		// newer clients are downgraded to the highest version of the server
+		{VersionPolicy{MaxProtocol: 2}, 5, "", 2, true},
+		{VersionPolicy{MinProtocol: 2, MaxProtocol: 3}, 3, "", 2, true}, // clamped to the versions supported
+		{VersionPolicy{MaxProtocol: 9}, 9, "", 2, true},
+		{VersionPolicy{MinProtocol: 2, MaxProtocol: 3}, 0, "", 0, false},
+		{VersionPolicy{MinProtocol: 2, MaxProtocol: 3}, 1, "", 0, false},
+		{VersionPolicy{MinClientVersion: "1.2.0"}, 1, "1.1.9", 0, false},
+		{VersionPolicy{MinClientVersion: "1.2.0"}, 1, "", 0, false},
+		{VersionPolicy{MinClientVersion: "1.2.0"}, 1, "1.2.1", 1, true},
+	}
+	for i, tt := range tests {
+		version, ok := tt.policy.negotiate(tt.protocol, tt.client)
+		if version != tt.version || ok != tt.ok {
+			t.Errorf("case[%d] want: version[%d] ok[%v], got: version[%d] ok[%v]", i, tt.version, tt.ok, version, ok)
+		}
+	}
+}